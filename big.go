@@ -0,0 +1,103 @@
+package query
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	bigIntType = reflect.TypeOf(big.Int{})
+	bigRatType = reflect.TypeOf(big.Rat{})
+)
+
+// maxBigDigits is the largest number of digits of a big.Int or big.Rat
+// value without a "maxdigits" tag option.
+const maxBigDigits = 100
+
+// ratPlaces is the number of decimal places a big.Rat without a finite
+// decimal form, such as 1/3, is rounded to when encoded.
+const ratPlaces = 20
+
+// isBig reports whether t is big.Int or big.Rat, or a pointer to one.
+func isBig(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == bigIntType || t == bigRatType
+}
+
+// setBig parses src into dst, a big.Int or big.Rat or a pointer to one, as
+// a new value: a pointer is set to a new big.Int or big.Rat rather than
+// written through, since the one it held may be shared, as with a prototype
+// given to WithDefaults. big.Int values are read from decimal integers and
+// big.Rat values from decimal numbers, such as "-12.5": the bases, exponents
+// and fractions their UnmarshalText methods accept are rejected. src may have
+// no more digits than the "maxdigits" tag option of opts gives, or else
+// maxBigDigits, so that huge values can't be forced on the decoder.
+func setBig(src string, dst reflect.Value, opts tagOptions) error {
+	max := maxBigDigits
+	if v, ok := opts.Value("maxdigits"); ok {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			max = n
+		}
+	}
+
+	units, frac, dot := strings.Cut(src, ".")
+	digits := strings.TrimPrefix(units, "-")
+	if !isDigits(digits) || dot && !isDigits(frac) {
+		return strconv.ErrSyntax
+	}
+	if n := len(digits) + len(frac); n > max {
+		return fmt.Errorf("%d digits, more than %d", n, max)
+	}
+
+	t := dst.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var n reflect.Value
+	switch t {
+	case bigIntType:
+		if dot {
+			return strconv.ErrSyntax
+		}
+		v, ok := new(big.Int).SetString(src, 10)
+		if !ok {
+			return strconv.ErrSyntax
+		}
+		n = reflect.ValueOf(v)
+	case bigRatType:
+		v, ok := new(big.Rat).SetString(src)
+		if !ok {
+			return strconv.ErrSyntax
+		}
+		n = reflect.ValueOf(v)
+	}
+	if dst.Kind() == reflect.Ptr {
+		dst.Set(n)
+	} else {
+		dst.Set(n.Elem())
+	}
+	return nil
+}
+
+// bigString returns the decimal form of v, a big.Int or big.Rat: a big.Rat
+// is written with as many decimal places as it needs, or ratPlaces when it
+// has no finite decimal form.
+func bigString(v reflect.Value) string {
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	switch b := p.Interface().(type) {
+	case *big.Int:
+		return b.String()
+	case *big.Rat:
+		if places, exact := b.FloatPrec(); exact {
+			return b.FloatString(places)
+		}
+		return b.FloatString(ratPlaces)
+	}
+	return ""
+}
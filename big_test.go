@@ -0,0 +1,70 @@
+package query
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestDecode_big(t *testing.T) {
+	type ledger struct {
+		Total  *big.Int   `q:"total"`
+		Ratio  big.Rat    `q:"ratio"`
+		Parts  []*big.Rat `q:"part,comma,maxdigits=4"`
+		Counts []big.Int  `q:"count"`
+	}
+	var got ledger
+	ok(t, NewDecoder("total=-123456789012345678901234567890&ratio=0.125&part=1.5,-0.25&count=7&count=8").Decode(&got))
+	total, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	exp := ledger{
+		Total:  total,
+		Ratio:  *big.NewRat(1, 8),
+		Parts:  []*big.Rat{big.NewRat(3, 2), big.NewRat(-1, 4)},
+		Counts: []big.Int{*big.NewInt(7), *big.NewInt(8)},
+	}
+	if got.Total.Cmp(exp.Total) != 0 || got.Ratio.Cmp(&exp.Ratio) != 0 || len(got.Parts) != 2 ||
+		got.Parts[0].Cmp(exp.Parts[0]) != 0 || got.Parts[1].Cmp(exp.Parts[1]) != 0 ||
+		len(got.Counts) != 2 || got.Counts[0].Cmp(&exp.Counts[0]) != 0 || got.Counts[1].Cmp(&exp.Counts[1]) != 0 {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	for _, test := range []struct {
+		query string
+		err   string
+	}{
+		{"total=0x10", `query: cannot decode "0x10" into total of type *big.Int: invalid syntax`},
+		{"total=1.0", `query: cannot decode "1.0" into total of type *big.Int: invalid syntax`},
+		{"ratio=1/3", `query: cannot decode "1/3" into ratio of type big.Rat: invalid syntax`},
+		{"ratio=1e3", `query: cannot decode "1e3" into ratio of type big.Rat: invalid syntax`},
+		{"part=1.2345", `query: cannot decode "1.2345" into part of type []*big.Rat: 5 digits, more than 4`},
+		{"total=" + strings.Repeat("9", 101), `query: cannot decode "` + strings.Repeat("9", 101) + `" into total of type *big.Int: 101 digits, more than 100`},
+	} {
+		err := NewDecoder(test.query).Decode(&ledger{})
+		if err == nil || err.Error() != test.err {
+			t.Fatalf("exp: %v\ngot: %v", test.err, err)
+		}
+		if !errors.Is(err, ErrInvalidValue) {
+			t.Fatalf("exp: %v\ngot: %v", ErrInvalidValue, err)
+		}
+	}
+}
+
+func TestValues_big(t *testing.T) {
+	type ledger struct {
+		Total *big.Int   `q:"total"`
+		Ratio big.Rat    `q:"ratio"`
+		Parts []*big.Rat `q:"part,comma"`
+		Empty *big.Int   `q:"empty,omitempty"`
+	}
+	got, err := Values(ledger{
+		Total: big.NewInt(-42),
+		Ratio: *big.NewRat(1, 8),
+		Parts: []*big.Rat{big.NewRat(3, 1), big.NewRat(1, 3)},
+	})
+	ok(t, err)
+	exp := "part=3%2C0.33333333333333333333&ratio=0.125&total=-42"
+	if got.Encode() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, got.Encode())
+	}
+}
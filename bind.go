@@ -0,0 +1,197 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// A BodyError describes a request body that Bind could not decode as JSON,
+// other than a value of the wrong type, which is an *UnmarshalTypeError.
+type BodyError struct {
+	Err error // error of encoding/json
+}
+
+func (e *BodyError) Error() string {
+	return "query: invalid JSON body: " + e.Err.Error()
+}
+
+// Unwrap returns the error of encoding/json.
+func (e *BodyError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrInvalidBody.
+func (e *BodyError) Is(target error) bool {
+	return target == ErrInvalidBody
+}
+
+// Code returns CodeInvalidBody.
+func (e *BodyError) Code() string {
+	return CodeInvalidBody
+}
+
+// Params returns no parameters: the body has no key.
+func (e *BodyError) Params() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+// Bind decodes the parameters of r into the value pointed by v, whichever
+// way they are sent. The query string of GET, HEAD and DELETE requests is
+// decoded as Decode does, using the "q" tags. Other requests may also have a
+// body: a form body is decoded as DecodeRequest does, and any other body as
+// JSON, using the "json" tags, before the keys present in the query string,
+// which win. The "default" and "required" tag options, and WithDefaults,
+// then only apply to the query of GET, HEAD and DELETE requests.
+//
+// The fields a JSON body sets are then checked as if their values had come
+// in the query: against WithAllowedKeys and WithDeniedKeys, the transforms
+// and the "enum", "min", "max" and "maxspan" tag options among others, so
+// that a struct is held to the same rules whichever way it is sent. Only the
+// fields of the top-level struct, and of its embedded structs, are checked:
+// the fields of nested structs and maps are not.
+//
+// Errors are those of Decode, values of the wrong type in a JSON body
+// included, as an *UnmarshalTypeError keyed by their path, while a body that
+// is not valid JSON fails with a *BodyError.
+func Bind(r *http.Request, v interface{}, opts ...Option) error {
+	var q string
+	if r.URL != nil {
+		q = r.URL.RawQuery
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete, "":
+		return NewDecoder(q, opts...).Decode(v)
+	}
+	switch mediaType(r) {
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		return DecodeRequest(r, v, opts...)
+	}
+
+	d := NewDecoder(q, opts...)
+	raw, err := d.readBody(r)
+	if err != nil {
+		return err
+	}
+	if len(raw) > 0 {
+		if err := checkTarget(v); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(raw, v); err != nil {
+			return jsonError(err)
+		}
+		if err := d.checkBody(reflect.ValueOf(v).Elem(), raw); err != nil {
+			return err
+		}
+	}
+	d.presentOnly = true
+	return d.Decode(v)
+}
+
+// jsonError returns the error of Bind for err, an error of json.Unmarshal.
+func jsonError(err error) error {
+	var te *json.UnmarshalTypeError
+	if errors.As(err, &te) {
+		key := te.Field
+		if key == "" {
+			key = strings.TrimPrefix(te.Struct, ".")
+		}
+		return &UnmarshalTypeError{Key: key, Value: te.Value, Type: te.Type, Err: err}
+	}
+	return &BodyError{Err: err}
+}
+
+// checkBody checks the fields of the struct rv that the JSON body raw sets
+// as Decode checks the fields it decodes, decoding them again from their
+// encoding by Values. A field whose error the error handler excuses is left
+// at zero.
+func (d *Decoder) checkBody(rv reflect.Value, raw []byte) error {
+	var present map[string]json.RawMessage
+	if rv.Kind() != reflect.Struct || json.Unmarshal(raw, &present) != nil {
+		return nil
+	}
+	encoded, err := Values(rv.Interface(), EncodeKeyStyle(d.keyStyle))
+	if err != nil {
+		return err
+	}
+	return d.checkFields(rv, present, encoded)
+}
+
+// checkFields runs checkBody over the fields of the struct rv, and of its
+// embedded structs, whose JSON names are keys of present.
+func (d *Decoder) checkFields(rv reflect.Value, present map[string]json.RawMessage, encoded url.Values) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf, fv := t.Field(i), rv.Field(i)
+		name, embedded := jsonName(sf)
+		if embedded {
+			if err := d.checkFields(fv, present, encoded); err != nil {
+				return err
+			}
+			continue
+		}
+		key, opts, ok := d.fieldKey(sf, "")
+		if !ok || key == "" || !fv.CanSet() || !decodable(sf.Type) || !jsonPresent(present, name) {
+			continue
+		}
+		if d.forbids(key) {
+			if !d.dropForbidden {
+				return &ForbiddenKeyError{Key: key}
+			}
+			fv.SetZero()
+			continue
+		}
+		vals, ok := lookup(encoded, key, sf.Type, opts)
+		if !ok {
+			continue
+		}
+		if err := d.decodeField(key, vals, fv, sf.Type, opts); err != nil {
+			if _, ok := err.(*UnsupportedTypeError); ok {
+				return withField(err, sf.Name, key)
+			}
+			if err := d.handle(key, err); err != nil {
+				return err
+			}
+			fv.SetZero()
+		}
+	}
+	return nil
+}
+
+// jsonName returns the name encoding/json gives the struct field sf, empty
+// for the fields it ignores, and whether sf is an embedded struct whose
+// fields it promotes.
+func jsonName(sf reflect.StructField) (string, bool) {
+	tag := sf.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" && sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+		return "", true
+	}
+	if name == "" {
+		name = sf.Name
+	}
+	return name, false
+}
+
+// jsonPresent reports whether name is a key of present, which
+// encoding/json matches case-insensitively.
+func jsonPresent(present map[string]json.RawMessage, name string) bool {
+	if name == "" {
+		return false
+	}
+	if _, ok := present[name]; ok {
+		return true
+	}
+	for k := range present {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package query
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBind(t *testing.T) {
+	type search struct {
+		Query string   `q:"q" json:"query"`
+		Tags  []string `q:"tag" json:"tags"`
+		Page  int      `q:"page,default=1" json:"page"`
+		Owner string   `q:"owner,required" json:"owner"`
+	}
+
+	for _, method := range []string{"GET", "HEAD", "DELETE"} {
+		var got search
+		r := httptest.NewRequest(method, "/?q=shoes&owner=me", strings.NewReader(`{"query":"ignored"}`))
+		ok(t, Bind(r, &got))
+		if exp := (search{Query: "shoes", Page: 1, Owner: "me"}); !reflect.DeepEqual(exp, got) {
+			t.Fatalf("%s: exp: %+v\ngot: %+v", method, exp, got)
+		}
+	}
+	if err := Bind(httptest.NewRequest("GET", "/?q=shoes", nil), &search{}); !errors.Is(err, ErrMissingKey) {
+		t.Fatalf("exp: %v\ngot: %v", ErrMissingKey, err)
+	}
+
+	var got search
+	r := httptest.NewRequest("POST", "/?q=boots&tag=b", strings.NewReader(`{"query":"shoes","tags":["a"],"page":3}`))
+	r.Header.Set("Content-Type", "application/json")
+	ok(t, Bind(r, &got))
+	if exp := (search{Query: "boots", Tags: []string{"b"}, Page: 3}); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	got = search{}
+	r = httptest.NewRequest("PUT", "/", strings.NewReader("q=shoes&owner=me"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ok(t, Bind(r, &got))
+	if exp := (search{Query: "shoes", Page: 1, Owner: "me"}); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	err := Bind(httptest.NewRequest("POST", "/", strings.NewReader(`{"page":"two"}`)), &search{})
+	var te *UnmarshalTypeError
+	if !errors.As(err, &te) || te.Key != "page" || te.Code() != CodeInvalidInteger {
+		t.Fatalf("exp: %T for page\ngot: %v", te, err)
+	}
+	if err := Bind(httptest.NewRequest("POST", "/", strings.NewReader(`{"page":`)), &search{}); !errors.Is(err, ErrInvalidBody) {
+		t.Fatalf("exp: %v\ngot: %v", ErrInvalidBody, err)
+	}
+	if err := Bind(httptest.NewRequest("POST", "/", strings.NewReader(`{}`)), search{}); !errors.Is(err, ErrInvalidTarget) {
+		t.Fatalf("exp: %v\ngot: %v", ErrInvalidTarget, err)
+	}
+}
+
+func TestBind_checksJSON(t *testing.T) {
+	type search struct {
+		Page  int      `q:"page,min=1" json:"page"`
+		Sort  string   `q:"sort,enum=asc|desc" json:"sort"`
+		Code  string   `q:"code,trim,upper" json:"code"`
+		Tags  []string `q:"tag,enum=a|b" json:"tags"`
+		Admin bool     `q:"admin" json:"admin"`
+	}
+	post := func(body string) *http.Request {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		return r
+	}
+
+	for body, sentinel := range map[string]error{
+		`{"page":-5}`:        ErrConstraint,
+		`{"page":0}`:         ErrConstraint,
+		`{"sort":"DROP"}`:    ErrConstraint,
+		`{"tags":["a","c"]}`: ErrConstraint,
+		`{"admin":true}`:     ErrForbiddenKey,
+	} {
+		err := Bind(post(body), &search{}, WithDeniedKeys("admin"))
+		if !errors.Is(err, sentinel) {
+			t.Fatalf("%s\nexp: %v\ngot: %v", body, sentinel, err)
+		}
+	}
+
+	var got search
+	ok(t, Bind(post(`{"Sort":"asc","code":" ab ","tags":["b"]}`), &got))
+	if exp := (search{Sort: "asc", Code: "AB", Tags: []string{"b"}}); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	got = search{}
+	ok(t, Bind(post(`{"page":-5,"sort":"asc"}`), &got, WithErrorHandler(func(string, error) error { return nil })))
+	if exp := (search{Sort: "asc"}); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+}
@@ -0,0 +1,427 @@
+package query
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Finciero/go-queryparams/internal/tagspec"
+)
+
+var (
+	unmarshalerType     = reflect.TypeOf(new(Unmarshaler)).Elem()
+	textUnmarshalerType = reflect.TypeOf(new(encoding.TextUnmarshaler)).Elem()
+)
+
+// A TypeCheckError lists the problems CheckType found in a struct type.
+type TypeCheckError struct {
+	Type     reflect.Type
+	Problems []string
+}
+
+func (e *TypeCheckError) Error() string {
+	return "query: invalid type " + e.Type.String() + ": " + strings.Join(e.Problems, "; ")
+}
+
+// CheckType inspects the struct type of v, which may be a struct or a pointer
+// to one, and reports every problem that would otherwise only show up when a
+// request is decoded: fields of unsupported kinds, several fields mapped to the
+// same key, unknown tag options and invalid option values, such as a "pattern"
+// that is not a regular expression or a "min" greater than "max". Nested
+// structs are checked recursively. It is meant to be called from init or tests
+// for every struct used as a Decode target.
+func CheckType(v interface{}) error {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return &InvalidUnmarshalError{t}
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return &UnsupportedTypeError{Type: t}
+	}
+
+	c := &checker{visiting: make(map[reflect.Type]bool)}
+	c.checkStruct(t, "", make(map[string]string))
+	if len(c.problems) > 0 {
+		return &TypeCheckError{t, c.problems}
+	}
+	return nil
+}
+
+// Compile checks the struct type of v as CheckType does, and prepares what
+// the decoder caches for it, such as the regular expressions of its
+// "pattern" tag options, so that the first request decoded into it neither
+// finds a bad tag nor pays for them. It is meant to be called from init for
+// every struct used as a Decode target.
+func Compile(v interface{}) error {
+	if err := CheckType(v); err != nil {
+		return err
+	}
+	flatPlanFor(structType(v))
+	return nil
+}
+
+// checker accumulates the problems found by CheckType.
+type checker struct {
+	problems []string
+	visiting map[reflect.Type]bool
+}
+
+func (c *checker) report(format string, args ...interface{}) {
+	c.problems = append(c.problems, fmt.Sprintf(format, args...))
+}
+
+// checkStruct checks the fields of the struct type t, naming them after path.
+// keys holds the keys already used in the same scope.
+func (c *checker) checkStruct(t reflect.Type, path string, keys map[string]string) {
+	if c.visiting[t] {
+		return
+	}
+	c.visiting[t] = true
+	defer delete(c.visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup(TagKey)
+		if tag == "-" {
+			continue
+		}
+		name, opts := parseTag(tag)
+		field := path + sf.Name
+
+		if name == "" && sf.Anonymous && sf.Type.Kind() == reflect.Struct && isNested(sf.Type) {
+			c.checkStruct(sf.Type, field+".", keys)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		if tagspec.Groups(sf.Name, name, opts) {
+			for _, opt := range opts {
+				if n := tagspec.Name(opt); n != "exclusive" && n != "together" {
+					c.report("%s: unknown tag option %q", field, opt)
+				}
+			}
+			continue
+		}
+		if opts.Contains("inline") {
+			if !isInlineMap(sf.Type) {
+				c.report("%s: inline field must be a url.Values or map[string][]string", field)
+			}
+			if prev, ok := keys["*"]; ok {
+				c.report("%s: only one inline field is allowed, %s is already one", field, prev)
+			} else {
+				keys["*"] = field
+			}
+			continue
+		}
+
+		if prev, ok := keys[name]; ok {
+			c.report("%s: key %q is already used by %s", field, name, prev)
+		} else {
+			keys[name] = field
+		}
+		for _, opt := range opts {
+			if !tagOptionNames[tagspec.Name(opt)] && !registered(opt) {
+				c.report("%s: unknown tag option %q", field, opt)
+			}
+		}
+		if sf.PkgPath != "" {
+			c.report("%s: field is not exported", field)
+		}
+		if opts.Contains("files") {
+			if sf.Type != fileHeadersType && sf.Type != fileHeaderType {
+				c.report("%s: files only applies to []*multipart.FileHeader and *multipart.FileHeader", field)
+			}
+			continue
+		}
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if opts.Contains("keepempty") && ft.Kind() != reflect.Map {
+			c.report("%s: keepempty only applies to maps", field)
+		}
+		switch {
+		case ft.Kind() == reflect.Struct && isNested(ft):
+			c.checkStruct(ft, field+".", make(map[string]string))
+		case ft.Kind() == reflect.Map && decodableMap(ft):
+		case isNestedSlice(ft):
+			c.checkElem(ft.Elem(), field)
+		case !decodable(ft):
+			c.report("%s: type %s is not supported", field, sf.Type)
+		default:
+			c.checkDefault(sf, field, opts)
+			c.checkLimits(sf, field, opts)
+			c.checkLocation(field, opts)
+			c.checkRaw(t, field, opts)
+			if opts.Contains("constcmp") && ft.Kind() != reflect.String {
+				c.report("%s: constcmp only applies to strings", field)
+			}
+		}
+	}
+}
+
+// checkElem checks the element type t of a slice of structs or maps held by
+// the field named field.
+func (c *checker) checkElem(t reflect.Type, field string) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct {
+		c.checkStruct(t, field+"[].", make(map[string]string))
+	} else if !decodableMap(t) {
+		c.report("%s: type %s is not supported", field, t)
+	}
+}
+
+// checkLimits reports the invalid tag options of the field sf among those
+// that limit or format its values.
+func (c *checker) checkLimits(sf reflect.StructField, field string, opts tagOptions) {
+	c.checkBounds(sf, field, opts)
+	if _, ok := opts.Value("enum"); !ok && opts.Contains("enumlenient") {
+		c.report("%s: enumlenient requires enum", field)
+	}
+	c.checkScale(sf, field, opts)
+	if n, ok := opts.Value("maxlen"); ok {
+		if _, valid := maxLen("maxlen=" + n); !valid {
+			c.report("%s: maxlen %q is not a positive integer", field, n)
+		}
+	}
+	c.checkFloatFormat(sf, field, opts)
+	c.checkBase(sf, field, opts)
+	c.checkPad(sf, field, opts)
+	c.checkTimeRange(sf, field, opts)
+	c.checkUnit(sf, field, opts)
+	if opts.Contains("strictnum") && !numericKind(sf.Type) {
+		c.report("%s: strictnum only applies to numbers", field)
+	}
+	c.checkLenientInt(sf, field, opts)
+	if expr, ok := opts.Value("pattern"); ok {
+		if _, err := pattern(expr); err != nil {
+			c.report("%s: pattern %q is not a valid regular expression", field, expr)
+		}
+	}
+}
+
+// checkBounds reports "min" and "max" options that are not numbers, on a
+// field that does not hold numbers, or with min above max.
+func (c *checker) checkBounds(sf reflect.StructField, field string, opts tagOptions) {
+	for _, name := range []string{"min", "max"} {
+		lim, ok := opts.Value(name)
+		if !ok {
+			continue
+		}
+		if !numericKind(sf.Type) {
+			c.report("%s: %s only applies to numbers", field, name)
+		} else if _, err := strconv.ParseFloat(lim, 64); err != nil {
+			c.report("%s: %s %q is not a number", field, name, lim)
+		}
+	}
+	min, hasMin := opts.Value("min")
+	max, hasMax := opts.Value("max")
+	if hasMin && hasMax && limit(min) > limit(max) {
+		c.report("%s: min %s is greater than max %s", field, min, max)
+	}
+}
+
+// checkScale reports a "scale" option that is not a number of decimal
+// places, or on a field that does not hold integers.
+func (c *checker) checkScale(sf reflect.StructField, field string, opts tagOptions) {
+	places, ok := opts.Value("scale")
+	if !ok {
+		return
+	}
+	if !integerKind(sf.Type) {
+		c.report("%s: scale only applies to integers", field)
+	} else if _, valid := scale(opts); !valid {
+		c.report("%s: scale %q is not a number of decimal places", field, places)
+	}
+}
+
+// checkFloatFormat reports invalid "prec" and "fmt" options, and those on a
+// field that does not hold floats.
+func (c *checker) checkFloatFormat(sf reflect.StructField, field string, opts tagOptions) {
+	if p, ok := opts.Value("prec"); ok {
+		if !floatKind(sf.Type) {
+			c.report("%s: prec only applies to floats", field)
+		} else if n, err := strconv.Atoi(p); err != nil || n < 0 {
+			c.report("%s: prec %q is not a number of digits", field, p)
+		}
+	}
+	if f, ok := opts.Value("fmt"); ok {
+		if !floatKind(sf.Type) {
+			c.report("%s: fmt only applies to floats", field)
+		} else if f != "f" && f != "e" && f != "g" {
+			c.report("%s: fmt %q is not one of f, e and g", field, f)
+		}
+	}
+}
+
+// checkBase reports an invalid "base" option, one on a field that does not
+// hold integers, and one combined with options reading decimal numbers.
+func (c *checker) checkBase(sf reflect.StructField, field string, opts tagOptions) {
+	b, ok := opts.Value("base")
+	if !ok {
+		return
+	}
+	if !integerKind(sf.Type) {
+		c.report("%s: base only applies to integers", field)
+	} else if _, valid := base(opts); !valid {
+		c.report("%s: base %q is not a number between 2 and 36", field, b)
+	}
+	for _, opt := range []string{"scale", "lenientint", "strictnum"} {
+		if _, ok := opts.Value(opt); ok || opts.Contains(opt) {
+			c.report("%s: base cannot be combined with %s", field, opt)
+		}
+	}
+}
+
+// checkPad reports an invalid "pad" option, one on a field that does not
+// hold integers, and one combined with "scale".
+func (c *checker) checkPad(sf reflect.StructField, field string, opts tagOptions) {
+	p, ok := opts.Value("pad")
+	if !ok {
+		return
+	}
+	if !integerKind(sf.Type) {
+		c.report("%s: pad only applies to integers", field)
+	} else if _, valid := padding(opts); !valid {
+		c.report("%s: pad %q is not a number of digits", field, p)
+	} else if _, ok := opts.Value("scale"); ok {
+		c.report("%s: pad cannot be combined with scale", field)
+	}
+}
+
+// checkTimeRange reports a "maxspan" option that is not a positive
+// duration, and "maxspan" and "prefix" options on a field that is not a
+// TimeRange.
+func (c *checker) checkTimeRange(sf reflect.StructField, field string, opts tagOptions) {
+	isRange := sf.Type == timeRangeType || sf.Type.Kind() == reflect.Ptr && sf.Type.Elem() == timeRangeType
+	if span, ok := opts.Value("maxspan"); ok {
+		if !isRange {
+			c.report("%s: maxspan only applies to TimeRange", field)
+		} else if _, err := parseSpan(span); err != nil {
+			c.report("%s: maxspan %q is not a positive duration", field, span)
+		}
+	}
+	if _, ok := opts.Value("prefix"); (ok || opts.Contains("prefix")) && !isRange {
+		c.report("%s: prefix only applies to TimeRange", field)
+	}
+}
+
+// checkUnit reports an unknown "unit" option, and one on a field that does
+// not hold durations.
+func (c *checker) checkUnit(sf reflect.StructField, field string, opts tagOptions) {
+	u, ok := opts.Value("unit")
+	if !ok {
+		return
+	}
+	t := sf.Type
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t != durationType {
+		c.report("%s: unit only applies to durations", field)
+	} else if _, err := durationUnit(opts); err != nil {
+		c.report("%s: unit %q is not one of ns, us, ms, s, m and h", field, u)
+	}
+}
+
+// checkLenientInt reports a "lenientint" option on a field that does not
+// hold integers, or with an epsilon outside [0, 0.5).
+func (c *checker) checkLenientInt(sf reflect.StructField, field string, opts tagOptions) {
+	eps, ok := opts.Value("lenientint")
+	if !ok && !opts.Contains("lenientint") {
+		return
+	}
+	if !integerKind(sf.Type) {
+		c.report("%s: lenientint only applies to integers", field)
+	} else if e, err := strconv.ParseFloat(eps, 64); ok && (err != nil || e < 0 || e >= 0.5) {
+		c.report("%s: lenientint %q is not a number between 0 and 0.5", field, eps)
+	}
+}
+
+// checkDefault reports a "default" tag option of the field sf that does not
+// decode into its type, or that is combined with "required".
+func (c *checker) checkDefault(sf reflect.StructField, field string, opts tagOptions) {
+	def, ok := opts.Value("default")
+	if !ok {
+		return
+	}
+	if opts.Contains("required") {
+		c.report("%s: a required field cannot have a default", field)
+	}
+	fv := reflect.New(sf.Type).Elem()
+	if err := new(Decoder).field(defaultValues(def, sf.Type, opts), fv, opts); err != nil {
+		c.report("%s: default %q is invalid: %v", field, def, err)
+	}
+}
+
+// checkLocation reports a "tz" tag option that does not name a location.
+func (c *checker) checkLocation(field string, opts tagOptions) {
+	if _, ok := opts.Value("tz"); !ok {
+		return
+	}
+	if _, err := location(opts, nil); err != nil {
+		c.report("%s: %v", field, err)
+	}
+}
+
+// checkRaw reports a "rawinto" tag option that does not name an exported
+// string or []string field of the struct type t.
+func (c *checker) checkRaw(t reflect.Type, field string, opts tagOptions) {
+	name, ok := opts.Value("rawinto")
+	if ok && !rawField(t, name) {
+		c.report("%s: rawinto %q is not a string or []string field", field, name)
+	}
+}
+
+// decodable reports whether the decoder knows how to store the values of a
+// single key in a field of type t.
+func decodable(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if unmarshaler(t) {
+		return true
+	}
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+		if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+			return true
+		}
+	}
+	return scalarKind(t.Kind()) || t == timeType || t == dateType || isBig(t)
+}
+
+// decodableMap reports whether the decoder knows how to store the entries of
+// a map of type t, or of the maps it holds.
+func decodableMap(t reflect.Type) bool {
+	if !mapKeyKind(t.Key().Kind()) {
+		return false
+	}
+	if t.Elem().Kind() == reflect.Map {
+		t = t.Elem()
+		return mapKeyKind(t.Key().Kind()) && !isNested(t.Elem()) && decodable(t.Elem())
+	}
+	return !isNested(t.Elem()) && decodable(t.Elem())
+}
+
+// scalarKind reports whether value can decode into a value of kind k.
+func scalarKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.String, reflect.Bool,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package query
+
+import (
+	"math/big"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckType(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		var test struct {
+			_        struct{}   `q:",exclusive=numeric|text,together=slice+time"`
+			Numeric  int        `q:"numeric,allowempty"`
+			Text     *string    `q:"text"`
+			Slice    []float64  `q:"slice,comma,rawinto=SliceRaw"`
+			SliceRaw []string   `q:"-"`
+			Bigs     []*big.Int `q:"big,maxdigits=40"`
+			Time     *time.Time `q:"time"`
+			Ignored  string     `q:"-"`
+			Untagged map[string]string
+			Meta     map[string]map[int]string `q:"meta"`
+			Code     string                    `q:"code,pattern=^[A-Z]{3}$"`
+		}
+		ok(t, CheckType(&test))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var test struct {
+			Numeric int             `q:"numeric,omitmepty"`
+			Other   int             `q:"numeric"`
+			Func    func()          `q:"func"`
+			private int             `q:"private"`
+			Limit   int             `q:"limit,required,default=ten"`
+			Name    string          `q:"name,min=1"`
+			Size    int             `q:"size,max=big"`
+			Band    int             `q:"band,min=10,max=1"`
+			Sort    []string        `q:"sort,enumlenient"`
+			At      time.Time       `q:"at,tz=Mars/Olympus"`
+			Amount  float64         `q:"amount,rawinto=Size"`
+			Price   float64         `q:"price,lenientint"`
+			Count   int             `q:"count,lenientint=0.5"`
+			Rate    int64           `q:"rate,scale=-1"`
+			Span    TimeRange       `q:"span,maxspan=soon"`
+			Window  time.Time       `q:"window,prefix=w_,maxspan=1d"`
+			Wait    []time.Duration `q:"wait,unit=fortnight"`
+			Delay   int             `q:"delay,unit=s"`
+			Sig     []byte          `q:"sig,constcmp"`
+			Code    string          `q:"code,pattern=[a-z"`
+			Nested  struct {
+				Map map[string]struct{} `q:"map"`
+			} `q:"nested"`
+			Rest url.Values          `q:"*"`
+			More map[string][]string `q:",inline"`
+		}
+		got := CheckType(test)
+		exp := &TypeCheckError{
+			Type: reflect.TypeOf(test),
+			Problems: []string{
+				`Numeric: unknown tag option "omitmepty"`,
+				`Other: key "numeric" is already used by Numeric`,
+				"Func: type func() is not supported",
+				"private: field is not exported",
+				"Limit: a required field cannot have a default",
+				`Limit: default "ten" is invalid: strconv.ParseInt: parsing "ten": invalid syntax`,
+				"Name: min only applies to numbers",
+				`Size: max "big" is not a number`,
+				"Band: min 10 is greater than max 1",
+				"Sort: enumlenient requires enum",
+				"At: unknown time zone Mars/Olympus",
+				`Amount: rawinto "Size" is not a string or []string field`,
+				"Price: lenientint only applies to integers",
+				`Count: lenientint "0.5" is not a number between 0 and 0.5`,
+				`Rate: scale "-1" is not a number of decimal places`,
+				`Span: maxspan "soon" is not a positive duration`,
+				"Window: maxspan only applies to TimeRange",
+				"Window: prefix only applies to TimeRange",
+				`Wait: unit "fortnight" is not one of ns, us, ms, s, m and h`,
+				"Delay: unit only applies to durations",
+				"Sig: constcmp only applies to strings",
+				`Code: pattern "[a-z" is not a valid regular expression`,
+				"Nested.Map: type map[string]struct {} is not supported",
+				"More: only one inline field is allowed, Rest is already one",
+			},
+		}
+		if !reflect.DeepEqual(exp, got) {
+			t.Fatalf("exp: %v\ngot: %v", exp, got)
+		}
+	})
+
+	t.Run("non-struct", func(t *testing.T) {
+		got := CheckType(2)
+		exp := &UnimplementerError{Type: reflect.TypeOf(2)}
+		if !reflect.DeepEqual(exp, got) {
+			t.Fatalf("exp: %v\ngot: %v", exp, got)
+		}
+	})
+}
+
+func TestCompile(t *testing.T) {
+	type valid struct {
+		Code string `q:"code,pattern=^[A-Z]{3}$"`
+	}
+	ok(t, Compile(&valid{}))
+	if _, ok := flatPlans.Load(reflect.TypeOf(valid{})); !ok {
+		t.Fatalf("exp: flat plan of %T\ngot: none", valid{})
+	}
+
+	var invalid struct {
+		Code string `q:"code,pattern=[a-z"`
+	}
+	err := Compile(&invalid)
+	exp := &TypeCheckError{
+		Type:     reflect.TypeOf(invalid),
+		Problems: []string{`Code: pattern "[a-z" is not a valid regular expression`},
+	}
+	if !reflect.DeepEqual(exp, err) {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+}
@@ -0,0 +1,181 @@
+package query
+
+import "reflect"
+
+// A CodedError is a decoding error with a stable machine code, one of the
+// Code constants, and the parameters needed to render a message for it in
+// any language, so that messages don't have to be parsed out of Error. Every
+// error type of the decoder implements it.
+type CodedError interface {
+	error
+	Code() string
+	Params() map[string]interface{}
+}
+
+// Codes of the decoding errors, and the parameters of each one besides
+// "key", the query key of the field, which they all have but CodeInvalidTarget.
+const (
+	CodeInvalidInteger  = "invalid_integer"  // "value"
+	CodeInvalidNumber   = "invalid_number"   // "value"
+	CodeInvalidBoolean  = "invalid_boolean"  // "value"
+	CodeInvalidTime     = "invalid_time"     // "value"
+	CodeInvalidDate     = "invalid_date"     // "value"
+	CodeInvalidDuration = "invalid_duration" // "value"
+	CodeInvalidAmount   = "invalid_amount"   // "value"
+	CodeInvalidValue    = "invalid_value"    // "value"
+	CodeRequired        = "required"         // none
+	CodeOutOfRange      = "out_of_range"     // "value", and "min", "max" or "maxspan"
+	CodeInvalidRange    = "invalid_range"    // "value"
+	CodeNotInEnum       = "not_in_enum"      // "value", "allowed" ([]string)
+	CodeNoMatch         = "no_match"         // "value", "pattern"
+	CodeInvalidUTF8     = "invalid_utf8"     // "value"
+	CodeControlChar     = "control_char"     // "value"
+	CodeTooDeep         = "too_deep"         // "max" (int)
+	CodeTooLong         = "too_long"         // "length", "max" (int), without "key"
+	CodeEmptyKey        = "empty_key"        // "values" ([]string), without "key"
+	CodeUnsupportedType = "unsupported_type" // "field", "type"
+	CodeInvalidTarget   = "invalid_target"   // "type", without "key"
+	CodeDuplicateKey    = "duplicate_key"    // "fields" ([]string)
+	CodeForbiddenKey    = "forbidden_key"    // none
+	CodeBadSignature    = "bad_signature"    // none
+	CodeInvalidBody     = "invalid_body"     // none, without "key"
+	CodeKeyConflict     = "key_conflict"     // "nested" ([]string)
+	CodeConflictingKeys = "conflicting_keys" // "keys" ([]string), without "key"
+	CodeMissingTogether = "missing_together" // "keys", "missing" ([]string), without "key"
+	CodeUnknownKey      = "unknown_key"      // none
+	CodeRepeatedKey     = "repeated_key"     // "count" (int)
+	CodeInvalidLength   = "invalid_length"   // "value", "len"
+)
+
+// Code returns the code of the values of the type of the field.
+func (e *UnmarshalTypeError) Code() string {
+	return typeCode(e.Type)
+}
+
+// Params returns the key of the field and the offending value.
+func (e *UnmarshalTypeError) Params() map[string]interface{} {
+	return map[string]interface{}{"key": e.Key, "value": e.Value}
+}
+
+// typeCode returns the code of an invalid value of a field of type t,
+// following typeMessage.
+func typeCode(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return CodeInvalidTime
+	case t == dateType:
+		return CodeInvalidDate
+	case t == numberType:
+		return CodeInvalidNumber
+	case t == flagType:
+		return CodeInvalidBoolean
+	case t == timeRangeType:
+		return CodeInvalidTime
+	case t == moneyType:
+		return CodeInvalidAmount
+	case t == bigIntType:
+		return CodeInvalidInteger
+	case t == bigRatType:
+		return CodeInvalidNumber
+	case t == durationType:
+		return CodeInvalidDuration
+	case unmarshaler(t):
+		return CodeInvalidValue
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return CodeInvalidInteger
+	case reflect.Float32, reflect.Float64:
+		return CodeInvalidNumber
+	case reflect.Bool:
+		return CodeInvalidBoolean
+	}
+	return CodeInvalidValue
+}
+
+// Code returns CodeRequired.
+func (e *MissingRequiredError) Code() string {
+	return CodeRequired
+}
+
+// Params returns the key of the field.
+func (e *MissingRequiredError) Params() map[string]interface{} {
+	return map[string]interface{}{"key": e.Key}
+}
+
+// Code returns CodeNotInEnum for the "enum" rule, CodeNoMatch for
+// "pattern", CodeInvalidRange for "order", CodeInvalidUTF8 for "utf8",
+// CodeControlChar for "control", and CodeOutOfRange for "min", "max" and
+// "maxspan".
+func (e *ValidationError) Code() string {
+	switch e.Rule {
+	case "enum":
+		return CodeNotInEnum
+	case "pattern":
+		return CodeNoMatch
+	case "order":
+		return CodeInvalidRange
+	case "utf8":
+		return CodeInvalidUTF8
+	case "control":
+		return CodeControlChar
+	case "len":
+		return CodeInvalidLength
+	}
+	return CodeOutOfRange
+}
+
+// Params returns the key of the field, the offending value and the limit
+// it breaks: the allowed values, or the "min" or "max" limit.
+func (e *ValidationError) Params() map[string]interface{} {
+	params := map[string]interface{}{"key": e.Key, "value": e.Value}
+	switch e.Rule {
+	case "enum":
+		params["allowed"] = enumValues(e.Limit)
+	case "order", "utf8", "control":
+	default:
+		params[e.Rule] = e.Limit
+	}
+	return params
+}
+
+// Code returns CodeTooDeep.
+func (e *DepthExceededError) Code() string {
+	return CodeTooDeep
+}
+
+// Params returns the key nested too deeply and the maximum depth.
+func (e *DepthExceededError) Params() map[string]interface{} {
+	return map[string]interface{}{"key": e.Key, "max": e.Max}
+}
+
+// Code returns CodeUnsupportedType.
+func (e *UnsupportedTypeError) Code() string {
+	return CodeUnsupportedType
+}
+
+// Params returns the key and name of the field, and its type.
+func (e *UnsupportedTypeError) Params() map[string]interface{} {
+	return map[string]interface{}{"key": e.Key, "field": e.Field, "type": typeName(e.Type)}
+}
+
+// Code returns CodeInvalidTarget.
+func (e *InvalidUnmarshalError) Code() string {
+	return CodeInvalidTarget
+}
+
+// Params returns the type of the value passed to Decode.
+func (e *InvalidUnmarshalError) Params() map[string]interface{} {
+	return map[string]interface{}{"type": typeName(e.Type)}
+}
+
+func typeName(t reflect.Type) string {
+	if t == nil {
+		return "nil"
+	}
+	return t.String()
+}
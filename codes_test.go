@@ -0,0 +1,43 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCodedError(t *testing.T) {
+	type params struct {
+		Limit  int      `q:"limit,min=1,max=100"`
+		Ratio  float64  `q:"ratio"`
+		Active bool     `q:"active"`
+		Sort   string   `q:"sort,required,enum=asc|desc"`
+		Ch     chan int `q:"ch"`
+		Tree   *node    `q:"tree"`
+	}
+	for _, test := range []struct {
+		query  string
+		v      interface{}
+		code   string
+		params map[string]interface{}
+	}{
+		{"sort=asc&limit=x", &params{}, CodeInvalidInteger, map[string]interface{}{"key": "limit", "value": "x"}},
+		{"sort=asc&ratio=x", &params{}, CodeInvalidNumber, map[string]interface{}{"key": "ratio", "value": "x"}},
+		{"sort=asc&active=x", &params{}, CodeInvalidBoolean, map[string]interface{}{"key": "active", "value": "x"}},
+		{"limit=1", &params{}, CodeRequired, map[string]interface{}{"key": "sort"}},
+		{"sort=asc&limit=0", &params{}, CodeOutOfRange, map[string]interface{}{"key": "limit", "value": "0", "min": "1"}},
+		{"sort=asc&limit=101", &params{}, CodeOutOfRange, map[string]interface{}{"key": "limit", "value": "101", "max": "100"}},
+		{"sort=up", &params{}, CodeNotInEnum, map[string]interface{}{"key": "sort", "value": "up", "allowed": []string{"asc", "desc"}}},
+		{"sort=asc&tree[next][next][next][next][next][name]=a", &params{}, CodeTooDeep, map[string]interface{}{"key": "tree[next][next][next][next][next]", "max": 5}},
+		{"sort=asc&ch=1", &params{}, CodeUnsupportedType, map[string]interface{}{"key": "ch", "field": "Ch", "type": "chan int"}},
+		{"sort=asc", params{}, CodeInvalidTarget, map[string]interface{}{"type": "query.params"}},
+	} {
+		var ce CodedError
+		if err := NewDecoder(test.query).Decode(test.v); !errors.As(err, &ce) {
+			t.Fatalf("%s\nexp: %T\ngot: %v", test.query, ce, err)
+		}
+		if ce.Code() != test.code || !reflect.DeepEqual(test.params, ce.Params()) {
+			t.Fatalf("%s\nexp: %v %v\ngot: %v %v", test.query, test.code, test.params, ce.Code(), ce.Params())
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package query
+
+import "slices"
+
+// A Config is a decoding profile: a set of options, fixed when it is
+// created, shared by the decoders it returns. Different parts of a program,
+// such as a public and an internal API, can each hold their own Config
+// instead of repeating their options or relying on SetDefaultOptions.
+//
+// A Config is safe for concurrent use. Configs share the caches the package
+// keeps by type, such as parsed tags and decoding plans, since those don't
+// depend on options: a type is only compiled once however many profiles
+// decode it.
+type Config struct {
+	opts decoderOptions
+}
+
+// NewConfig returns a Config with the options set by SetDefaultOptions at
+// the time of the call, followed by opts.
+func NewConfig(opts ...Option) *Config {
+	return &Config{opts: NewDecoder("", opts...).decoderOptions}
+}
+
+// NewDecoder returns a new decoder that reads the query string s with the
+// options of c, followed by opts, which only apply to this decoder.
+func (c *Config) NewDecoder(s string, opts ...Option) *Decoder {
+	d := &Decoder{q: s, decoderOptions: c.opts}
+	// Options that append must not write to the arrays of c.
+	d.hooks = slices.Clip(d.hooks)
+	d.groups = slices.Clip(d.groups)
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// With returns a new Config with the options of c, followed by opts, for
+// profiles built on another one, such as LenientConfig() with a program's
+// error handler. c is left unchanged.
+func (c *Config) With(opts ...Option) *Config {
+	return &Config{opts: c.NewDecoder("", opts...).decoderOptions}
+}
+
+// Decode decodes the query string s into the value pointed by v with the
+// options of c. It is a shorthand for c.NewDecoder(s).Decode(v).
+func (c *Config) Decode(s string, v interface{}) error {
+	return c.NewDecoder(s).Decode(v)
+}
@@ -0,0 +1,48 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestConfig(t *testing.T) {
+	public := NewConfig(WithMaxQueryLength(12), WithAllowedKeys("q", "page"))
+	internal := NewConfig(WithEmptyAsMissing())
+
+	err := public.Decode("q=foo&page=23", &listOptions{})
+	if !errors.Is(err, ErrTooLong) {
+		t.Fatalf("exp: %v\ngot: %v", ErrTooLong, err)
+	}
+	err = public.Decode("next[page]=2", &listOptions{})
+	if exp := (&ForbiddenKeyError{Key: "next[page]"}); !reflect.DeepEqual(exp, err) {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+
+	var got listOptions
+	ok(t, internal.Decode("q=foo&page=", &got))
+	if exp := (listOptions{Query: "foo"}); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+	ok(t, public.NewDecoder("q=foo&page=234", WithMaxQueryLength(0)).Decode(&got))
+	if got.Page != 234 {
+		t.Fatalf("exp: %v\ngot: %v", 234, got.Page)
+	}
+}
+
+func TestConfig_appendingOptions(t *testing.T) {
+	bang := func(_, raw string, _ reflect.Type) (string, error) { return raw + "!", nil }
+	c := NewConfig(WithDecodeHook(bang), WithDecodeHook(bang), WithDecodeHook(bang))
+	if len(c.opts.hooks) == cap(c.opts.hooks) {
+		t.Fatalf("exp: %v\ngot: %v", "room to append to the hooks of c", cap(c.opts.hooks))
+	}
+
+	a := c.NewDecoder("q=a", WithDecodeHook(func(_, raw string, _ reflect.Type) (string, error) { return raw + "a", nil }))
+	b := c.NewDecoder("q=b", WithDecodeHook(func(_, raw string, _ reflect.Type) (string, error) { return raw + "b", nil }))
+	var ga, gb listOptions
+	ok(t, a.Decode(&ga))
+	ok(t, b.Decode(&gb))
+	if ga.Query != "a!!!a" || gb.Query != "b!!!b" {
+		t.Fatalf("exp: %v\ngot: %v, %v", "a!!!a, b!!!b", ga.Query, gb.Query)
+	}
+}
@@ -0,0 +1,142 @@
+package query
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// A KeyConflict is the rule by which the decoder resolves a key given both
+// alone and with keys nested in it, as in "filter=abc&filter[status]=open",
+// which proxies appending their own parameters tend to produce.
+type KeyConflict int
+
+const (
+	// ConflictByField leaves the keys to the fields reading them: a nested
+	// field reads the nested keys and a scalar field the key alone, while
+	// the other keys are unknown. It is the default.
+	ConflictByField KeyConflict = iota
+
+	// ConflictScalarWins drops the keys nested in a key given alone, which
+	// are reported as unknown.
+	ConflictScalarWins
+
+	// ConflictNestedWins drops a key given alone when keys are nested in
+	// it, and reports it as unknown.
+	ConflictNestedWins
+
+	// ConflictError makes Decode fail with a *KeyConflictError.
+	ConflictError
+)
+
+// WithKeyConflict sets the rule by which the decoder resolves a key given
+// both alone and with nested keys. Keys such as "tag[]" count as nested in
+// "tag". It has no effect with FlatKeys, which don't nest keys.
+func WithKeyConflict(c KeyConflict) Option {
+	return func(d *Decoder) {
+		d.keyConflict = c
+	}
+}
+
+// A KeyConflictError describes a key given both alone and with nested keys,
+// which the decoder refuses with ConflictError.
+type KeyConflictError struct {
+	Key    string   // key given alone
+	Nested []string // keys nested in it, in sorted order
+}
+
+func (e *KeyConflictError) Error() string {
+	return "query: key " + e.Key + " is given both alone and nested, as " + strings.Join(e.Nested, ", ")
+}
+
+// Is reports whether target is ErrKeyConflict.
+func (e *KeyConflictError) Is(target error) bool {
+	return target == ErrKeyConflict
+}
+
+// Fields returns the key with a message stating it is also nested.
+func (e *KeyConflictError) Fields() map[string]string {
+	return map[string]string{e.Key: "is given both alone and with nested keys"}
+}
+
+// MarshalJSON encodes the fields of the error.
+func (e *KeyConflictError) MarshalJSON() ([]byte, error) {
+	return marshalFields(e.Fields())
+}
+
+// Code returns CodeKeyConflict.
+func (e *KeyConflictError) Code() string {
+	return CodeKeyConflict
+}
+
+// Params returns the key and the keys nested in it.
+func (e *KeyConflictError) Params() map[string]interface{} {
+	return map[string]interface{}{"key": e.Key, "nested": e.Nested}
+}
+
+// resolveConflicts returns src without the keys losing to the key conflict
+// rule of the decoder, which it moves to d.conflicts, or fails with a
+// *KeyConflictError for the first conflicting key in sorted order.
+func (d *Decoder) resolveConflicts(src url.Values) (url.Values, error) {
+	if d.keyConflict == ConflictByField || d.keyStyle == FlatKeys {
+		return src, nil
+	}
+
+	keys := make([]string, 0, len(src))
+	for k := range src {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	lost := make(map[string]bool)
+	for _, k := range keys {
+		nested := d.nestedKeys(keys, k)
+		if len(nested) == 0 {
+			continue
+		}
+		switch d.keyConflict {
+		case ConflictError:
+			return nil, &KeyConflictError{Key: k, Nested: nested}
+		case ConflictScalarWins:
+			if !lost[k] {
+				for _, n := range nested {
+					lost[n] = true
+				}
+			}
+		case ConflictNestedWins:
+			lost[k] = true
+		}
+	}
+	if len(lost) == 0 {
+		return src, nil
+	}
+
+	kept := make(url.Values, len(src)-len(lost))
+	d.conflicts = make(url.Values, len(lost))
+	for k, vals := range src {
+		if lost[k] {
+			d.conflicts[k] = vals
+		} else {
+			kept[k] = vals
+		}
+	}
+	return kept, nil
+}
+
+// nestedKeys returns the keys of the sorted keys nested in key, which sort
+// right after key followed by the opening bracket or dot, leaving out
+// literal keys.
+func (d *Decoder) nestedKeys(keys []string, key string) []string {
+	open := "["
+	if d.keyStyle == DotKeys {
+		open = "."
+	}
+	i := sort.SearchStrings(keys, key+open)
+	var nested []string
+	for ; i < len(keys) && strings.HasPrefix(keys[i], key+open); i++ {
+		if !d.literal(keys[i], key) {
+			nested = append(nested, keys[i])
+		}
+	}
+	return nested
+}
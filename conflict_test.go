@@ -0,0 +1,59 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecode_WithKeyConflict(t *testing.T) {
+	const query = "q=foo&filter=abc&filter[status]=open&filter[meta][a]=1"
+	type scalar struct {
+		Filter string `q:"filter"`
+	}
+	nested := listOptions{Query: "foo", Filter: filter{Status: "open", Meta: map[string]string{"a": "1"}}}
+
+	for _, test := range []struct {
+		rule    KeyConflict
+		v       interface{}
+		exp     interface{}
+		unknown []string
+	}{
+		{ConflictByField, &listOptions{}, &nested, []string{"filter"}},
+		{ConflictByField, &scalar{}, &scalar{"abc"}, []string{"filter[meta][a]", "filter[status]", "q"}},
+		{ConflictScalarWins, &listOptions{}, &listOptions{Query: "foo"}, []string{"filter", "filter[meta][a]", "filter[status]"}},
+		{ConflictScalarWins, &scalar{}, &scalar{"abc"}, []string{"filter[meta][a]", "filter[status]", "q"}},
+		{ConflictNestedWins, &listOptions{}, &nested, []string{"filter"}},
+		{ConflictNestedWins, &scalar{}, &scalar{}, []string{"filter", "filter[meta][a]", "filter[status]", "q"}},
+	} {
+		var stats DecodeStats
+		d := NewDecoder(query, WithKeyConflict(test.rule))
+		ok(t, d.Decode(test.v, WithStats(&stats)))
+		if !reflect.DeepEqual(test.exp, test.v) {
+			t.Fatalf("%d: exp: %+v\ngot: %+v", test.rule, test.exp, test.v)
+		}
+		if !reflect.DeepEqual(test.unknown, stats.Unknown) {
+			t.Fatalf("%d: exp: %v\ngot: %v", test.rule, test.unknown, stats.Unknown)
+		}
+		for i, w := range d.Warnings() {
+			if w.Key != test.unknown[i] || w.Action != WarnUnknownKey {
+				t.Fatalf("%d: exp: %v\ngot: %v", test.rule, test.unknown[i], w)
+			}
+		}
+	}
+
+	err := NewDecoder(query, WithKeyConflict(ConflictError)).Decode(&listOptions{})
+	exp := &KeyConflictError{Key: "filter", Nested: []string{"filter[meta][a]", "filter[status]"}}
+	if !reflect.DeepEqual(exp, err) {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+	if !errors.Is(err, ErrKeyConflict) || err.(CodedError).Code() != CodeKeyConflict {
+		t.Fatalf("exp: %v\ngot: %v", ErrKeyConflict, err)
+	}
+
+	err = NewDecoder("filter=abc&filter.status=open", WithKeyStyle(DotKeys), WithKeyConflict(ConflictError)).Decode(&listOptions{})
+	if !errors.Is(err, ErrKeyConflict) {
+		t.Fatalf("exp: %v\ngot: %v", ErrKeyConflict, err)
+	}
+	ok(t, NewDecoder(query, WithKeyStyle(FlatKeys), WithKeyConflict(ConflictError)).Decode(&scalar{}))
+}
@@ -0,0 +1,65 @@
+package query
+
+import (
+	"reflect"
+	"sync"
+)
+
+// A Converter decodes and encodes fields of types the package doesn't know,
+// without those types having to implement Unmarshaler or Marshaler, such as
+// types generated by other tools. It is registered with RegisterConverter.
+type Converter struct {
+	// Match reports whether the converter handles values of type t, which
+	// is never a pointer: fields of type *t are allocated when decoded.
+	Match func(t reflect.Type) bool
+
+	// Decode stores vals, the values of the key of a field, in v, an
+	// addressable value of a type matched by Match.
+	Decode func(vals []string, v reflect.Value) error
+
+	// Encode returns the values of v, a value of a type matched by Match. A
+	// converter without Encode leaves its types to the encoder.
+	Encode func(v reflect.Value) ([]string, error)
+}
+
+var (
+	convertersMu sync.Mutex
+	converters   []Converter
+	convertersOf sync.Map // map[reflect.Type]*Converter, nil for the types without one
+)
+
+// RegisterConverter makes the decoder, and the encoder when c has Encode,
+// handle the fields whose type is matched by c, alone or through a pointer,
+// as a single value. Converters registered later take precedence. It is
+// meant to be called from init functions, before anything is decoded.
+func RegisterConverter(c Converter) {
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	converters = append(converters, c)
+	convertersOf.Range(func(t, _ interface{}) bool {
+		convertersOf.Delete(t)
+		return true
+	})
+}
+
+// converter returns the converter of values of type t, or nil when none is
+// registered for it.
+func converter(t reflect.Type) *Converter {
+	if t.Kind() == reflect.Ptr {
+		return nil
+	}
+	if c, ok := convertersOf.Load(t); ok {
+		return c.(*Converter)
+	}
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	var found *Converter
+	for i := len(converters) - 1; i >= 0; i-- {
+		if converters[i].Match(t) {
+			found = &converters[i]
+			break
+		}
+	}
+	convertersOf.Store(t, found)
+	return found
+}
@@ -0,0 +1,104 @@
+package query
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var dateType = reflect.TypeOf(Date{})
+
+// dateLayout is the layout of dates in queries.
+const dateLayout = "2006-01-02"
+
+// A Date is a calendar date, without a time of day or a time zone, such as
+// an accounting date. Its zero value is not a valid date.
+//
+// As a field, alone or in slices, arrays and maps, a Date is decoded from a
+// value in the exact form 2006-01-02: times and other layouts are rejected.
+// It is encoded in that form.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// DateOf returns the date of t in its location.
+func DateOf(t time.Time) Date {
+	y, m, d := t.Date()
+	return Date{y, m, d}
+}
+
+// ParseDate parses s in the form 2006-01-02.
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return Date{}, err
+	}
+	return DateOf(t), nil
+}
+
+// String returns the date in the form 2006-01-02.
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// IsZero reports whether d is the zero Date.
+func (d Date) IsZero() bool {
+	return d == Date{}
+}
+
+// In returns the time at midnight of d in loc.
+func (d Date) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// AddDays returns d plus n days, which may be negative.
+func (d Date) AddDays(n int) Date {
+	return DateOf(d.In(time.UTC).AddDate(0, 0, n))
+}
+
+// Compare returns -1 when d is before e, 1 when it is after it and 0 when
+// they are the same date.
+func (d Date) Compare(e Date) int {
+	if c := cmp.Compare(d.Year, e.Year); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(d.Month, e.Month); c != 0 {
+		return c
+	}
+	return cmp.Compare(d.Day, e.Day)
+}
+
+// Before reports whether d is before e.
+func (d Date) Before(e Date) bool {
+	return d.Compare(e) < 0
+}
+
+// After reports whether d is after e.
+func (d Date) After(e Date) bool {
+	return d.Compare(e) > 0
+}
+
+// MarshalText returns the date in the form 2006-01-02.
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText parses a date in the form 2006-01-02.
+func (d *Date) UnmarshalText(text []byte) error {
+	date, err := ParseDate(string(text))
+	if err != nil {
+		return err
+	}
+	*d = date
+	return nil
+}
+
+func setDate(src string, dst reflect.Value) error {
+	if src == "" {
+		return nil
+	}
+	return dst.Interface().(*Date).UnmarshalText([]byte(src))
+}
@@ -0,0 +1,69 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecode_Date(t *testing.T) {
+	type report struct {
+		Date    Date            `q:"date"`
+		Days    []Date          `q:"day,comma"`
+		Closing map[string]Date `q:"closing"`
+		Until   *Date           `q:"until"`
+	}
+
+	var got report
+	ok(t, NewDecoder("date=2024-03-31&day=2024-01-01,2024-02-29&closing[q1]=2024-03-31&until=2024-12-31").Decode(&got))
+	exp := report{
+		Date:    Date{2024, time.March, 31},
+		Days:    []Date{{2024, time.January, 1}, {2024, time.February, 29}},
+		Closing: map[string]Date{"q1": {2024, time.March, 31}},
+		Until:   &Date{2024, time.December, 31},
+	}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	for _, q := range []string{"date=2024-03-31T00:00:00Z", "date=2023-02-29", "day=2024-1-1", "until=31/12/2024"} {
+		var got report
+		err := NewDecoder(q).Decode(&got)
+		if !errors.Is(err, ErrInvalidValue) {
+			t.Fatalf("%s\nexp: %v\ngot: %v", q, ErrInvalidValue, err)
+		}
+		if exp, got := CodeInvalidDate, err.(CodedError).Code(); exp != got {
+			t.Fatalf("exp: %v\ngot: %v", exp, got)
+		}
+	}
+}
+
+func TestValues_Date(t *testing.T) {
+	type report struct {
+		Date Date   `q:"date"`
+		Days []Date `q:"day,comma"`
+		From Date   `q:"from,omitempty"`
+	}
+	got, err := Values(report{
+		Date: DateOf(time.Date(2024, 3, 31, 23, 0, 0, 0, time.FixedZone("", -3*3600))),
+		Days: []Date{{2024, time.January, 1}, {2024, time.February, 29}},
+	})
+	ok(t, err)
+	if exp := "date=2024-03-31&day=2024-01-01%2C2024-02-29"; got.Encode() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, got.Encode())
+	}
+}
+
+func TestDate(t *testing.T) {
+	d := Date{2024, time.February, 28}
+	if exp, got := (Date{2024, time.March, 1}), d.AddDays(2); exp != got {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+	if !d.Before(d.AddDays(1)) || !d.After(d.AddDays(-1)) || d.Compare(d) != 0 {
+		t.Fatalf("exp: %v\ngot: %v", "dates ordered", d)
+	}
+	if exp, got := time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC), d.In(time.UTC); !exp.Equal(got) {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+}
@@ -20,10 +20,42 @@ package query
 
 import (
 	"encoding"
+	"encoding/json"
+	"errors"
+	"math"
+	"mime/multipart"
 	"net/url"
 	"reflect"
 	"runtime"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// Errors matched by errors.Is against the errors of the decoder, so that they
+// can be told apart without looking at their type. Each one is matched by a
+// single error type, given after it.
+var (
+	ErrInvalidTarget   = errors.New("query: invalid decode target")     // *InvalidUnmarshalError
+	ErrUnsupportedType = errors.New("query: unsupported type")          // *UnsupportedTypeError
+	ErrInvalidValue    = errors.New("query: invalid value")             // *UnmarshalTypeError
+	ErrMissingKey      = errors.New("query: missing required key")      // *MissingRequiredError
+	ErrConstraint      = errors.New("query: value breaks a constraint") // *ValidationError
+	ErrTooDeep         = errors.New("query: key nested too deeply")     // *DepthExceededError
+	ErrForbiddenKey    = errors.New("query: forbidden key")             // *ForbiddenKeyError
+	ErrGroup           = errors.New("query: keys break a group")        // *GroupError
+	ErrTooLong         = errors.New("query: query string too long")     // *QueryTooLongError
+	ErrDuplicateKey    = errors.New("query: duplicate key")             // *DuplicateKeyError
+	ErrKeyConflict     = errors.New("query: key also nested")           // *KeyConflictError
+	ErrEmptyKey        = errors.New("query: empty key")                 // *EmptyKeyError
+	ErrInvalidBody     = errors.New("query: invalid body")              // *BodyError
+	ErrUnknownKey      = errors.New("query: unknown key")               // *UnknownKeyError
+	ErrRepeatedKey     = errors.New("query: repeated key")              // *RepeatedKeyError
 )
 
 // An InvalidUnmarshalError describes an invalid argument passed to Unmarshal.
@@ -42,34 +74,677 @@ func (e *InvalidUnmarshalError) Error() string {
 	return "query: Decode(nil " + e.Type.String() + ")"
 }
 
-// UnimplementerError error types that are not implemented yet.
-type UnimplementerError struct {
-	Type reflect.Type
+// Is reports whether target is ErrInvalidTarget.
+func (e *InvalidUnmarshalError) Is(target error) bool {
+	return target == ErrInvalidTarget
+}
+
+// An UnsupportedTypeError describes a value whose type can't be decoded or
+// encoded. Field and Key are set when the value is a struct field.
+type UnsupportedTypeError struct {
+	Type  reflect.Type // unsupported type
+	Field string       // name of the struct field
+	Key   string       // query key of the field
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	msg := "query: unsupported type " + typeName(e.Type)
+	if e.Field != "" {
+		msg += " of field " + e.Field
+	}
+	if e.Key != "" {
+		msg += " (key " + e.Key + ")"
+	}
+	return msg
+}
+
+// Is reports whether target is ErrUnsupportedType.
+func (e *UnsupportedTypeError) Is(target error) bool {
+	return target == ErrUnsupportedType
+}
+
+// UnimplementerError is the former name of UnsupportedTypeError.
+//
+// Deprecated: Use UnsupportedTypeError, which UnimplementerError is an alias
+// of. It will be removed in the next release.
+type UnimplementerError = UnsupportedTypeError
+
+// withField sets the field name and key of err, when it is an
+// *UnsupportedTypeError that doesn't have them yet.
+func withField(err error, field, key string) error {
+	if e, ok := err.(*UnsupportedTypeError); ok {
+		if e.Field == "" {
+			e.Field = field
+		}
+		if e.Key == "" {
+			e.Key = key
+		}
+	}
+	return err
+}
+
+// A FieldError is a decoding error about specific query parameters. Fields
+// maps each parameter key to a message suitable for clients, which is kept
+// stable and separate from the text of Error, and MarshalJSON encodes them as
+// {"errors":{"key":"message"}}, ready to be written as the body of a 400
+// response.
+type FieldError interface {
+	error
+	json.Marshaler
+	Fields() map[string]string
+}
+
+// An UnmarshalTypeError describes a query value that could not be decoded
+// into the type of its field.
+type UnmarshalTypeError struct {
+	Key   string       // query key of the field
+	Value string       // offending value
+	Type  reflect.Type // type of the field
+	Err   error        // underlying error
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	return "query: cannot decode " + strconv.Quote(e.Value) + " into " + e.Key + " of type " + e.Type.String() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error, such as a *strconv.NumError.
+func (e *UnmarshalTypeError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrInvalidValue.
+func (e *UnmarshalTypeError) Is(target error) bool {
+	return target == ErrInvalidValue
+}
+
+// Fields returns the key of the field with a message describing the
+// expected value.
+func (e *UnmarshalTypeError) Fields() map[string]string {
+	return map[string]string{e.Key: typeMessage(e.Type)}
+}
+
+// MarshalJSON encodes the fields of the error.
+func (e *UnmarshalTypeError) MarshalJSON() ([]byte, error) {
+	return marshalFields(e.Fields())
 }
 
-func (e *UnimplementerError) Error() string {
-	return "query: " + e.Type.String() + " is not supported yet."
+func marshalFields(fields map[string]string) ([]byte, error) {
+	return json.Marshal(struct {
+		Errors map[string]string `json:"errors"`
+	}{fields})
+}
+
+// typeMessage describes for clients the values accepted by a field of type t.
+func typeMessage(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return "must be a valid time"
+	case t == dateType:
+		return "must be a valid date"
+	case t == numberType:
+		return "must be a number"
+	case t == flagType:
+		return "must be a boolean"
+	case t == timeRangeType:
+		return "must be a valid time range"
+	case t == moneyType:
+		return "must be a valid amount"
+	case t == bigIntType:
+		return "must be an integer"
+	case t == bigRatType:
+		return "must be a number"
+	case t == durationType:
+		return "must be a valid duration"
+	case unmarshaler(t):
+		return "is invalid"
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "must be an integer"
+	case reflect.Float32, reflect.Float64:
+		return "must be a number"
+	case reflect.Bool:
+		return "must be a boolean"
+	}
+	return "is invalid"
+}
+
+// A MissingRequiredError describes a field tagged with the "required" option
+// whose key is absent from the query.
+type MissingRequiredError struct {
+	Key string // query key of the field
+}
+
+func (e *MissingRequiredError) Error() string {
+	return "query: missing required key " + e.Key
+}
+
+// Is reports whether target is ErrMissingKey.
+func (e *MissingRequiredError) Is(target error) bool {
+	return target == ErrMissingKey
+}
+
+// Fields returns the key of the field with a message stating it is required.
+func (e *MissingRequiredError) Fields() map[string]string {
+	return map[string]string{e.Key: "is required"}
+}
+
+// MarshalJSON encodes the fields of the error.
+func (e *MissingRequiredError) MarshalJSON() ([]byte, error) {
+	return marshalFields(e.Fields())
+}
+
+// Unmarshaler is the interface implemented by types that decode themselves
+// from every value of their query parameter. It is the counterpart of
+// Marshaler. UnmarshalQuery must not modify vals, which may be shared with
+// other calls to Decode.
+type Unmarshaler interface {
+	UnmarshalQuery(vals []string) error
 }
 
 // A Decoder reads and decodes URL query strings.
+//
+// A Decoder is configured once by the options given to NewDecoder, and can
+// then be used by several goroutines at once: its query string is parsed
+// once, and each call to Decode keeps its state to itself.
 type Decoder struct {
-	q string
+	q        string
+	borrowed bool // q shares the memory of the []byte given to NewDecoderBytes
+	decoderOptions
+
+	// State of a call to Decode.
+	warnings []Warning
+	read     int            // number of keys of the query read by fields
+	stack    []reflect.Type // structs being decoded, with FlatKeys
+	names    []string       // keys of the map being decoded
+	required []string       // keys required by Require
+	stats    *DecodeStats   // statistics set by WithStats
+
+	conflicts url.Values        // keys dropped by the key conflict rule
+	cleared   []string          // keys of maps set empty by their key alone
+	literals  map[string]string // keys of literal fields, with their scope
+
+	files map[string][]*multipart.FileHeader // file parts read by DecodeRequest
+	given url.Values                         // values coming before those of q, read by DecodeRequest
+
+	// State shared by the calls to Decode.
+	parse        sync.Once
+	src          url.Values
+	parseErr     error
+	mu           sync.Mutex
+	lastWarnings []Warning
+}
+
+// decoderOptions holds the configuration of a Decoder.
+type decoderOptions struct {
+	emptyAsMissing bool
+	keyStyle       KeyStyle
+	canonicalKey   func(string) string
+
+	// splitLists makes slice fields without a delimited format read
+	// comma-separated values, for sources holding a single value per key.
+	splitLists bool
+
+	hooks        []DecodeHook
+	errorHandler func(key string, err error) error
+	maxDepth     int
+	noPooling    bool
+	interning    bool
+	fallback     url.Values
+	defaults     interface{}
+	location     *time.Location
+	maxLength    int
+
+	validUTF8      bool
+	noControlChars bool
+
+	allowDuplicates bool
+
+	disallowUnknown bool
+	noRepeats       bool
+	exactArrays     bool
+	finiteFloats    bool
+
+	skipMalformed bool
+	lastValue     bool
+	reuseSlices   bool
+
+	keyConflict KeyConflict
+	emptyKeys   EmptyKeyRule
+
+	expectedSignature func(url.Values) string
+	multipartMemory   int64
+
+	// presentOnly leaves the fields of absent keys alone, without their
+	// defaults or required checks, for Bind to decode the query over a body.
+	presentOnly bool
+
+	allowedKeys   []string
+	deniedKeys    []string
+	dropForbidden bool
+
+	groups []Group
+}
+
+// A Warning describes a value that the decoder ignored instead of failing.
+type Warning struct {
+	Key    string // query key
+	Value  string // ignored value, with several values joined by ","
+	Action string // what the decoder did: one of the Warn constants
+	Err    error  // error passed to the error handler, if any
+}
+
+// Actions reported by warnings.
+const (
+	WarnEmptyValue   = "ignored empty value"       // dropped by WithEmptyAsMissing
+	WarnInvalidValue = "ignored invalid value"     // error discarded by the error handler
+	WarnMissingValue = "ignored missing value"     // required key absent, discarded by the error handler
+	WarnUnknownKey   = "ignored unknown key"       // no field reads the key
+	WarnForbiddenKey = "ignored forbidden key"     // dropped by WithDropForbiddenKeys
+	WarnEmptyKey     = "ignored empty key"         // pairs such as "=value", with EmptyKeyWarn
+	WarnEnumValue    = "ignored value not in enum" // dropped by the "enumlenient" tag option
+)
+
+// An Option configures a Decoder.
+type Option func(*Decoder)
+
+// WithEmptyAsMissing makes the decoder treat a key that is present with an
+// empty value (e.g. "numeric=") as if it were absent, leaving the field
+// untouched. It only applies to fields that are neither strings nor bools,
+// since for those an empty value is meaningful. A field can opt out with the
+// "allowempty" tag option.
+func WithEmptyAsMissing() Option {
+	return func(d *Decoder) {
+		d.emptyAsMissing = true
+	}
+}
+
+// WithKeyStyle sets how the keys of nested structs and maps are written in
+// the query string. It defaults to BracketKeys, and should match the style
+// used to encode the query.
+func WithKeyStyle(s KeyStyle) Option {
+	return func(d *Decoder) {
+		d.keyStyle = s
+	}
+}
+
+// A DecodeHook transforms the raw value of the key key before it is
+// converted to the type target. For slices, it is called with each element
+// and the element type.
+type DecodeHook func(key, raw string, target reflect.Type) (string, error)
+
+// WithDecodeHook adds hook to the decoder. Hooks run in the order they are
+// added, each on the value returned by the previous one, for every field,
+// including the fields of nested structs and the entries of maps. An error
+// returned by a hook aborts decoding.
+func WithDecodeHook(hook DecodeHook) Option {
+	return func(d *Decoder) {
+		d.hooks = append(d.hooks, hook)
+	}
+}
+
+// WithErrorHandler makes the decoder pass the error decoding each field to
+// handler, with the key of the field, instead of aborting. When handler
+// returns nil decoding goes on and the field is left at its zero value, so
+// that no part of the rejected value remains; otherwise decoding stops with
+// the returned error, which may differ from err.
+func WithErrorHandler(handler func(key string, err error) error) Option {
+	return func(d *Decoder) {
+		d.errorHandler = handler
+	}
+}
+
+// DefaultMaxDepth is the nesting depth of keys allowed by default.
+const DefaultMaxDepth = 5
+
+// A DepthExceededError describes a query key nested deeper than the maximum
+// depth of the decoder.
+type DepthExceededError struct {
+	Key string // key of the struct or map nested too deeply
+	Max int    // maximum depth
+}
+
+func (e *DepthExceededError) Error() string {
+	return "query: " + e.Key + " is nested deeper than " + strconv.Itoa(e.Max) + " levels"
+}
+
+// Is reports whether target is ErrTooDeep.
+func (e *DepthExceededError) Is(target error) bool {
+	return target == ErrTooDeep
+}
+
+// Fields returns the key of the struct or map with a message stating it is
+// nested too deeply.
+func (e *DepthExceededError) Fields() map[string]string {
+	return map[string]string{e.Key: "is nested too deeply"}
+}
+
+// MarshalJSON encodes the fields of the error.
+func (e *DepthExceededError) MarshalJSON() ([]byte, error) {
+	return marshalFields(e.Fields())
+}
+
+// WithMaxDepth sets the number of levels the keys of nested structs and
+// maps may be nested in, DefaultMaxDepth unless set. Decode fails with a
+// *DepthExceededError when a nested field deeper than n has keys in the
+// query, which guards against keys like "a[a][a]...=1" decoded into
+// recursive types. A depth of 0 or less removes the limit. FlatKeys don't
+// nest keys and are not limited.
+func WithMaxDepth(n int) Option {
+	return func(d *Decoder) {
+		d.maxDepth = n
+	}
+}
+
+// WithFallback makes the decoder read the keys absent from its query string
+// from vals, as if they were part of it. Precedence is decided key by key:
+// a key present in the query string hides all of its values in vals, and
+// the keys of a nested struct or map are taken from both when they differ.
+// For example, a query string of per-request parameters can override
+// defaults stored as another query string:
+//
+//	defaults, err := url.ParseQuery(stored)
+//	...
+//	err = query.NewDecoder(r.URL.RawQuery, query.WithFallback(defaults)).Decode(&opts)
+func WithFallback(vals url.Values) Option {
+	return func(d *Decoder) {
+		d.fallback = vals
+	}
+}
+
+// WithReuseSlices makes the decoder store the elements of a slice field in
+// its backing array when it has room for them, as for structs taken from a
+// pool with pre-sized slices, instead of in a new slice. The elements are
+// written in place, so the array must not be shared with other slices, such
+// as those of a template the struct was copied from, and a value that fails
+// to decode may leave the elements before it overwritten. Slices reached
+// through a pointer always get a new array.
+func WithReuseSlices() Option {
+	return func(d *Decoder) {
+		d.reuseSlices = true
+	}
+}
+
+// A DecodeOption configures a single call to Decode.
+type DecodeOption func(*Decoder)
+
+// Require makes the fields with the query keys keys required for a call to
+// Decode, as if they were tagged with the "required" option, so that the
+// same struct can have different required keys depending on the caller.
+// The keys of nested structs and maps are required to have at least one
+// key nested in them. A missing key makes Decode fail with the same
+// *MissingRequiredError as the tag. A key that no field reads makes Decode
+// fail, rather than being silently never missing.
+//
+//	err := dec.Decode(&opts, query.Require("account_id", "from"))
+func Require(keys ...string) DecodeOption {
+	return func(d *Decoder) {
+		d.required = append(d.required, keys...)
+	}
+}
+
+// checkRequired returns an error for the keys given to Require that no
+// field of the struct type t reads, which could never be found missing.
+func (d *Decoder) checkRequired(t reflect.Type) error {
+	if len(d.required) == 0 {
+		return nil
+	}
+	infos := d.fieldInfos(nil, t, "", "", make(map[reflect.Type]bool))
+	for _, key := range d.required {
+		if !slices.ContainsFunc(infos, func(f FieldInfo) bool { return f.Key == key }) {
+			return errors.New("query: required key " + strconv.Quote(key) + " is read by no field of " + t.String())
+		}
+	}
+	return nil
+}
+
+func (d *Decoder) requires(key string) bool {
+	for _, k := range d.required {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// NewDecoderBytes returns a new decoder that reads the query string b, as
+// NewDecoder does with string(b), without copying b to scan it. b must not
+// be modified while the decoder is in use; the decoded values don't retain
+// it.
+func NewDecoderBytes(b []byte, opts ...Option) *Decoder {
+	d := NewDecoder("", opts...)
+	if len(b) > 0 {
+		d.q, d.borrowed = unsafe.String(&b[0], len(b)), true
+	}
+	return d
+}
+
+// UnmarshalBytes decodes the query string b into the value pointed by v. It
+// is a shorthand for NewDecoderBytes(b, opts...).Decode(v).
+func UnmarshalBytes(b []byte, v interface{}, opts ...Option) error {
+	return NewDecoderBytes(b, opts...).Decode(v)
 }
 
 // NewDecoder returns a new decoder that read the given string.
-func NewDecoder(s string) *Decoder {
-	return &Decoder{s}
+//
+// The options set by SetDefaultOptions apply first, followed by opts.
+func NewDecoder(s string, opts ...Option) *Decoder {
+	d := &Decoder{q: s}
+	d.maxDepth = DefaultMaxDepth
+	if defaults := defaultOptions.Load(); defaults != nil {
+		for _, opt := range *defaults {
+			opt(d)
+		}
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// defaultOptions holds the options set by SetDefaultOptions. The slice is
+// never modified once stored, only replaced.
+var defaultOptions atomic.Pointer[[]Option]
+
+// SetDefaultOptions sets options that every decoder returned by NewDecoder,
+// and by the functions built on it, applies before its own, such as the
+// settings shared by the services of an organization. The options of a
+// decoder override them, or add to them for options that accumulate, such
+// as WithDecodeHook and WithGroups.
+//
+// It is meant to be called once at startup, but is safe to call at any
+// time: decoders already created keep the options they were created with,
+// and later calls replace the defaults, which SetDefaultOptions() clears.
+func SetDefaultOptions(opts ...Option) {
+	defaults := append([]Option(nil), opts...)
+	defaultOptions.Store(&defaults)
+}
+
+// HasDefaultOptions reports whether SetDefaultOptions has set options, such
+// as for code decoding queries without a Decoder to hand them to one when
+// it cannot apply them.
+func HasDefaultOptions() bool {
+	defaults := defaultOptions.Load()
+	return defaults != nil && len(*defaults) > 0
 }
 
 // Decode reads the query string from its input and stores it in the value pointed by v.
 // Note that v should specify with the a "q" tag every exportable field that
-// has a value in the query string.
-func (d *Decoder) Decode(v interface{}) error {
-	vals, err := url.ParseQuery(d.q)
-	if err != nil || len(vals) == 0 {
+// has a value in the query string. Nested structs and maps with string keys
+// are decoded from keys scoped by their field's key, written in the style set
+// by WithKeyStyle. Slices of them are decoded from keys scoped by an index,
+// as in accounts[0][id]=1&accounts[1][id]=2.
+//
+// Slices and arrays hold the values of their key in order, each decoded as
+// a field of the element type would be, through UnmarshalText for types
+// implementing encoding.TextUnmarshaler, such as netip.Addr. An array keeps
+// as many values as it has elements, leaving the others as they were.
+//
+// When the key of a field is absent, the value of its "default=value" tag
+// option is decoded instead; without one, a field tagged with the "required"
+// option makes Decode return a *MissingRequiredError. This holds for an
+// empty query string too, which leaves every key absent. Values are checked
+// against the "enum=a|b", "pattern=regexp", "min=n" and "max=n" tag options
+// before they are decoded, failing with a *ValidationError and leaving the
+// field as it was. A pattern matches any part of a value unless anchored, as
+// in "pattern=^[a-z]+$", and can't hold a comma.
+//
+// The values of a key replace those a field held before: a slice holds the
+// decoded elements alone, in a new backing array, so that the memory it
+// shared before the call, such as the array of a template the struct was
+// copied from, never changes. The new slice is only stored once every
+// element is decoded. A slice reached through a non-nil pointer, as by a
+// *[]int field, is written through the pointer as any other value is. The
+// WithReuseSlices option keeps the backing array of slice fields that have
+// room for the elements instead, for structs that own their arrays.
+// Maps get the decoded entries added to those they held. A map field is left
+// alone, nil if it was, when no key is nested in its own, while its key given
+// alone with an empty value, as in "tags=" or a bare "tags", sets it to an
+// empty map first, dropping the entries it held: a PATCH can thus tell
+// clearing the tags from leaving them untouched. Any other value of its key
+// alone is unknown.
+//
+// Integers and floats may be surrounded by spaces, such as the one "?n=+2"
+// arrives with, unless their field has the "strictnum" tag option, which
+// only accepts their canonical form: "2", but not " 2", "+2" or "02".
+// Integers of a field with the "base=n" tag option are read in that base,
+// in either case: "1F" and "1f" are 31 with base=16. Their "min" and "max"
+// options, written in base 10, bound the integer read.
+//
+// The values of a field tagged with transforms, such as "trim", "upper" or
+// "maxlen=10", or those registered with RegisterTransform, go through them
+// in the order of the tag before they are decoded and validated.
+//
+// A key without a value, as in "?flag" or "?flag=", sets a bool field to
+// true. A *bool field is set to a non-nil true or false when its key is
+// present and left nil when it is absent, or when its value is invalid, so
+// that it can tell a filter that is off from one that isn't given.
+//
+// v must be a non-nil pointer to a struct: Decode fails with an
+// *InvalidUnmarshalError when v is nil or not a pointer, and with an
+// *UnsupportedTypeError when it points to another type.
+//
+// The options opts only apply to this call.
+func (d *Decoder) Decode(v interface{}, opts ...DecodeOption) error {
+	if err := d.checkLength(); err != nil {
+		d.setWarnings(nil)
+		return err
+	}
+	if len(opts) == 0 {
+		if ok, err := d.decodeFlat(v); ok {
+			d.setWarnings(nil)
+			return err
+		}
+	}
+
+	call := d.newCall()
+	defer func() {
+		d.setWarnings(call.warnings)
+		d.release(call)
+	}()
+	for _, opt := range opts {
+		opt(call)
+	}
+	if call.stats != nil {
+		defer call.stats.finish(call, time.Now())
+	}
+
+	src, err := d.query()
+	if call.stats != nil {
+		call.stats.Keys = len(src)
+	}
+	if err != nil && !d.skipMalformed {
+		return err
+	}
+	if call.stats != nil {
+		call.stats.count(d.q, d.borrowed)
+	}
+	return call.unmarshal(src, v)
+}
+
+// DecodeValue decodes the query string into rv, as Decode does into the
+// value rv points to, for callers holding a reflect.Value: rv is either an
+// addressable struct, such as a field reached through a pointer, or a
+// pointer to one. Any other value, including an invalid one or one obtained
+// through unexported fields, fails with an *InvalidUnmarshalError.
+func (d *Decoder) DecodeValue(rv reflect.Value, opts ...DecodeOption) error {
+	switch {
+	case !rv.IsValid():
+		return &InvalidUnmarshalError{nil}
+	case !rv.CanInterface():
+		return &InvalidUnmarshalError{rv.Type()}
+	case rv.Kind() == reflect.Ptr:
+		return d.Decode(rv.Interface(), opts...)
+	case rv.CanAddr():
+		return d.Decode(rv.Addr().Interface(), opts...)
+	}
+	return &InvalidUnmarshalError{rv.Type()}
+}
+
+// checkTarget returns an *InvalidUnmarshalError when v is not a non-nil
+// pointer, or an *UnsupportedTypeError when it doesn't point to a struct,
+// whatever the query holds.
+func checkTarget(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+	if t := rv.Elem().Type(); t.Kind() != reflect.Struct {
+		return &UnsupportedTypeError{Type: t}
+	}
+	return nil
+}
+
+// query returns the query string of the decoder parsed, after the values
+// given by DecodeRequest, DecodeHeader or DecodeCookies, the first time it
+// is called.
+func (d *Decoder) query() (url.Values, error) {
+	d.parse.Do(func() {
+		q := d.q
+		if d.borrowed {
+			q = strings.Clone(q)
+		}
+		if d.interning {
+			d.src, d.parseErr = parseInterned(q)
+		} else {
+			d.src, d.parseErr = url.ParseQuery(q)
+		}
+		if d.given != nil {
+			src := make(url.Values, len(d.given)+len(d.src))
+			for k, vals := range d.given {
+				src[k] = append(src[k], vals...)
+			}
+			for k, vals := range d.src {
+				src[k] = append(src[k], vals...)
+			}
+			d.src = src
+		}
+		for k, vals := range d.fallback {
+			if _, ok := d.src[k]; !ok {
+				d.src[k] = vals
+			}
+		}
+	})
+	return d.src, d.parseErr
+}
+
+// ForEach calls fn with the key and value of each pair of the query string,
+// unescaped, in the order they are written in, which url.Values loses. It
+// stops at the first error returned by fn, and returns it. Pairs that Decode
+// rejects, such as the ones holding invalid escapes, are skipped, and the
+// first of their errors is returned after the last pair.
+//
+// With a decoder returned by NewDecoderBytes, key and value may share the
+// memory of its []byte, and must be copied to outlive changes to it.
+func (d *Decoder) ForEach(fn func(key, value string) error) error {
+	if err := d.checkLength(); err != nil {
 		return err
 	}
-	return d.unmarshal(vals, v)
+	return scanPairs(d.q, fn)
 }
 
 func (d *Decoder) unmarshal(src url.Values, v interface{}) (err error) {
@@ -82,108 +757,1410 @@ func (d *Decoder) unmarshal(src url.Values, v interface{}) (err error) {
 		}
 	}()
 
+	if err = checkTarget(v); err != nil {
+		return
+	}
 	rv := reflect.ValueOf(v)
-	if rv.Kind() != reflect.Ptr || rv.IsNil() {
-		return &InvalidUnmarshalError{reflect.TypeOf(v)}
+	if t := rv.Elem().Type(); t.Kind() == reflect.Struct {
+		if err = d.checkDuplicates(t); err != nil {
+			return
+		}
+		d.literals = d.literalKeys(t)
+		if err = d.checkRequired(t); err != nil {
+			return
+		}
+	}
+	if d.expectedSignature != nil {
+		if err = d.verifySignature(src, rv.Elem().Type()); err != nil {
+			return
+		}
+	}
+	if src, err = d.dropEmptyKey(src); err != nil {
+		return
+	}
+	if src, err = d.mask(src); err != nil {
+		return
+	}
+	if src, err = d.resolveConflicts(src); err != nil {
+		return
+	}
+	if err = d.checkGroups(src, d.groups, ""); err != nil {
+		return
+	}
+	if err = d.prepopulate(rv.Elem()); err != nil {
+		return
+	}
+	if err = d.values(src, rv.Elem(), rv.Elem().Type(), "", 0); err != nil {
+		return
+	}
+	if inlineFields(rv.Elem().Type()) {
+		d.inline(rv.Elem(), d.unclaimed(src, rv.Elem().Type()))
+		err = d.unknown(nil, nil)
+	} else {
+		err = d.unknown(src, rv.Elem().Type())
 	}
-	err = d.values(src, rv.Elem(), rv.Elem().Type())
 	return
 }
 
-func (d *Decoder) values(src url.Values, dst reflect.Value, dstType reflect.Type) error {
-	var (
-		vals []string
-		ok   bool
-	)
+// unknown warns about the keys of src that no field of the struct type t
+// reads, and the keys dropped by the key conflict rule, in sorted order, or
+// fails with an *UnknownKeyError for them with WithDisallowUnknownKeys.
+// Keys of src are only looked for when fewer keys than src holds were read.
+func (d *Decoder) unknown(src url.Values, t reflect.Type) error {
+	if d.read >= len(src) && len(d.conflicts) == 0 {
+		return nil
+	}
+
+	var keys []string
+	for k := range d.conflicts {
+		keys = append(keys, k)
+	}
+	if d.read < len(src) {
+		for k := range src {
+			if !d.claims(t, "", k) {
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	if d.stats != nil {
+		d.stats.Unknown = append(d.stats.Unknown, keys...)
+	}
+	for _, k := range keys {
+		vals, ok := src[k]
+		if !ok {
+			vals = d.conflicts[k]
+		}
+		var err error
+		if d.disallowUnknown {
+			err = &UnknownKeyError{Key: k}
+			if err := d.handle(k, err); err != nil {
+				return err
+			}
+		}
+		d.warn(k, strings.Join(vals, ","), WarnUnknownKey, err)
+	}
+	return nil
+}
+
+func (d *Decoder) warn(key, value, action string, err error) {
+	d.warnings = append(d.warnings, Warning{key, value, action, err})
+}
+
+// Warnings returns the values ignored by the last call to Decode instead of
+// failing, in the order they were found. It returns nil when there are none.
+// When Decode is called by several goroutines at once, the last call is the
+// one that returned last.
+func (d *Decoder) Warnings() []Warning {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.lastWarnings
+}
+
+func (d *Decoder) setWarnings(warnings []Warning) {
+	d.mu.Lock()
+	d.lastWarnings = warnings
+	d.mu.Unlock()
+}
+
+// values decodes src into the fields of the struct dst, of type dstType,
+// whose keys are scoped by scope, nested depth levels deep.
+func (d *Decoder) values(src url.Values, dst reflect.Value, dstType reflect.Type, scope string, depth int) error {
+	if d.keyStyle == FlatKeys {
+		d.stack = append(d.stack, dstType)
+		defer func() { d.stack = d.stack[:len(d.stack)-1] }()
+	}
 
 	for i := 0; i < dst.NumField(); i++ {
 		ft, fv := dstType.Field(i), dst.Field(i)
 
-		if vals, ok = src[ft.Tag.Get(tagKey)]; !ok {
+		if groups := fieldGroups(ft); groups != nil {
+			if err := d.checkGroups(src, groups, scope); err != nil {
+				return err
+			}
+			continue
+		}
+
+		key, opts, ok := d.fieldKey(ft, scope)
+		if !ok || opts.Contains("inline") {
+			continue
+		}
+		if opts.Contains("files") {
+			d.setFiles(fv, key)
 			continue
 		}
 
-		var addr = fv.Addr()
-		if fv.Kind() == reflect.Ptr {
-			if fv.IsNil() {
-				fv.Set(reflect.New(fv.Type().Elem()))
+		if isNestedSlice(ft.Type) {
+			if err := d.nestedSlice(src, fv, key, depth+1); err != nil {
+				return withField(err, ft.Name, key)
 			}
-			addr = fv
-			fv = fv.Elem()
+			continue
 		}
 
-		if u, ok := addr.Interface().(encoding.TextUnmarshaler); ok {
-			if vals[0] != "" {
-				if err := u.UnmarshalText([]byte(vals[0])); err != nil {
+		if isNested(ft.Type) {
+			level := depth
+			if key != scope {
+				level++
+			}
+			if d.recursive(ft.Type) {
+				continue
+			}
+			if d.requires(key) && !d.scopes(src, key) {
+				err := &MissingRequiredError{Key: key}
+				if err := d.handle(key, err); err != nil {
 					return err
 				}
+				d.warn(key, "", WarnMissingValue, err)
+				continue
+			}
+			if err := d.nested(src, fv, key, level); err != nil {
+				return withField(err, ft.Name, key)
 			}
 			continue
 		}
 
-		switch fv.Kind() {
-		case reflect.Slice, reflect.Array:
-			n := len(vals)
-			if fv.Kind() == reflect.Slice {
-				fv.Set(reflect.MakeSlice(fv.Type(), n, n))
+		if d.splitLists && opts.delimiter() == 0 {
+			opts = append(opts[:len(opts):len(opts)], "comma")
+		}
+
+		var vals []string
+		if from, to, split := d.keyStyle.rangeKeys(scope, opts); split {
+			var n int
+			vals, n = lookupRange(src, from, to)
+			ok, d.read = n > 0, d.read+n
+		} else if cur, split := d.keyStyle.currencyKey(scope, ft.Type, opts); split {
+			if vals, ok = lookupMoney(src, key, cur); ok {
+				d.read++
+			}
+		} else if vals, ok = lookup(src, key, ft.Type, opts); ok {
+			d.read += keyCount(vals, ft.Type, opts)
+		}
+		if ok && d.emptyAsMissing && !opts.Contains("allowempty") && !acceptsEmpty(ft.Type) {
+			if filled := nonEmpty(vals); len(filled) < len(vals) {
+				d.warn(key, "", WarnEmptyValue, nil)
+				vals = filled
 			}
-			for j := 0; j < fv.Len() && j < n; j++ {
-				if err := value(vals[j], fv.Index(j).Addr()); err != nil {
+			ok = len(vals) > 0
+		}
+		if ok {
+			rawInto(dst, vals, opts)
+		}
+		if !ok && d.presentOnly {
+			continue
+		}
+		if !ok {
+			if def, hasDefault := opts.Value("default"); hasDefault {
+				vals = defaultValues(def, ft.Type, opts)
+			} else if opts.Contains("required") || d.requires(key) {
+				err := &MissingRequiredError{Key: key}
+				if err := d.handle(key, err); err != nil {
 					return err
 				}
+				d.warn(key, "", WarnMissingValue, err)
+				continue
+			} else {
+				continue
 			}
-		case reflect.Struct, reflect.Map:
-			return &UnimplementerError{reflect.TypeOf(fv)}
-		default:
-			if err := value(vals[0], addr); err != nil {
+		}
+
+		if err := d.decodeField(key, vals, fv, ft.Type, opts); err != nil {
+			if _, ok := err.(*UnsupportedTypeError); ok {
+				return withField(err, ft.Name, key)
+			}
+			if err := d.handle(key, err); err != nil {
 				return err
 			}
+			d.warn(key, shown(strings.Join(vals, ","), opts), WarnInvalidValue, err)
+			fv.SetZero()
+		} else if ok && d.stats != nil {
+			d.stats.Fields++
 		}
 	}
 
 	return nil
 }
 
-// dst must be a pointer in order to use this function
-func value(src string, dst reflect.Value) (err error) {
-	el := dst.Elem()
-	switch el.Kind() {
-	case reflect.String:
-		el.SetString(src)
-	case reflect.Bool:
-		err = setBool(src, dst)
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		err = setUint(src, dst)
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		err = setInt(src, dst)
+// decodeField runs the hooks of the decoder on vals, the values of the key
+// key, stores them in the field fv of type t and validates them.
+func (d *Decoder) decodeField(key string, vals []string, fv reflect.Value, t reflect.Type, opts tagOptions) error {
+	if err := d.checkStrict(key, vals, t, opts); err != nil {
+		return err
+	}
+	vals, err := d.hook(key, d.lastValues(vals, t), t)
+	if err != nil {
+		return typeError(key, vals, t, opts, err)
+	}
+	vals, err = transform(vals, opts)
+	if err != nil {
+		return typeError(key, vals, t, opts, err)
+	}
+	vals, ok := d.lenientEnum(key, vals, opts)
+	if !ok {
+		return nil
+	}
+	if err := d.checkText(key, vals, t, opts); err != nil {
+		return err
+	}
+	if err := validate(key, vals, opts); err != nil {
+		if _, ok := err.(*strconv.NumError); ok {
+			return typeError(key, vals, t, opts, err)
+		}
+		return err
+	}
+	if err := d.field(vals, fv, opts); err != nil {
+		if _, ok := err.(*UnsupportedTypeError); ok {
+			return err
+		}
+		return typeError(key, vals, t, opts, err)
+	}
+	if d.finiteFloats && !finite(fv) {
+		return typeError(key, vals, t, opts, errNotFinite)
+	}
+	return validateRange(key, vals, fv, opts)
+}
+
+// typeError returns the error decoding vals, the values of the key key, into
+// a field of type t with the tag options opts. The values of secret fields
+// are redacted, and so is err, which may quote them.
+func typeError(key string, vals []string, t reflect.Type, opts tagOptions, err error) *UnmarshalTypeError {
+	if opts.Contains("secret") {
+		return &UnmarshalTypeError{Key: key, Value: redacted, Type: t, Err: errRedacted}
+	}
+	return &UnmarshalTypeError{Key: key, Value: strings.Join(vals, ","), Type: t, Err: err}
+}
+
+// errRedacted replaces the errors decoding secret fields.
+var errRedacted = errors.New("invalid value, redacted")
+
+// shown returns the value v of a field with the tag options opts as it may
+// be shown in errors and warnings: redacted for secret fields.
+func shown(v string, opts tagOptions) string {
+	if opts.Contains("secret") {
+		return redacted
+	}
+	return v
+}
+
+// handle passes err, the error decoding the field with key key, to the error
+// handler of the decoder. It returns the error that aborts decoding, if any.
+func (d *Decoder) handle(key string, err error) error {
+	if d.errorHandler == nil {
+		return err
+	}
+	return d.errorHandler(key, err)
+}
+
+// hook returns vals transformed by the hooks of the decoder, for a field of
+// type t with the key key. vals itself is left untouched.
+func (d *Decoder) hook(key string, vals []string, t reflect.Type) ([]string, error) {
+	if len(d.hooks) == 0 {
+		return vals, nil
+	}
+
+	target := t
+	if target.Kind() == reflect.Ptr {
+		target = target.Elem()
+	}
+	if (target.Kind() == reflect.Slice || target.Kind() == reflect.Array) && !unmarshaler(target) {
+		target = target.Elem()
+	}
+
+	out := make([]string, len(vals))
+	for i, raw := range vals {
+		for _, h := range d.hooks {
+			var err error
+			if raw, err = h(key, raw, target); err != nil {
+				return vals, err
+			}
+		}
+		out[i] = raw
+	}
+	return out, nil
+}
+
+// field stores vals in fv, which must be addressable, according to the tag
+// options opts. A nil pointer is allocated, and set back to nil when vals
+// can't be stored. Slices and arrays are decoded into a new value, set to fv
+// once every element is decoded, except for slices reusing their backing
+// array with WithReuseSlices.
+func (d *Decoder) field(vals []string, fv reflect.Value, opts tagOptions) (err error) {
+	if isBig(fv.Type()) {
+		return value(vals[0], fv.Addr(), opts, d.location)
+	}
+
+	var addr = fv.Addr()
+	shared := false
+	if fv.Kind() == reflect.Ptr {
+		shared = !fv.IsNil()
+		if fv.IsNil() {
+			ptr := fv
+			ptr.Set(reflect.New(fv.Type().Elem()))
+			defer func() {
+				if err != nil {
+					ptr.SetZero()
+				}
+			}()
+		}
+		addr = fv
+		fv = fv.Elem()
+	}
+
+	if s, ok := addr.Interface().(*StringSet); ok {
+		s.decode(vals, opts.Contains("fold"))
+		return nil
+	}
+
+	if r, ok := addr.Interface().(*TimeRange); ok {
+		return r.decode(vals[0], opts, d.location)
+	}
+
+	if m, ok := addr.Interface().(*Money); ok {
+		return m.decode(vals, opts)
+	}
+
+	if f, ok := addr.Interface().(*Flag); ok {
+		return f.UnmarshalText([]byte(vals[0]))
+	}
+
+	if c := converter(fv.Type()); c != nil {
+		return c.Decode(vals, fv)
+	}
+
+	if u, ok := addr.Interface().(Unmarshaler); ok {
+		return u.UnmarshalQuery(vals)
+	}
+
+	if u, ok := addr.Interface().(encoding.TextUnmarshaler); ok && fv.Type() != timeType {
+		if vals[0] != "" {
+			return u.UnmarshalText([]byte(vals[0]))
+		}
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.Slice, reflect.Array:
+		n := len(vals)
+		var dst reflect.Value
+		switch {
+		case fv.Kind() == reflect.Array:
+			dst = reflect.New(fv.Type()).Elem()
+			dst.Set(fv)
+		case d.reuseSlices && !shared && !fv.IsNil() && fv.Cap() >= n:
+			fv.SetLen(n)
+			fv.Clear()
+			dst = fv
+		default:
+			dst = reflect.MakeSlice(fv.Type(), n, n)
+		}
+		for j := 0; j < dst.Len() && j < n; j++ {
+			if err := elemValue(vals[j], dst.Index(j).Addr(), opts, d.location); err != nil {
+				return err
+			}
+		}
+		fv.Set(dst)
+		return nil
+	default:
+		return value(vals[0], addr, opts, d.location)
+	}
+}
+
+// elemValue decodes src into the element of a slice or array pointed by dst,
+// as value does, or through its UnmarshalText method for the other types
+// implementing encoding.TextUnmarshaler, such as netip.Addr. An empty src
+// leaves such an element unchanged, as it does a field.
+func elemValue(src string, dst reflect.Value, opts tagOptions, loc *time.Location) error {
+	t := dst.Type().Elem()
+	u, ok := dst.Interface().(encoding.TextUnmarshaler)
+	if !ok || t == timeType || t == dateType || t == numberType || isBig(t) {
+		return value(src, dst, opts, loc)
+	}
+	if src == "" {
+		return nil
+	}
+	return u.UnmarshalText([]byte(src))
+}
+
+// fieldKey returns the query key of the struct field sf nested in scope, and
+// its tag options. It reports false for fields the decoder ignores: those
+// without a "q" tag or tagged with "-". Untagged embedded structs share the
+// scope of their parent.
+func (d *Decoder) fieldKey(sf reflect.StructField, scope string) (string, tagOptions, bool) {
+	tag, ok := sf.Tag.Lookup(TagKey)
+	if tag == "-" {
+		return "", nil, false
+	}
+	name, opts := cachedTag(tag)
+	if name == "" {
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && isNested(sf.Type) {
+			return scope, opts, true
+		}
+		if !ok || scope != "" {
+			return "", nil, false
+		}
+	}
+	key := d.keyStyle.join(scope, name)
+	if d.canonicalKey != nil {
+		key = d.canonicalKey(key)
+	}
+	return key, opts, true
+}
+
+// nested decodes the struct or map field fv, whose query keys are scoped by
+// key. A nil pointer is only allocated when at least one of those keys is
+// present in src.
+func (d *Decoder) nested(src url.Values, fv reflect.Value, key string, depth int) error {
+	if d.exceeds(depth) {
+		if d.scopes(src, key) {
+			return &DepthExceededError{Key: key, Max: d.maxDepth}
+		}
+		return nil
+	}
+
+	if fv.Kind() == reflect.Ptr {
+		if !d.present(src, fv.Type().Elem(), key, depth) {
+			return nil
+		}
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		fv = fv.Elem()
+	}
+
+	if fv.Kind() == reflect.Map {
+		if vals, ok := src[key]; ok && d.emptyMap(vals) {
+			fv.Set(reflect.MakeMap(fv.Type()))
+			d.read++
+			d.cleared = append(d.cleared, key)
+		}
+		return d.mapValues(src, fv, key, depth)
+	}
+	return d.values(src, fv, fv.Type(), key, depth)
+}
+
+// emptyMap reports whether vals, the values of the key of a map field given
+// alone, set the field to an empty map: they are all empty, as in "tags=" or
+// a bare "tags", unless WithEmptyAsMissing makes them absent.
+func (d *Decoder) emptyMap(vals []string) bool {
+	if d.emptyAsMissing {
+		return false
+	}
+	for _, v := range vals {
+		if v != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// nestedSlice decodes the slice field fv, whose elements are structs or maps,
+// from the keys of src nested in key by an index, as in accounts[0][id]. The
+// elements are ordered by index, gaps between indexes being dropped as for
+// the "indexed" tag option, and each sits one level deeper than the slice.
+// The slice is left alone when no key is nested in key.
+func (d *Decoder) nestedSlice(src url.Values, fv reflect.Value, key string, depth int) error {
+	if d.exceeds(depth) {
+		if d.scopes(src, key) {
+			return &DepthExceededError{Key: key, Max: d.maxDepth}
+		}
+		return nil
+	}
+	if fv.Kind() == reflect.Ptr {
+		if !d.scopes(src, key) {
+			return nil
+		}
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		fv = fv.Elem()
+	}
+	if d.keyStyle == FlatKeys && fv.Type().Elem().Kind() != reflect.Map {
+		return &UnsupportedTypeError{Type: fv.Type()}
+	}
+
+	var indexes []int
+	for k := range src {
+		name, rest, ok := d.keyStyle.segment(k, key)
+		if !ok || rest == "" || d.literal(k, key) {
+			continue
+		}
+		if i, ok := parseIndex(name); ok {
+			indexes = append(indexes, i)
+		}
+	}
+	if len(indexes) == 0 {
+		return nil
+	}
+	sort.Ints(indexes)
+	indexes = slices.Compact(indexes)
+
+	elems := reflect.MakeSlice(fv.Type(), len(indexes), len(indexes))
+	for j, i := range indexes {
+		scope := d.keyStyle.joinMap(key, strconv.Itoa(i))
+		if err := d.nested(src, elems.Index(j), scope, depth+1); err != nil {
+			return err
+		}
+	}
+	fv.Set(elems)
+	return nil
+}
+
+// exceeds reports whether keys nested depth levels deep are beyond the
+// maximum depth. FlatKeys don't nest keys, and have no maximum depth.
+func (d *Decoder) exceeds(depth int) bool {
+	return d.maxDepth > 0 && depth > d.maxDepth && d.keyStyle != FlatKeys
+}
+
+// recursive reports whether the nested field type t is a struct being
+// decoded already. With FlatKeys, its keys can't be told apart from the keys
+// of the outer struct, and it is left alone.
+func (d *Decoder) recursive(t reflect.Type) bool {
+	if d.keyStyle != FlatKeys {
+		return false
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for _, s := range d.stack {
+		if s == t {
+			return true
+		}
+	}
+	return false
+}
+
+// present reports whether src holds any key of a struct or map of type t
+// scoped by key, nested depth levels deep. Beyond the maximum depth, any key
+// in the scope counts; with FlatKeys, recursive types are not looked into.
+func (d *Decoder) present(src url.Values, t reflect.Type, key string, depth int) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if d.exceeds(depth) {
+		return d.scopes(src, key)
+	}
+	if d.keyStyle == FlatKeys && t.Kind() == reflect.Struct {
+		d.stack = append(d.stack, t)
+		defer func() { d.stack = d.stack[:len(d.stack)-1] }()
+	}
+
+	if t.Kind() == reflect.Map {
+		if vals, ok := src[key]; ok && d.emptyMap(vals) {
+			return true
+		}
+	}
+	if !d.scopes(src, key) {
+		return false
+	}
+
+	if t.Kind() == reflect.Map {
+		for k := range src {
+			if d.mapClaims(t, key, k) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fk, _, ok := d.fieldKey(sf, key)
+		if !ok {
+			continue
+		}
+		if isNestedSlice(sf.Type) {
+			if d.scopes(src, fk) {
+				return true
+			}
+		} else if isNested(sf.Type) {
+			level := depth
+			if fk != key {
+				level++
+			}
+			if d.recursive(sf.Type) {
+				continue
+			}
+			if d.present(src, sf.Type, fk, level) {
+				return true
+			}
+		} else if _, ok := src[fk]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mapValues decodes into the map fv every entry of src whose key is scoped
+// by key. Entries already in the map are kept unless overwritten.
+func (d *Decoder) mapValues(src url.Values, fv reflect.Value, key string, depth int) error {
+	t := fv.Type()
+	if !mapKeyKind(t.Key().Kind()) {
+		return &UnsupportedTypeError{Type: t}
+	}
+	if t.Elem().Kind() == reflect.Map {
+		return d.mapMaps(src, fv, key, depth)
+	}
+	if isNested(t.Elem()) {
+		return &UnsupportedTypeError{Type: t}
+	}
+
+	names := d.names[:0]
+	for k := range src {
+		if _, ok := d.keyStyle.mapKey(k, key); ok && !d.literal(k, key) {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	d.names = names
+	d.read += len(names)
+
+	var ev, kv reflect.Value
+	for _, k := range names {
+		name, _ := d.keyStyle.mapKey(k, key)
+		kv = zeroElem(kv, t.Key())
+		err := value(name, kv.Addr(), nil, nil)
+		if err != nil {
+			err = &UnmarshalTypeError{Key: k, Value: name, Type: t.Key(), Err: err}
+		}
+		var vals []string
+		if err == nil {
+			vals, err = d.hook(k, d.lastValues(src[k], t.Elem()), t.Elem())
+		}
+		if err == nil {
+			err = d.checkText(k, vals, t.Elem(), nil)
+		}
+		ev = zeroElem(ev, t.Elem())
+		if err == nil {
+			err = d.field(vals, ev, nil)
+			if _, ok := err.(*UnsupportedTypeError); ok {
+				err = withField(err, "", k)
+			} else if err != nil {
+				err = typeError(k, vals, t.Elem(), nil, err)
+			}
+		}
+		if err != nil {
+			if herr := d.handle(k, err); herr != nil {
+				return herr
+			}
+			d.warn(k, strings.Join(src[k], ","), WarnInvalidValue, err)
+			continue
+		}
+		if fv.IsNil() {
+			fv.Set(reflect.MakeMap(t))
+		}
+		fv.SetMapIndex(kv, ev)
+	}
+	return nil
+}
+
+// mapMaps decodes into the map fv, whose values are maps, the entries of src
+// nested two levels in key, such as meta[a][x] for the entry x of the map a.
+// Inner maps are only allocated when they have entries, and sit one level
+// deeper than fv. Maps of maps of maps are not supported.
+func (d *Decoder) mapMaps(src url.Values, fv reflect.Value, key string, depth int) error {
+	t := fv.Type()
+	if inner := t.Elem(); !mapKeyKind(inner.Key().Kind()) || isNested(inner.Elem()) {
+		return &UnsupportedTypeError{Type: inner, Key: d.keyStyle.joinMap(key, "*")}
+	}
+
+	var names []string
+	for k := range src {
+		if name, ok := d.keyStyle.mapScope(k, key); ok && !d.literal(k, key) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	names = slices.Compact(names)
+
+	kv := reflect.New(t.Key()).Elem()
+	for _, name := range names {
+		scope := d.keyStyle.joinMap(key, name)
+		if d.exceeds(depth + 1) {
+			return &DepthExceededError{Key: scope, Max: d.maxDepth}
+		}
+		if err := value(name, kv.Addr(), nil, nil); err != nil {
+			err = &UnmarshalTypeError{Key: scope, Value: name, Type: t.Key(), Err: err}
+			if herr := d.handle(scope, err); herr != nil {
+				return herr
+			}
+			d.warn(scope, "", WarnInvalidValue, err)
+			continue
+		}
+		inner := reflect.New(t.Elem()).Elem()
+		if m := fv.MapIndex(kv); m.IsValid() {
+			inner.Set(m)
+		}
+		if err := d.mapValues(src, inner, scope, depth+1); err != nil {
+			return err
+		}
+		if inner.IsNil() {
+			continue
+		}
+		if fv.IsNil() {
+			fv.Set(reflect.MakeMap(t))
+		}
+		fv.SetMapIndex(kv, inner)
+	}
+	return nil
+}
+
+// inline stores rest in the fields of dst, and of its embedded structs,
+// tagged with the "inline" option.
+func (d *Decoder) inline(dst reflect.Value, rest url.Values) {
+	if len(rest) == 0 {
+		return
+	}
+
+	for i := 0; i < dst.NumField(); i++ {
+		sf, fv := dst.Type().Field(i), dst.Field(i)
+		tag := sf.Tag.Get(TagKey)
+		if tag == "-" {
+			continue
+		}
+		name, opts := cachedTag(tag)
+		if name == "" && sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			d.inline(fv, rest)
+			continue
+		}
+		if !opts.Contains("inline") || !isInlineMap(sf.Type) {
+			continue
+		}
+
+		if fv.IsNil() {
+			fv.Set(reflect.MakeMapWithSize(sf.Type, len(rest)))
+		}
+		for k, vals := range rest {
+			fv.SetMapIndex(reflect.ValueOf(k).Convert(sf.Type.Key()), reflect.ValueOf(vals).Convert(sf.Type.Elem()))
+		}
+	}
+}
+
+// inlineFields reports whether the struct type t, or one of its embedded
+// structs, has a field tagged with the "inline" option.
+func inlineFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, opts := cachedTag(sf.Tag.Get(TagKey))
+		if opts.Contains("inline") && isInlineMap(sf.Type) {
+			return true
+		}
+		if name == "" && sf.Anonymous && sf.Type.Kind() == reflect.Struct && inlineFields(sf.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// unclaimed returns the values of src whose key no field of the struct type
+// t reads.
+func (d *Decoder) unclaimed(src url.Values, t reflect.Type) url.Values {
+	var rest url.Values
+	for k, vals := range src {
+		if d.claims(t, "", k) {
+			continue
+		}
+		if rest == nil {
+			rest = make(url.Values)
+		}
+		rest[k] = append([]string(nil), vals...)
+	}
+	return rest
+}
+
+// claims reports whether a field of the struct type t, scoped by scope,
+// reads key.
+func (d *Decoder) claims(t reflect.Type, scope, key string) bool {
+	if d.keyStyle == FlatKeys {
+		d.stack = append(d.stack, t)
+		defer func() { d.stack = d.stack[:len(d.stack)-1] }()
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fk, opts, ok := d.fieldKey(sf, scope)
+		if !ok || opts.Contains("inline") || opts.Contains("files") {
+			continue
+		}
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch {
+		case isNestedSlice(ft):
+			if d.sliceClaims(ft.Elem(), fk, key) {
+				return true
+			}
+		case ft.Kind() == reflect.Map && isNested(ft):
+			if d.mapClaims(ft, fk, key) || key == fk && slices.Contains(d.cleared, key) {
+				return true
+			}
+		case isNested(ft):
+			if d.recursive(ft) {
+				continue
+			}
+			if fk == scope || d.keyStyle == FlatKeys || d.keyStyle.nests(key, fk) {
+				if d.claims(ft, fk, key) {
+					return true
+				}
+			}
+		default:
+			if from, to, split := d.keyStyle.rangeKeys(scope, opts); split {
+				if key == from || key == to {
+					return true
+				}
+			} else if cur, split := d.keyStyle.currencyKey(scope, sf.Type, opts); split && key == cur {
+				return true
+			} else if reads(key, fk, sf.Type, opts) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mapClaims reports whether key is an entry of the map of type t scoped by
+// scope, or of a map in it.
+func (d *Decoder) mapClaims(t reflect.Type, scope, key string) bool {
+	if t.Elem().Kind() != reflect.Map {
+		_, ok := d.keyStyle.mapKey(key, scope)
+		return ok
+	}
+	name, ok := d.keyStyle.mapScope(key, scope)
+	return ok && d.mapClaims(t.Elem(), d.keyStyle.joinMap(scope, name), key)
+}
+
+// sliceClaims reports whether the element of type t of a slice scoped by
+// scope reads the query key key.
+func (d *Decoder) sliceClaims(t reflect.Type, scope, key string) bool {
+	name, rest, ok := d.keyStyle.segment(key, scope)
+	if !ok || rest == "" {
+		return false
+	}
+	if _, ok := parseIndex(name); !ok {
+		return false
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	scope = d.keyStyle.joinMap(scope, name)
+	if t.Kind() == reflect.Map {
+		return d.mapClaims(t, scope, key)
+	}
+	return d.claims(t, scope, key)
+}
+
+// mapKeyKind reports whether the decoder can read the keys of maps of kind
+// k from the query: strings and integers.
+func mapKeyKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// isInlineMap reports whether t can hold the values of several keys, as
+// url.Values does.
+func isInlineMap(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String &&
+		t.Elem().Kind() == reflect.Slice && t.Elem().Elem().Kind() == reflect.String
+}
+
+// isNested reports whether fields of type t hold a struct or map decoded from
+// several scoped keys rather than from the values of a single key.
+func isNested(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Map {
+		return true
+	}
+	return t.Kind() == reflect.Struct && !unmarshaler(t)
+}
+
+// isNestedSlice reports whether fields of type t hold a slice of structs or
+// maps, or of pointers to them, each decoded from keys scoped by its index.
+func isNestedSlice(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Slice && !unmarshaler(t) && isNested(t.Elem())
+}
+
+// unmarshaler reports whether *t implements Unmarshaler or
+// encoding.TextUnmarshaler, or t has a registered Converter.
+func unmarshaler(t reflect.Type) bool {
+	p := reflect.PtrTo(t)
+	return p.Implements(unmarshalerType) || p.Implements(textUnmarshalerType) || converter(t) != nil
+}
+
+// lookup returns the values of key in src. For slice and array fields they are
+// gathered according to the format selected by the tag options, mirroring
+// how the encoder writes them.
+func lookup(src url.Values, key string, t reflect.Type, opts tagOptions) ([]string, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if !listType(t) {
+		vals, ok := src[key]
+		return vals, ok
+	}
+
+	switch {
+	case opts.delimiter() != 0:
+		vals, ok := src[key]
+		if !ok {
+			return nil, false
+		}
+		var elems []string
+		for _, v := range vals {
+			elems = append(elems, splitElems(v, opts.delimiter())...)
+		}
+		return elems, true
+	case opts.Contains("brackets"):
+		vals, ok := src[key+"[]"]
+		return vals, ok
+	case opts.Contains("numbered"):
+		return indexed(src, key, "")
+	case opts.Contains("indexed"):
+		return indexed(src, key, "[")
+	}
+	vals, ok := src[key]
+	return vals, ok
+}
+
+// defaultValues returns the values of the "default" tag option def for a
+// field of type t, split into elements for the delimited slice formats.
+func defaultValues(def string, t reflect.Type, opts tagOptions) []string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if sep := opts.delimiter(); sep != 0 && listType(t) {
+		return splitElems(def, sep)
+	}
+	return []string{def}
+}
+
+// indexed returns the values of the keys of src made of key, open and an
+// index (followed by "]" when open is "["), ordered by index. Gaps between
+// indexes are dropped.
+func indexed(src url.Values, key, open string) ([]string, bool) {
+	type elem struct {
+		i   int
+		val string
+	}
+
+	var elems []elem
+	for k, vals := range src {
+		if i, ok := elemIndex(k, key, open); ok {
+			elems = append(elems, elem{i, vals[0]})
+		}
+	}
+	if len(elems) == 0 {
+		return nil, false
+	}
+
+	sort.Slice(elems, func(a, b int) bool { return elems[a].i < elems[b].i })
+	vals := make([]string, len(elems))
+	for j, e := range elems {
+		vals[j] = e.val
+	}
+	return vals, true
+}
+
+// keyCount returns the number of keys of the query holding vals, the values
+// returned by lookup for a field of type t with the tag options opts.
+func keyCount(vals []string, t reflect.Type, opts tagOptions) int {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if listType(t) && opts.delimiter() == 0 &&
+		(opts.Contains("numbered") || opts.Contains("indexed")) {
+		return len(vals)
+	}
+	return 1
+}
+
+// elemIndex returns the index of the slice element that k refers to, when k
+// is made of key, open and an index (followed by "]" when open is "[").
+func elemIndex(k, key, open string) (int, bool) {
+	if !strings.HasPrefix(k, key) || !strings.HasPrefix(k[len(key):], open) {
+		return 0, false
+	}
+	s := k[len(key)+len(open):]
+	if open == "[" {
+		if !strings.HasSuffix(s, "]") {
+			return 0, false
+		}
+		s = s[:len(s)-1]
+	}
+	return parseIndex(s)
+}
+
+// reads reports whether a field of type t with the key fk and the tag
+// options opts reads the query key key, as lookup does.
+func reads(key, fk string, t reflect.Type, opts tagOptions) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Array || unmarshaler(t) || opts.delimiter() != 0 {
+		return key == fk
+	}
+	switch {
+	case opts.Contains("brackets"):
+		return len(key) == len(fk)+2 && strings.HasPrefix(key, fk) && strings.HasSuffix(key, "[]")
+	case opts.Contains("numbered"):
+		_, ok := elemIndex(key, fk, "")
+		return ok
+	case opts.Contains("indexed"):
+		_, ok := elemIndex(key, fk, "[")
+		return ok
+	}
+	return key == fk
+}
+
+// splitElems splits a delimited list written by the encoder on every del not
+// escaped by a backslash, and unescapes its elements. An empty string holds
+// no elements.
+func splitElems(s string, del byte) []string {
+	if s == "" {
+		return nil
+	}
+
+	var (
+		elems []string
+		b     strings.Builder
+	)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		case s[i] == del:
+			elems = append(elems, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return append(elems, b.String())
+}
+
+// acceptsEmpty reports whether an empty query value is meaningful for fields
+// of type t, which is the case for strings and bools (or pointers, slices and
+// arrays of them).
+func acceptsEmpty(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.String || t.Kind() == reflect.Bool
+}
+
+// nonEmpty returns vals without its empty elements.
+func nonEmpty(vals []string) []string {
+	out := vals[:0:0]
+	for _, v := range vals {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// dst must be a pointer in order to use this function
+func value(src string, dst reflect.Value, opts tagOptions, loc *time.Location) (err error) {
+	el := dst.Elem()
+	if isBig(el.Type()) {
+		return setBig(src, el, opts)
+	}
+	switch el.Type() {
+	case timeType:
+		return setTime(src, dst, opts, loc)
+	case durationType:
+		return setDuration(src, dst, opts)
+	case dateType:
+		return setDate(src, dst)
+	case numberType:
+		return setNumber(src, dst)
+	}
+
+	switch el.Kind() {
+	case reflect.String:
+		el.SetString(src)
+	case reflect.Bool:
+		err = setBool(src, dst)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if src, err = numText(src, opts); err == nil {
+			err = setUint(src, dst, opts)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if src, err = numText(src, opts); err == nil {
+			err = setInt(src, dst, opts)
+		}
 	case reflect.Float32, reflect.Float64:
-		err = setFloat(src, dst)
+		if src, err = numText(src, opts); err == nil {
+			err = setFloat(src, dst)
+		}
 	default:
-		err = &UnimplementerError{reflect.TypeOf(el)}
+		err = &UnsupportedTypeError{Type: el.Type()}
 	}
 	return
 }
 
-func setUint(src string, dst reflect.Value) error {
-	el := dst.Elem()
-	if val, err := strconv.ParseUint(src, 10, el.Type().Bits()); err == nil {
-		el.SetUint(val)
-	} else {
+func setTime(src string, dst reflect.Value, opts tagOptions, loc *time.Location) error {
+	if src == "" {
+		return nil
+	}
+
+	val, err := parseTime(src, opts, loc)
+	if err != nil {
+		return err
+	}
+	dst.Elem().Set(reflect.ValueOf(val))
+	return nil
+}
+
+func setDuration(src string, dst reflect.Value, opts tagOptions) error {
+	val, err := parseDuration(src, opts)
+	if err != nil {
 		return err
 	}
+	dst.Elem().SetInt(int64(val))
 	return nil
 }
 
-func setInt(src string, dst reflect.Value) error {
+// errNotCanonical is the error of a number that the "strictnum" tag option
+// rejects.
+var errNotCanonical = errors.New("not a number in canonical form")
+
+// numText returns the number src as it is parsed into an integer or float
+// field: without surrounding spaces, as the + of "?n=+2" arrives as a space.
+// With the "strictnum" tag option, src must instead be in canonical form,
+// and fails with errNotCanonical otherwise: a decimal number without spaces,
+// a + sign or leading zeros.
+func numText(src string, opts tagOptions) (string, error) {
+	if !opts.Contains("strictnum") {
+		return strings.TrimSpace(src), nil
+	}
+	if !canonicalNumber(src) {
+		return "", errNotCanonical
+	}
+	return src, nil
+}
+
+// canonicalNumber reports whether s is a decimal number in canonical form:
+// an optional minus sign, an integer part without leading zeros, and an
+// optional fraction and exponent, as in "-12.5" or "1e6".
+func canonicalNumber(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	if !isNumber(s) || !isDigit(s[0]) {
+		return false
+	}
+	units, _, _ := strings.Cut(strings.ToLower(s), "e")
+	units, _, _ = strings.Cut(units, ".")
+	return units == "0" || units[0] != '0'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func setUint(src string, dst reflect.Value, opts tagOptions) error {
 	el := dst.Elem()
-	if val, err := strconv.ParseInt(src, 10, el.Type().Bits()); err == nil {
-		el.SetInt(val)
+	n, err := unscale(src, opts)
+	if err != nil {
+		return numError(err, "ParseUint", src)
+	}
+	val, err := strconv.ParseUint(n, radix(opts), el.Type().Bits())
+	if l, ok := lenientInteger(src, opts); err != nil && ok {
+		n = l
+		val, err = strconv.ParseUint(n, 10, el.Type().Bits())
+	}
+	if err != nil {
+		return numError(intBounds(err, n, el.Type(), opts), "ParseUint", src)
+	}
+	el.SetUint(val)
+	return nil
+}
+
+func setInt(src string, dst reflect.Value, opts tagOptions) error {
+	el := dst.Elem()
+	n, err := unscale(src, opts)
+	if err != nil {
+		return numError(err, "ParseInt", src)
+	}
+	val, err := strconv.ParseInt(n, radix(opts), el.Type().Bits())
+	if l, ok := lenientInteger(src, opts); err != nil && ok {
+		n = l
+		val, err = strconv.ParseInt(n, 10, el.Type().Bits())
+	}
+	if err != nil {
+		return numError(intBounds(err, n, el.Type(), opts), "ParseInt", src)
+	}
+	el.SetInt(val)
+	return nil
+}
+
+// lenientInteger returns the integer written by src as a float literal, when
+// opts has the "lenientint" option: "3.0" or "3." for 3, but not "3.5".
+// Given an epsilon, as in "lenientint=1e-9", any float within it of an
+// integer is rounded to it, half to even, except floats written with an
+// exponent beyond 2^53, which a float64 can't round exactly.
+func lenientInteger(src string, opts tagOptions) (string, bool) {
+	eps, rounds := opts.Value("lenientint")
+	if !rounds && !opts.Contains("lenientint") {
+		return "", false
+	}
+	if i := strings.IndexByte(src, '.'); i > 0 && strings.Trim(src[i+1:], "0") == "" {
+		return src[:i], true
+	}
+	if !rounds {
+		return "", false
+	}
+	e, err := strconv.ParseFloat(eps, 64)
+	if err != nil {
+		return "", false
+	}
+	if !strings.ContainsAny(src, "eEpPxX") {
+		return roundDecimal(src, e)
+	}
+	// Floats with an exponent are rounded as float64, which holds every
+	// integer up to 2^53 but not all of those beyond.
+	f, err := strconv.ParseFloat(src, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) || math.Abs(f) > 1<<53 {
+		return "", false
+	}
+	n := math.RoundToEven(f)
+	if math.Abs(f-n) > e {
+		return "", false
+	}
+	return strconv.FormatFloat(n, 'f', 0, 64), true
+}
+
+// roundDecimal rounds the decimal literal src, such as "-12.0000001", to the
+// nearest integer, half to even, when it is within eps of it. It rounds the
+// digits of src, so that integers beyond the precision of a float64 are kept
+// exactly.
+func roundDecimal(src string, eps float64) (string, bool) {
+	sign, digits := "", src
+	if strings.HasPrefix(src, "-") || strings.HasPrefix(src, "+") {
+		sign, digits = src[:1], src[1:]
+	}
+	whole, frac, _ := strings.Cut(digits, ".")
+	if whole == "" {
+		whole = "0"
+	}
+	if !isDigits(whole) || frac != "" && !isDigits(frac) {
+		return "", false
+	}
+	f, err := strconv.ParseFloat("0."+frac, 64)
+	if err != nil {
+		return "", false
+	}
+	up := f > 0.5
+	if strings.TrimRight(frac, "0") == "5" {
+		up = (whole[len(whole)-1]-'0')%2 == 1
+	}
+	dist := f
+	if up {
+		dist = 1 - f
+	}
+	if dist > eps {
+		return "", false
+	}
+	if !up {
+		return sign + whole, true
+	}
+	n := []byte(whole)
+	i := len(n) - 1
+	for ; i >= 0 && n[i] == '9'; i-- {
+		n[i] = '0'
+	}
+	if i < 0 {
+		n = append([]byte{'1'}, n...)
 	} else {
+		n[i]++
+	}
+	return sign + string(n), true
+}
+
+// A boundError is the error of an integer out of the bounds of its field,
+// such as a negative value for an unsigned field. It unwraps to the error
+// strconv reports for it.
+type boundError struct {
+	msg string
+	err error
+}
+
+func (e *boundError) Error() string {
+	return e.msg
+}
+
+func (e *boundError) Unwrap() error {
+	return e.err
+}
+
+// intBounds returns err, the error parsing the integer literal n into a
+// field of type t with the tag options opts, as a *boundError when n is
+// negative for an unsigned field or out of the range of t. The bounds are
+// written in the unit of the query, shifted by the "scale" option.
+func intBounds(err error, n string, t reflect.Type, opts tagOptions) error {
+	ne, ok := err.(*strconv.NumError)
+	if !ok {
 		return err
 	}
-	return nil
+	places, _ := scale(opts)
+	shift := uint(64 - t.Bits())
+	neg := strings.HasPrefix(n, "-")
+	switch unsigned := t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uintptr; {
+	case unsigned && neg && isDigitsIn(n[1:], radix(opts)):
+		return &boundError{msg: "value must be a non-negative integer", err: ne.Err}
+	case ne.Err != strconv.ErrRange:
+		return err
+	case neg:
+		min := unshiftDecimal(strconv.FormatInt(math.MinInt64>>shift, 10), places)
+		if places == 0 {
+			min = formatInt(math.MinInt64>>shift, opts)
+		}
+		return &boundError{msg: "value is below minimum " + min + " for this parameter", err: ne.Err}
+	case unsigned:
+		max := unshiftDecimal(strconv.FormatUint(math.MaxUint64>>shift, 10), places)
+		if places == 0 {
+			max = formatUint(math.MaxUint64>>shift, opts)
+		}
+		return &boundError{msg: "value exceeds maximum " + max + " for this parameter", err: ne.Err}
+	default:
+		max := unshiftDecimal(strconv.FormatInt(math.MaxInt64>>shift, 10), places)
+		if places == 0 {
+			max = formatInt(math.MaxInt64>>shift, opts)
+		}
+		return &boundError{msg: "value exceeds maximum " + max + " for this parameter", err: ne.Err}
+	}
+}
+
+// numError returns err, an error parsing the integer read from src with
+// the strconv function fn, as if fn had parsed src itself.
+func numError(err error, fn, src string) error {
+	switch e := err.(type) {
+	case *strconv.NumError:
+		err = e.Err
+	default:
+		if err == errDecimal {
+			err = strconv.ErrSyntax
+		}
+	}
+	return &strconv.NumError{Func: fn, Num: src, Err: err}
 }
 
+// setBool parses src into the bool pointed by dst. An empty src, given by a
+// key present without a value, is true.
 func setBool(src string, dst reflect.Value) error {
 	if src == "" {
 		dst.Elem().SetBool(true)
@@ -1,8 +1,18 @@
 package query
 
 import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"net/netip"
+	"net/url"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestDecode_ArgumentTypes(t *testing.T) {
@@ -37,6 +47,32 @@ func TestDecode_ArgumentTypes(t *testing.T) {
 		}
 	})
 
+	t.Run("empty query", func(t *testing.T) {
+		var test struct{ Foo uint }
+		for _, v := range []interface{}{nil, test, (*struct{ Foo uint })(nil)} {
+			got := NewDecoder("").Decode(v)
+			exp := &InvalidUnmarshalError{reflect.TypeOf(v)}
+
+			if !reflect.DeepEqual(got, exp) {
+				t.Fatalf("exp: %v\ngot: %v", exp, got)
+			}
+		}
+		ok(t, NewDecoder("").Decode(&test))
+	})
+
+	t.Run("v=non-struct pointer", func(t *testing.T) {
+		for _, v := range []interface{}{new(int), new(map[string]int), new(*struct{ Foo uint })} {
+			for _, q := range []string{"", "foo=2"} {
+				got := NewDecoder(q).Decode(v)
+				exp := &UnsupportedTypeError{Type: reflect.TypeOf(v).Elem()}
+
+				if !reflect.DeepEqual(got, exp) {
+					t.Fatalf("exp: %v\ngot: %v", exp, got)
+				}
+			}
+		}
+	})
+
 	t.Run("v=pointer", func(t *testing.T) {
 		var test = new(struct{ Foo uint })
 		got := dec.Decode(test)
@@ -237,6 +273,48 @@ func TestDecode_OneLevel(t *testing.T) {
 	})
 }
 
+func TestDecode_SliceFormats(t *testing.T) {
+	for _, tt := range []struct {
+		query string
+		got   interface{}
+		exp   interface{}
+	}{
+		{"ids=1&ids=2", &struct {
+			IDs []int `q:"ids"`
+		}{}, []int{1, 2}},
+		{"ids=1,2&ids=3", &struct {
+			IDs []int `q:"ids,comma"`
+		}{}, []int{1, 2, 3}},
+		{"ids=1 2", &struct {
+			IDs []int `q:"ids,space"`
+		}{}, []int{1, 2}},
+		{"ids=1%3B2", &struct {
+			IDs [2]int `q:"ids,semicolon"`
+		}{}, [2]int{1, 2}},
+		{"ids[]=1&ids[]=2&ids=3", &struct {
+			IDs []int `q:"ids,brackets"`
+		}{}, []int{1, 2}},
+		{"ids[1]=2&ids[0]=1&ids[x]=3&ids[5]=4", &struct {
+			IDs []int `q:"ids,indexed"`
+		}{}, []int{1, 2, 4}},
+		{"ids1=2&ids0=1", &struct {
+			IDs *[]int `q:"ids,numbered"`
+		}{}, []int{1, 2}},
+		{`names=a\,b,c\\d,`, &struct {
+			Names []string `q:"names,comma"`
+		}{}, []string{"a,b", `c\d`, ""}},
+		{"names=", &struct {
+			Names []string `q:"names,comma"`
+		}{}, []string{}},
+	} {
+		ok(t, NewDecoder(tt.query).Decode(tt.got))
+		got := reflect.Indirect(reflect.ValueOf(tt.got).Elem().Field(0)).Interface()
+		if !reflect.DeepEqual(tt.exp, got) {
+			t.Fatalf("%s\nexp: %v\ngot: %v", tt.query, tt.exp, got)
+		}
+	}
+}
+
 func TestDecode_OneLevelOverrides(t *testing.T) {
 	t.Run("field=integer", func(t *testing.T) {
 		test := struct {
@@ -465,8 +543,1451 @@ func TestDecode_OneLevelOverrides(t *testing.T) {
 	})
 }
 
+func TestDecode_UnmarshalTypeError(t *testing.T) {
+	var test struct {
+		Filter struct {
+			Limit []uint `q:"limit"`
+		} `q:"filter"`
+	}
+	err := NewDecoder("filter[limit]=1&filter[limit]=-2").Decode(&test)
+
+	var fe FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("exp: %T\ngot: %v", fe, err)
+	}
+	exp := map[string]string{"filter[limit]": "must be an integer"}
+	if got := fe.Fields(); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		t.Fatalf("exp: %T\ngot: %v", numErr, err)
+	}
+
+	b, err := json.Marshal(fe)
+	ok(t, err)
+	if exp, got := `{"errors":{"filter[limit]":"must be an integer"}}`, string(b); exp != got {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+}
+
+func TestDecode_SentinelErrors(t *testing.T) {
+	type params struct {
+		Limit int      `q:"limit,max=100"`
+		Sort  string   `q:"sort,required"`
+		Ch    chan int `q:"ch"`
+		Tree  *node    `q:"tree"`
+	}
+	for _, test := range []struct {
+		query    string
+		v        interface{}
+		sentinel error
+	}{
+		{"sort=a", params{}, ErrInvalidTarget},
+		{"sort=a&ch=1", &params{}, ErrUnsupportedType},
+		{"sort=a&limit=x", &params{}, ErrInvalidValue},
+		{"limit=1", &params{}, ErrMissingKey},
+		{"sort=a&limit=101", &params{}, ErrConstraint},
+		{"sort=a&tree[next][next][next][next][next][name]=a", &params{}, ErrTooDeep},
+	} {
+		err := NewDecoder(test.query).Decode(test.v)
+		if !errors.Is(err, test.sentinel) {
+			t.Fatalf("%s\nexp: %v\ngot: %v", test.query, test.sentinel, err)
+		}
+		for _, other := range []error{ErrInvalidTarget, ErrUnsupportedType, ErrInvalidValue, ErrMissingKey, ErrConstraint, ErrTooDeep} {
+			if other != test.sentinel && errors.Is(err, other) {
+				t.Fatalf("%s\nexp: not %v\ngot: %v", test.query, other, err)
+			}
+		}
+	}
+}
+
+func TestDecode_Require(t *testing.T) {
+	type params struct {
+		Account string            `q:"account_id"`
+		From    string            `q:"from"`
+		Sort    string            `q:"sort,default=asc"`
+		Filter  filter            `q:"filter"`
+		Meta    map[string]string `q:"meta"`
+	}
+	dec := NewDecoder("account_id=7&filter[status]=open")
+	var got params
+	ok(t, dec.Decode(&got))
+	ok(t, dec.Decode(&got, Require("account_id", "sort", "filter")))
+	if got.Sort != "asc" {
+		t.Fatalf("exp: %v\ngot: %v", "asc", got.Sort)
+	}
+
+	for _, keys := range [][]string{{"account_id", "from"}, {"meta"}} {
+		err := dec.Decode(&params{}, Require(keys...))
+		exp := &MissingRequiredError{Key: keys[len(keys)-1]}
+		if !reflect.DeepEqual(exp, err) {
+			t.Fatalf("exp: %v\ngot: %v", exp, err)
+		}
+	}
+
+	err := NewDecoder("").Decode(&params{}, Require("from"))
+	if exp := (&MissingRequiredError{Key: "from"}); !reflect.DeepEqual(exp, err) {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+	ok(t, dec.Decode(&got))
+	ok(t, dec.Decode(&got, Require("filter[status]")))
+
+	err = dec.Decode(&got, Require("acount_id"))
+	if exp := `query: required key "acount_id" is read by no field of query.params`; err == nil || err.Error() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+}
+
+func TestDecode_UnsupportedTypeError(t *testing.T) {
+	var v struct {
+		Ch     chan int `q:"ch"`
+		Filter struct {
+			Meta map[float64]string `q:"meta"`
+		} `q:"filter"`
+	}
+	for _, test := range []struct {
+		query string
+		exp   *UnsupportedTypeError
+		msg   string
+	}{
+		{"ch=1", &UnsupportedTypeError{Type: reflect.TypeOf(v.Ch), Field: "Ch", Key: "ch"}, "query: unsupported type chan int of field Ch (key ch)"},
+		{"filter[meta][1]=a", &UnsupportedTypeError{Type: reflect.TypeOf(v.Filter.Meta), Field: "Meta", Key: "filter[meta]"}, "query: unsupported type map[float64]string of field Meta (key filter[meta])"},
+	} {
+		err := NewDecoder(test.query).Decode(&v)
+		var got *UnsupportedTypeError
+		if !errors.As(err, &got) || !reflect.DeepEqual(test.exp, got) {
+			t.Fatalf("exp: %+v\ngot: %+v", test.exp, err)
+		}
+		if err.Error() != test.msg {
+			t.Fatalf("exp: %v\ngot: %v", test.msg, err)
+		}
+		var old *UnimplementerError
+		if !errors.As(err, &old) {
+			t.Fatalf("exp: %T\ngot: %v", old, err)
+		}
+	}
+}
+
+func TestDecode_DefaultAndRequired(t *testing.T) {
+	type params struct {
+		Limit  int      `q:"limit,default=50"`
+		Sort   string   `q:"sort,default=name"`
+		Status []string `q:"status,space,default=open closed"`
+		Tags   []string `q:"tag,default=new"`
+		Owner  string   `q:"owner,required"`
+	}
+
+	var got params
+	ok(t, NewDecoder("owner=me&sort=&tag=x").Decode(&got))
+	exp := params{Limit: 50, Sort: "", Status: []string{"open", "closed"}, Tags: []string{"x"}, Owner: "me"}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	got = params{}
+	ok(t, NewDecoder("owner=me&limit=", WithEmptyAsMissing()).Decode(&got))
+	if got.Limit != 50 {
+		t.Fatalf("exp: %v\ngot: %v", 50, got.Limit)
+	}
+
+	err := NewDecoder("limit=10").Decode(&got)
+	exp2 := &MissingRequiredError{Key: "owner"}
+	if !reflect.DeepEqual(exp2, err) {
+		t.Fatalf("exp: %v\ngot: %v", exp2, err)
+	}
+	if fields := err.(FieldError).Fields(); fields["owner"] != "is required" {
+		t.Fatalf("exp: %v\ngot: %v", "is required", fields)
+	}
+}
+
+func TestDecode_DefaultAndRequired_emptyQuery(t *testing.T) {
+	type flat struct {
+		Limit int    `q:"limit,default=50"`
+		Sort  string `q:"sort,default=name"`
+	}
+	type params struct {
+		Limit  int      `q:"limit,default=50"`
+		Status []string `q:"status,space,default=open closed"`
+		Owner  string   `q:"owner,required"`
+	}
+
+	for _, query := range []string{"", "   ", "?"} {
+		var got flat
+		ok(t, NewDecoder(query).Decode(&got))
+		if exp := (flat{Limit: 50, Sort: "name"}); exp != got {
+			t.Fatalf("%q: exp: %+v\ngot: %+v", query, exp, got)
+		}
+
+		var p params
+		err := NewDecoder(query).Decode(&p)
+		exp := &MissingRequiredError{Key: "owner"}
+		if !reflect.DeepEqual(exp, err) {
+			t.Fatalf("%q: exp: %v\ngot: %v", query, exp, err)
+		}
+		if p.Limit != 50 || !reflect.DeepEqual([]string{"open", "closed"}, p.Status) {
+			t.Fatalf("%q: got: %+v", query, p)
+		}
+	}
+}
+
+func TestDecode_Hooks(t *testing.T) {
+	yesNo := func(key, raw string, target reflect.Type) (string, error) {
+		if target.Kind() != reflect.Bool {
+			return raw, nil
+		}
+		switch raw {
+		case "S":
+			return "true", nil
+		case "N":
+			return "false", nil
+		}
+		return raw, nil
+	}
+	var keys []string
+	trim := func(key, raw string, target reflect.Type) (string, error) {
+		keys = append(keys, key)
+		return strings.TrimPrefix(raw, "$"), nil
+	}
+
+	var got struct {
+		Active  bool              `q:"active"`
+		Flags   []bool            `q:"flag,comma"`
+		Amounts []float64         `q:"amount"`
+		Range   pagination        `q:"range"`
+		Prices  map[string]string `q:"price"`
+	}
+	query := "active=S&flag=N,S&amount=$1.5&amount=2&range[page]=$3&price[a]=$4"
+	ok(t, NewDecoder(query, WithDecodeHook(yesNo), WithDecodeHook(trim)).Decode(&got))
+
+	if !got.Active || !reflect.DeepEqual([]bool{false, true}, got.Flags) || !reflect.DeepEqual([]float64{1.5, 2}, got.Amounts) ||
+		got.Range.Page != 3 || got.Prices["a"] != "4" {
+		t.Fatalf("got: %+v", got)
+	}
+	exp := []string{"active", "flag", "flag", "amount", "amount", "range[page]", "price[a]"}
+	if !reflect.DeepEqual(exp, keys) {
+		t.Fatalf("exp: %v\ngot: %v", exp, keys)
+	}
+
+	fail := func(key, raw string, target reflect.Type) (string, error) {
+		return "", errors.New("rejected")
+	}
+	err := NewDecoder("active=S", WithDecodeHook(fail)).Decode(&got)
+	if _, isType := err.(*UnmarshalTypeError); !isType {
+		t.Fatalf("exp: %T\ngot: %v", &UnmarshalTypeError{}, err)
+	}
+}
+
+func TestDecode_ErrorHandler(t *testing.T) {
+	type params struct {
+		Page   int               `q:"page"`
+		Limit  int               `q:"limit,max=100"`
+		Source string            `q:"utm_source,required"`
+		Sort   string            `q:"sort"`
+		IDs    []int             `q:"id"`
+		Meta   map[string]int    `q:"meta"`
+		Labels map[string]string `q:"label"`
+	}
+	query := "page=x&limit=500&sort=name&id=1&id=x&meta[a]=1&meta[b]=x&label[a]=y"
+
+	var keys []string
+	lenient := func(key string, err error) error {
+		keys = append(keys, key)
+		return nil
+	}
+	// the fields whose errors are handled are left at zero
+	ids := []int{7, 8, 9}
+	got := params{Limit: 20, IDs: ids}
+	ok(t, NewDecoder(query, WithErrorHandler(lenient)).Decode(&got))
+	exp := params{Sort: "name", Meta: map[string]int{"a": 1}, Labels: map[string]string{"a": "y"}}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+	expKeys := []string{"page", "limit", "utm_source", "id", "meta[b]"}
+	if !reflect.DeepEqual(expKeys, keys) {
+		t.Fatalf("exp: %v\ngot: %v", expKeys, keys)
+	}
+	if !reflect.DeepEqual([]int{7, 8, 9}, ids) {
+		t.Fatalf("exp: %v\ngot: %v", []int{7, 8, 9}, ids)
+	}
+
+	errFatal := errors.New("fatal")
+	strict := func(key string, err error) error {
+		if key == "limit" {
+			return errFatal
+		}
+		return nil
+	}
+	if err := NewDecoder(query, WithErrorHandler(strict)).Decode(&params{}); err != errFatal {
+		t.Fatalf("exp: %v\ngot: %v", errFatal, err)
+	}
+}
+
+func TestDecoder_Warnings(t *testing.T) {
+	type params struct {
+		Page   int               `q:"page"`
+		Limit  int               `q:"limit"`
+		IDs    []int             `q:"id,indexed"`
+		Filter map[string]string `q:"filter"`
+		Range  pagination        `q:"range"`
+	}
+
+	dec := NewDecoder("page=x&limit=&id[0]=1&id[1]=2&filter[a]=b&range[page]=1&utm=mail&debug",
+		WithEmptyAsMissing(), WithErrorHandler(func(string, error) error { return nil }))
+	ok(t, dec.Decode(&params{}))
+
+	got := dec.Warnings()
+	if len(got) != 4 || got[0].Err == nil {
+		t.Fatalf("got: %+v", got)
+	}
+	got[0].Err = nil
+	exp := []Warning{
+		{"page", "x", WarnInvalidValue, nil},
+		{"limit", "", WarnEmptyValue, nil},
+		{"debug", "", WarnUnknownKey, nil},
+		{"utm", "mail", WarnUnknownKey, nil},
+	}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	dec = NewDecoder("page=1&id[0]=1&filter[a]=b&range[page]=1")
+	ok(t, dec.Decode(&params{}))
+	if got := dec.Warnings(); got != nil {
+		t.Fatalf("exp: %v\ngot: %+v", nil, got)
+	}
+}
+
+type node struct {
+	Name string `q:"name"`
+	Next *node  `q:"next"`
+}
+
+func TestDecode_MaxDepth(t *testing.T) {
+	nested := func(n int) string {
+		return "next" + strings.Repeat("[next]", n-1)
+	}
+
+	var got node
+	ok(t, NewDecoder(nested(5)+"[name]=x").Decode(&got))
+	if got.Next.Next.Next.Next.Next.Name != "x" {
+		t.Fatalf("got: %+v", got)
+	}
+
+	err := NewDecoder(nested(6) + "[name]=x").Decode(&node{})
+	exp := &DepthExceededError{Key: nested(6), Max: DefaultMaxDepth}
+	if !reflect.DeepEqual(exp, err) {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+
+	ok(t, NewDecoder(nested(6)+"[name]=x", WithMaxDepth(0)).Decode(&node{}))
+	err = NewDecoder(nested(2)+"[name]=x", WithMaxDepth(1)).Decode(&node{})
+	if _, isDepth := err.(*DepthExceededError); !isDepth {
+		t.Fatalf("exp: %T\ngot: %v", exp, err)
+	}
+
+	t.Run("style=flat", func(t *testing.T) {
+		var got node
+		dec := NewDecoder("name=x&other=y", WithKeyStyle(FlatKeys))
+		ok(t, dec.Decode(&got))
+		if got.Name != "x" || got.Next != nil {
+			t.Fatalf("got: %+v", got)
+		}
+		if w := dec.Warnings(); len(w) != 1 || w[0].Key != "other" {
+			t.Fatalf("got: %+v", w)
+		}
+	})
+}
+
+func TestDecoder_Concurrent(t *testing.T) {
+	dec := NewDecoder("q=foo&filter[status]=open&filter[range][page]=2&filter[meta][a]=1&page=1&per_page=x&extra=1",
+		WithErrorHandler(func(string, error) error { return nil }))
+
+	var wg sync.WaitGroup
+	got := make([]listOptions, 100)
+	for i := range got {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := dec.Decode(&got[i]); err != nil {
+				t.Error(err)
+			}
+			dec.Warnings()
+		}(i)
+	}
+	wg.Wait()
+
+	exp := listOptions{
+		Query:      "foo",
+		Filter:     filter{Status: "open", Range: &pagination{Page: 2}, Meta: map[string]string{"a": "1"}},
+		pagination: pagination{Page: 1},
+	}
+	for i := range got {
+		if !reflect.DeepEqual(exp, got[i]) {
+			t.Fatalf("%d\nexp: %+v\ngot: %+v", i, exp, got[i])
+		}
+	}
+	if w := dec.Warnings(); len(w) != 2 {
+		t.Fatalf("exp: %v warnings\ngot: %+v", 2, w)
+	}
+}
+
+func TestDecoder_ForEach(t *testing.T) {
+	type pair struct{ key, value string }
+	var got []pair
+	d := NewDecoder("b=2&a=1&&b=%zz&c&a=x+y%21&d;e=3&%zz")
+	err := d.ForEach(func(key, value string) error {
+		got = append(got, pair{key, value})
+		return nil
+	})
+	exp := []pair{{"b", "2"}, {"a", "1"}, {"c", ""}, {"a", "x y!"}}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+	_, expErr := url.QueryUnescape("%zz")
+	if err == nil || err.Error() != expErr.Error() {
+		t.Fatalf("exp: %v\ngot: %v", expErr, err)
+	}
+	err = NewDecoder("a=1&b;c=1&%zz").ForEach(func(string, string) error { return nil })
+	if exp := "invalid semicolon separator in query"; err == nil || err.Error() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+
+	stop := errors.New("stop")
+	got = nil
+	err = d.ForEach(func(key, value string) error {
+		got = append(got, pair{key, value})
+		if key == "a" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || len(got) != 2 {
+		t.Fatalf("exp: %v after 2 pairs\ngot: %v after %v", stop, err, got)
+	}
+}
+
+func TestDecode_Fallback(t *testing.T) {
+	type options struct {
+		listOptions
+		Tags []string `q:"tag"`
+	}
+	defaults, err := url.ParseQuery("page=9&per_page=50&filter[status]=closed&filter[meta][a]=1&tag=b&tag=c")
+	ok(t, err)
+
+	var got options
+	ok(t, NewDecoder("page=2&filter[status]=open&tag=a", WithFallback(defaults)).Decode(&got))
+	exp := options{
+		listOptions: listOptions{
+			Filter:     filter{Status: "open", Meta: map[string]string{"a": "1"}},
+			pagination: pagination{Page: 2, PerPage: 50},
+		},
+		Tags: []string{"a"},
+	}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	got = options{}
+	ok(t, NewDecoder("", WithFallback(defaults)).Decode(&got))
+	if got.Page != 9 || !reflect.DeepEqual([]string{"b", "c"}, got.Tags) {
+		t.Fatalf("exp: %v\ngot: %+v", "the fallback values", got)
+	}
+}
+
+func TestDecode_ReuseSliceCapacity(t *testing.T) {
+	type params struct {
+		IDs  []int    `q:"id"`
+		Tags []string `q:"tag,comma"`
+	}
+	ids := []int{9, 9, 9, 9, 9}
+	got := params{IDs: ids[:1], Tags: make([]string, 0, 1)}
+
+	ok(t, NewDecoder("id=1&id=2&tag=a,b", WithReuseSlices()).Decode(&got))
+	exp := params{IDs: []int{1, 2}, Tags: []string{"a", "b"}}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+	if &got.IDs[0] != &ids[0] || cap(got.IDs) != 5 {
+		t.Fatalf("exp: the backing array of ids\ngot: %v", got.IDs)
+	}
+	if !reflect.DeepEqual([]int{1, 2, 9, 9, 9}, ids) {
+		t.Fatalf("exp: %v\ngot: %v", []int{1, 2, 9, 9, 9}, ids)
+	}
+
+	// without the option, the backing array is left alone
+	ok(t, NewDecoder("id=3").Decode(&got))
+	if !reflect.DeepEqual([]int{3}, got.IDs) || !reflect.DeepEqual([]int{1, 2, 9, 9, 9}, ids) {
+		t.Fatalf("exp: %v, backing %v\ngot: %v, backing %v", []int{3}, []int{1, 2, 9, 9, 9}, got.IDs, ids)
+	}
+
+	// a failing element leaves the field as it was
+	got.IDs = ids[:3]
+	if err := NewDecoder("id=4&id=x").Decode(&got); err == nil {
+		t.Fatalf("exp: error\ngot: %v", err)
+	}
+	if !reflect.DeepEqual([]int{1, 2, 9}, got.IDs) {
+		t.Fatalf("exp: %v\ngot: %v", []int{1, 2, 9}, got.IDs)
+	}
+
+	var empty struct {
+		IDs []int `q:"id,comma"`
+	}
+	ok(t, NewDecoder("id=").Decode(&empty))
+	if empty.IDs == nil || len(empty.IDs) != 0 {
+		t.Fatalf("exp: %v\ngot: %#v", []int{}, empty.IDs)
+	}
+}
+
+func TestDecode_TextUnmarshalerElems(t *testing.T) {
+	type hosts struct {
+		Pair  [2]netip.Addr `q:"pair"`
+		Hosts []netip.Addr  `q:"host"`
+	}
+
+	var got hosts
+	ok(t, NewDecoder("pair=10.0.0.1&pair=::1&pair=10.0.0.3&host=1.1.1.1&host=8.8.8.8").Decode(&got))
+	exp := hosts{
+		Pair:  [2]netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("::1")},
+		Hosts: []netip.Addr{netip.MustParseAddr("1.1.1.1"), netip.MustParseAddr("8.8.8.8")},
+	}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+	ok(t, CheckType(&got))
+
+	got = hosts{}
+	ok(t, NewDecoder("pair=10.0.0.1").Decode(&got))
+	if exp := [2]netip.Addr{netip.MustParseAddr("10.0.0.1")}; got.Pair != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, got.Pair)
+	}
+
+	err := NewDecoder("pair=10.0.0.1&pair=localhost").Decode(&got)
+	var typeErr *UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Key != "pair" {
+		t.Fatalf("exp: %T for pair\ngot: %v", typeErr, err)
+	}
+
+	s, err := Marshal(exp)
+	ok(t, err)
+	if exp := "host=1.1.1.1&host=8.8.8.8&pair=10.0.0.1&pair=%3A%3A1"; s != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, s)
+	}
+}
+
+func TestDecode_SliceAliasing(t *testing.T) {
+	type params struct {
+		IDs  []int    `q:"id"`
+		Tags []string `q:"tag,comma"`
+		Ptr  *[]int   `q:"ptr"`
+	}
+	shared := []int{7, 8, 9}
+	backing := shared
+	template := params{IDs: []int{1, 2, 3}, Tags: []string{"a", "b", "c"}, Ptr: &shared}
+
+	got := template
+	ok(t, NewDecoder("id=5&id=6&tag=x&ptr=1&ptr=2").Decode(&got))
+
+	// the contents are replaced fully
+	exp := params{IDs: []int{5, 6}, Tags: []string{"x"}, Ptr: &[]int{1, 2}}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+	// the memory shared with the template before the call is unchanged
+	if !reflect.DeepEqual([]int{1, 2, 3}, template.IDs) || !reflect.DeepEqual([]string{"a", "b", "c"}, template.Tags) {
+		t.Fatalf("exp: %v %v\ngot: %v %v", []int{1, 2, 3}, []string{"a", "b", "c"}, template.IDs, template.Tags)
+	}
+	// a slice behind a pointer is written through, with a new backing array
+	if !reflect.DeepEqual([]int{1, 2}, shared) || !reflect.DeepEqual([]int{7, 8, 9}, backing) {
+		t.Fatalf("exp: %v, backing %v\ngot: %v, backing %v", []int{1, 2}, []int{7, 8, 9}, shared, backing)
+	}
+
+	// invalid values leave the shared memory alone as well
+	shared = backing
+	got = template
+	handled := WithErrorHandler(func(string, error) error { return nil })
+	ok(t, NewDecoder("id=5&id=x&ptr=1&ptr=x", handled).Decode(&got))
+	if !reflect.DeepEqual([]int{1, 2, 3}, template.IDs) || !reflect.DeepEqual([]int{7, 8, 9}, backing) {
+		t.Fatalf("exp: %v, backing %v\ngot: %v, backing %v", []int{1, 2, 3}, []int{7, 8, 9}, template.IDs, backing)
+	}
+}
+
+func BenchmarkDecodeNested(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var v listOptions
+		if err := NewDecoder("q=foo&filter[status]=open&filter[meta][a]=1&filter[meta][b]=2&page=1&per_page=3").Decode(&v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeSlice(b *testing.B) {
+	type params struct {
+		IDs []int `q:"id"`
+	}
+	dec := NewDecoder(strings.Repeat("id=12345&", 999) + "id=12345")
+	b.Run("fresh", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var v params
+			if err := dec.Decode(&v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	reuse := NewDecoder(strings.Repeat("id=12345&", 999)+"id=12345", WithReuseSlices())
+	b.Run("reused", func(b *testing.B) {
+		v := params{IDs: make([]int, 0, 1000)}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := reuse.Decode(&v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestDecode_EmptyAsMissing(t *testing.T) {
+	const query = "numeric=&float=&time=&slice=&slice=2&empty=&text="
+
+	t.Run("default", func(t *testing.T) {
+		var test struct {
+			Numeric int `q:"numeric"`
+		}
+		if err := NewDecoder(query).Decode(&test); err == nil {
+			t.Fatalf("exp: error\ngot: %v", err)
+		}
+	})
+
+	t.Run("field=integer", func(t *testing.T) {
+		test := struct {
+			Numeric int `q:"numeric"`
+		}{
+			Numeric: 3,
+		}
+		ok(t, NewDecoder(query, WithEmptyAsMissing()).Decode(&test))
+		exp := 3
+		got := test.Numeric
+		if exp != got {
+			t.Fatalf("exp: %v\ngot: %v", exp, got)
+		}
+	})
+
+	t.Run("field=pointer to integer", func(t *testing.T) {
+		var test struct {
+			Numeric *int `q:"numeric"`
+		}
+		ok(t, NewDecoder(query, WithEmptyAsMissing()).Decode(&test))
+		if test.Numeric != nil {
+			t.Fatalf("exp: %v\ngot: %v", nil, *test.Numeric)
+		}
+	})
+
+	t.Run("field=float64", func(t *testing.T) {
+		test := struct {
+			Float float64 `q:"float"`
+		}{
+			Float: 4.56,
+		}
+		ok(t, NewDecoder(query, WithEmptyAsMissing()).Decode(&test))
+		exp := 4.56
+		got := test.Float
+		if exp != got {
+			t.Fatalf("exp: %v\ngot: %v", exp, got)
+		}
+	})
+
+	t.Run("field=pointer to time", func(t *testing.T) {
+		var test struct {
+			Time *time.Time `q:"time"`
+		}
+		ok(t, NewDecoder(query, WithEmptyAsMissing()).Decode(&test))
+		if test.Time != nil {
+			t.Fatalf("exp: %v\ngot: %v", nil, *test.Time)
+		}
+	})
+
+	t.Run("field=time", func(t *testing.T) {
+		at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		test := struct {
+			Time time.Time `q:"time"`
+		}{
+			Time: at,
+		}
+		ok(t, NewDecoder(query, WithEmptyAsMissing()).Decode(&test))
+		if !test.Time.Equal(at) {
+			t.Fatalf("exp: %v\ngot: %v", at, test.Time)
+		}
+	})
+
+	t.Run("field=slice", func(t *testing.T) {
+		var test struct {
+			Slice []int `q:"slice"`
+			Empty []int `q:"empty"`
+		}
+		ok(t, NewDecoder(query, WithEmptyAsMissing()).Decode(&test))
+		exp := []int{2}
+		got := test.Slice
+		if !reflect.DeepEqual(exp, got) {
+			t.Fatalf("exp: %v\ngot: %v", exp, got)
+		}
+		if test.Empty != nil {
+			t.Fatalf("exp: %v\ngot: %v", nil, test.Empty)
+		}
+	})
+
+	t.Run("field=string", func(t *testing.T) {
+		test := struct {
+			Text string `q:"text"`
+		}{
+			Text: "this should be overwritten",
+		}
+		ok(t, NewDecoder(query, WithEmptyAsMissing()).Decode(&test))
+		if test.Text != "" {
+			t.Fatalf("exp: %q\ngot: %q", "", test.Text)
+		}
+	})
+
+	t.Run("tag=allowempty", func(t *testing.T) {
+		var test struct {
+			Numeric int `q:"numeric,allowempty"`
+		}
+		if err := NewDecoder(query, WithEmptyAsMissing()).Decode(&test); err == nil {
+			t.Fatalf("exp: error\ngot: %v", err)
+		}
+	})
+}
+
+type filter struct {
+	Status string            `q:"status"`
+	Range  *pagination       `q:"range"`
+	Meta   map[string]string `q:"meta"`
+}
+
+type pagination struct {
+	Page    int `q:"page"`
+	PerPage int `q:"per_page"`
+}
+
+type listOptions struct {
+	Query  string  `q:"q"`
+	Filter filter  `q:"filter"`
+	Next   *filter `q:"next"`
+	pagination
+}
+
+func TestDecode_Nested(t *testing.T) {
+	exp := listOptions{
+		Query: "foo",
+		Filter: filter{
+			Status: "open",
+			Range:  &pagination{Page: 2},
+			Meta:   map[string]string{"a": "1", "b": "2"},
+		},
+		pagination: pagination{Page: 1, PerPage: 10},
+	}
+
+	for _, tt := range []struct {
+		style KeyStyle
+		query string
+	}{
+		{BracketKeys, "q=foo&filter[status]=open&filter[range][page]=2&filter[meta][a]=1&filter[meta][b]=2&filter[meta][c][d]=3&page=1&per_page=10"},
+		{DotKeys, "q=foo&filter.status=open&filter.range.page=2&filter.meta.a=1&filter.meta.b=2&filter.meta.c.d=3&page=1&per_page=10"},
+	} {
+		var got listOptions
+		ok(t, NewDecoder(tt.query, WithKeyStyle(tt.style)).Decode(&got))
+		if !reflect.DeepEqual(exp, got) {
+			t.Fatalf("style %d\nexp: %+v\ngot: %+v", tt.style, exp, got)
+		}
+	}
+
+	t.Run("style=flat", func(t *testing.T) {
+		var got struct {
+			Query  string `q:"q"`
+			Filter struct {
+				Status string            `q:"status"`
+				Meta   map[string]string `q:"meta"`
+			} `q:"filter"`
+		}
+		ok(t, NewDecoder("q=foo&status=open&meta[a]=1", WithKeyStyle(FlatKeys)).Decode(&got))
+		if got.Query != "foo" || got.Filter.Status != "open" || got.Filter.Meta["a"] != "1" {
+			t.Fatalf("got: %+v", got)
+		}
+	})
+
+	t.Run("field=unsupported map", func(t *testing.T) {
+		var got struct {
+			Map map[float64]string `q:"map"`
+		}
+		err := NewDecoder("map[1]=a").Decode(&got)
+		if _, isUnimplemented := err.(*UnimplementerError); !isUnimplemented {
+			t.Fatalf("exp: %T\ngot: %v", &UnimplementerError{}, err)
+		}
+	})
+
+	t.Run("field=map of maps", func(t *testing.T) {
+		var got struct {
+			Meta  map[string]map[string]string `q:"meta"`
+			Score *map[int]map[string]float64  `q:"score"`
+			Empty map[string]map[string]int    `q:"empty"`
+		}
+		d := NewDecoder("meta[a][x]=1&meta[a][y]=2&meta[b][x]=3&score[1][low]=0.5")
+		ok(t, d.Decode(&got))
+		exp := map[string]map[string]string{"a": {"x": "1", "y": "2"}, "b": {"x": "3"}}
+		if !reflect.DeepEqual(exp, got.Meta) {
+			t.Fatalf("exp: %v\ngot: %v", exp, got.Meta)
+		}
+		if got.Score == nil || (*got.Score)[1]["low"] != 0.5 || got.Empty != nil {
+			t.Fatalf("exp: %v\ngot: %+v", "score[1][low] only", got)
+		}
+		if w := d.Warnings(); w != nil {
+			t.Fatalf("exp: %v\ngot: %v", nil, w)
+		}
+
+		err := NewDecoder("meta[a][x]=1", WithMaxDepth(1)).Decode(&got)
+		if exp := (&DepthExceededError{Key: "meta[a]", Max: 1}); !reflect.DeepEqual(exp, err) {
+			t.Fatalf("exp: %v\ngot: %v", exp, err)
+		}
+
+		var deeper struct {
+			Meta map[string]map[string]map[string]string `q:"meta"`
+		}
+		err = NewDecoder("meta[a][b][c]=1").Decode(&deeper)
+		exp2 := "query: unsupported type map[string]map[string]string of field Meta (key meta[*])"
+		if err == nil || err.Error() != exp2 {
+			t.Fatalf("exp: %v\ngot: %v", exp2, err)
+		}
+	})
+
+	t.Run("field=slice of structs and maps", func(t *testing.T) {
+		type account struct {
+			ID   int    `q:"id"`
+			Type string `q:"type"`
+		}
+		var got struct {
+			Accounts []account           `q:"accounts"`
+			Owners   []*account          `q:"owner"`
+			Rows     []map[string]string `q:"row"`
+		}
+		d := NewDecoder("accounts[0][id]=1&accounts[0][type]=savings&accounts[1][id]=2" +
+			"&owner[7][id]=7&owner[3][id]=3&row[0][a]=x&row[2][b]=y&accounts[01][id]=9&accounts[0][bad]=1")
+		ok(t, d.Decode(&got))
+		if exp := []account{{1, "savings"}, {2, ""}}; !reflect.DeepEqual(exp, got.Accounts) {
+			t.Fatalf("exp: %v\ngot: %v", exp, got.Accounts)
+		}
+		if len(got.Owners) != 2 || got.Owners[0].ID != 3 || got.Owners[1].ID != 7 {
+			t.Fatalf("exp: %v\ngot: %v", "owners 3 and 7", got.Owners)
+		}
+		if exp := []map[string]string{{"a": "x"}, {"b": "y"}}; !reflect.DeepEqual(exp, got.Rows) {
+			t.Fatalf("exp: %v\ngot: %v", exp, got.Rows)
+		}
+		exp := []Warning{
+			{"accounts[01][id]", "9", WarnUnknownKey, nil},
+			{"accounts[0][bad]", "1", WarnUnknownKey, nil},
+		}
+		if w := d.Warnings(); !reflect.DeepEqual(exp, w) {
+			t.Fatalf("exp: %v\ngot: %v", exp, w)
+		}
+
+		var dotted struct {
+			Accounts []account `q:"accounts"`
+		}
+		ok(t, NewDecoder("accounts.0.id=1&accounts.1.type=loan", WithKeyStyle(DotKeys)).Decode(&dotted))
+		if exp := []account{{1, ""}, {0, "loan"}}; !reflect.DeepEqual(exp, dotted.Accounts) {
+			t.Fatalf("exp: %v\ngot: %v", exp, dotted.Accounts)
+		}
+
+		err := NewDecoder("accounts[0][id]=1", WithMaxDepth(1)).Decode(&dotted)
+		if exp := (&DepthExceededError{Key: "accounts[0]", Max: 1}); !reflect.DeepEqual(exp, err) {
+			t.Fatalf("exp: %v\ngot: %v", exp, err)
+		}
+		ok(t, CheckType(&got))
+	})
+
+	t.Run("field=integer keys", func(t *testing.T) {
+		var got struct {
+			Levels  map[int]string    `q:"level"`
+			Weights map[int64]float64 `q:"weight"`
+		}
+		ok(t, NewDecoder("level[1]=low&level[-2]=high&weight[1]=0.5&weight[2]=0.3").Decode(&got))
+		if exp := map[int]string{1: "low", -2: "high"}; !reflect.DeepEqual(exp, got.Levels) {
+			t.Fatalf("exp: %v\ngot: %v", exp, got.Levels)
+		}
+		if exp := map[int64]float64{1: 0.5, 2: 0.3}; !reflect.DeepEqual(exp, got.Weights) {
+			t.Fatalf("exp: %v\ngot: %v", exp, got.Weights)
+		}
+
+		err := NewDecoder("weight[x]=0.5").Decode(&got)
+		exp := `query: cannot decode "x" into weight[x] of type int64: strconv.ParseInt: parsing "x": invalid syntax`
+		if err == nil || err.Error() != exp {
+			t.Fatalf("exp: %v\ngot: %v", exp, err)
+		}
+		if !errors.Is(err, ErrInvalidValue) {
+			t.Fatalf("exp: %v\ngot: %v", ErrInvalidValue, err)
+		}
+	})
+}
+
+func TestDecode_EmptyMap(t *testing.T) {
+	type patch struct {
+		Tags  map[string]string  `q:"tags"`
+		Attrs *map[string]string `q:"attrs"`
+	}
+	for _, test := range []struct {
+		query   string
+		exp     map[string]string
+		unknown []string
+	}{
+		{"", nil, nil},
+		{"tags=", map[string]string{}, nil},
+		{"tags", map[string]string{}, nil},
+		{"tags=&tags[a]=1", map[string]string{"a": "1"}, nil},
+		{"tags[a]=1", map[string]string{"a": "1"}, nil},
+		{"tags=x", nil, []string{"tags"}},
+	} {
+		var stats DecodeStats
+		var got patch
+		ok(t, NewDecoder(test.query).Decode(&got, WithStats(&stats)))
+		if !reflect.DeepEqual(test.exp, got.Tags) {
+			t.Fatalf("%s\nexp: %#v\ngot: %#v", test.query, test.exp, got.Tags)
+		}
+		if len(test.unknown) > 0 && !reflect.DeepEqual(test.unknown, stats.Unknown) || len(test.unknown) == 0 && len(stats.Unknown) > 0 {
+			t.Fatalf("%s\nexp: %v\ngot: %v", test.query, test.unknown, stats.Unknown)
+		}
+	}
+
+	got := patch{Tags: map[string]string{"a": "1"}}
+	ok(t, NewDecoder("tags=&attrs").Decode(&got))
+	if got.Tags == nil || len(got.Tags) != 0 {
+		t.Fatalf("exp: %v\ngot: %#v", map[string]string{}, got.Tags)
+	}
+	if got.Attrs == nil || *got.Attrs == nil || len(*got.Attrs) != 0 {
+		t.Fatalf("exp: pointer to an empty map\ngot: %#v", got.Attrs)
+	}
+
+	got = patch{}
+	ok(t, NewDecoder("tags=", WithEmptyAsMissing()).Decode(&got))
+	if got.Tags != nil {
+		t.Fatalf("exp: %v\ngot: %#v", nil, got.Tags)
+	}
+}
+
+func TestDecode_MapTypes(t *testing.T) {
+	type elem struct {
+		A int `q:"a"`
+	}
+	for _, tt := range []struct {
+		m   interface{}
+		exp error
+	}{
+		{map[string]string{}, nil},
+		{map[int]int{}, ErrInvalidValue},
+		{map[uint8]float64{}, ErrInvalidValue},
+		{map[string]*int{}, ErrInvalidValue},
+		{map[string][]int{}, ErrInvalidValue},
+		{map[string][2]int{}, ErrInvalidValue},
+		{map[string]*[]int{}, ErrInvalidValue},
+		{map[string]time.Time{}, ErrInvalidValue},
+		{map[string]time.Duration{}, ErrInvalidValue},
+		{map[string]Date{}, ErrInvalidValue},
+		{map[string]Number{}, ErrInvalidValue},
+		{map[string]*big.Int{}, ErrInvalidValue},
+		{map[string]map[string]int{}, nil},
+		{map[string]map[string][]string{}, nil},
+		{map[float64]string{}, ErrUnsupportedType},
+		{map[float32]string{}, ErrUnsupportedType},
+		{map[complex64]string{}, ErrUnsupportedType},
+		{map[bool]string{}, ErrUnsupportedType},
+		{map[uintptr]string{}, ErrUnsupportedType},
+		{map[[2]int]string{}, ErrUnsupportedType},
+		{map[elem]string{}, ErrUnsupportedType},
+		{map[*string]string{}, ErrUnsupportedType},
+		{map[interface{}]string{}, ErrUnsupportedType},
+		{map[string]interface{}{}, ErrUnsupportedType},
+		{map[string]chan int{}, ErrUnsupportedType},
+		{map[string]func(){}, ErrUnsupportedType},
+		{map[string]complex128{}, ErrUnsupportedType},
+		{map[string]**int{}, ErrUnsupportedType},
+		{map[string]elem{}, ErrUnsupportedType},
+		{map[string]*elem{}, ErrUnsupportedType},
+		{map[string]map[float64]int{}, ErrUnsupportedType},
+		{map[string]map[string]elem{}, ErrUnsupportedType},
+		{map[string]map[string]map[string]int{}, ErrUnsupportedType},
+	} {
+		typ := reflect.StructOf([]reflect.StructField{{Name: "M", Type: reflect.TypeOf(tt.m), Tag: `q:"m"`}})
+		v := reflect.New(typ).Interface()
+		err := NewDecoder("m[1]=1&m[NaN]=x&m[1][2]=3").Decode(v)
+		if !errors.Is(err, tt.exp) || (err == nil) != (tt.exp == nil) {
+			t.Fatalf("%T\nexp: %v\ngot: %v", tt.m, tt.exp, err)
+		}
+		if check := CheckType(v); (check != nil) != (tt.exp == ErrUnsupportedType) {
+			t.Fatalf("%T\nexp: CheckType error %v\ngot: %v", tt.m, tt.exp == ErrUnsupportedType, check)
+		}
+	}
+
+	t.Run("encode NaN key", func(t *testing.T) {
+		got, err := Values(struct {
+			M map[float64]string `q:"m"`
+		}{map[float64]string{math.NaN(): "a"}})
+		ok(t, err)
+		if exp := "m%5BNaN%5D=a"; got.Encode() != exp {
+			t.Fatalf("exp: %v\ngot: %v", exp, got.Encode())
+		}
+	})
+}
+
+func TestDecode_Inline(t *testing.T) {
+	type embedded struct {
+		Rest map[string][]string `q:",inline"`
+	}
+	var got struct {
+		Page   int               `q:"page"`
+		Tags   []string          `q:"tag,brackets"`
+		IDs    []int             `q:"id,indexed"`
+		Filter map[string]string `q:"filter"`
+		Range  pagination        `q:"range"`
+		embedded
+	}
+	ok(t, NewDecoder("page=2&tag[]=a&id[0]=1&filter[x]=y&range[page]=3&utm_source=mail&utm_source=web&debug").Decode(&got))
+
+	exp := map[string][]string{"utm_source": {"mail", "web"}, "debug": {""}}
+	if !reflect.DeepEqual(exp, got.Rest) {
+		t.Fatalf("exp: %v\ngot: %v", exp, got.Rest)
+	}
+	if got.Page != 2 || got.Range.Page != 3 || len(got.Tags) != 1 || len(got.IDs) != 1 {
+		t.Fatalf("got: %+v", got)
+	}
+}
+
+func TestDecode_CatchAll(t *testing.T) {
+	var got struct {
+		Page   int               `q:"page"`
+		Filter map[string]string `q:"filter"`
+		Vendor url.Values        `q:"*"`
+	}
+	ok(t, NewDecoder("page=2&filter[x]=y&utm_source=mail&utm_source=web&gclid=abc").Decode(&got))
+	exp := url.Values{"utm_source": {"mail", "web"}, "gclid": {"abc"}}
+	if !reflect.DeepEqual(exp, got.Vendor) {
+		t.Fatalf("exp: %v\ngot: %v", exp, got.Vendor)
+	}
+
+	vals, err := Values(got)
+	ok(t, err)
+	if exp := "filter%5Bx%5D=y&gclid=abc&page=2&utm_source=mail&utm_source=web"; vals.Encode() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, vals.Encode())
+	}
+}
+
+func TestDecode_RoundTrip(t *testing.T) {
+	in := listOptions{
+		Query: "foo",
+		Filter: filter{
+			Status: "open",
+			Range:  &pagination{Page: 2, PerPage: 5},
+			Meta:   map[string]string{"a": "1"},
+		},
+		Next:       &filter{Status: "closed", Range: &pagination{}},
+		pagination: pagination{Page: 1, PerPage: 10},
+	}
+
+	t.Run("slices", func(t *testing.T) {
+		type slices struct {
+			A []string `q:"a"`
+			B []string `q:"b,comma"`
+			C []string `q:"c,space"`
+			D []string `q:"d,semicolon"`
+			E []string `q:"e,brackets"`
+			F []string `q:"f,numbered"`
+			G []string `q:"g,indexed"`
+		}
+		elems := []string{"x,y", "x y", "x;y", `x\y`}
+		in := slices{elems, elems, elems, elems, elems, elems, elems}
+
+		s, err := Marshal(in)
+		ok(t, err)
+
+		var got slices
+		ok(t, NewDecoder(s).Decode(&got))
+		if !reflect.DeepEqual(in, got) {
+			t.Fatalf("%s\nexp: %+v\ngot: %+v", s, in, got)
+		}
+	})
+
+	for _, style := range []KeyStyle{BracketKeys, DotKeys} {
+		s, err := Marshal(in, EncodeKeyStyle(style))
+		ok(t, err)
+
+		var got listOptions
+		ok(t, NewDecoder(s, WithKeyStyle(style)).Decode(&got))
+		if !reflect.DeepEqual(in, got) {
+			t.Fatalf("style %d: %s\nexp: %+v\ngot: %+v", style, s, in, got)
+		}
+	}
+}
+
 func ok(t testing.TB, err error) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestDecode_secret(t *testing.T) {
+	var got struct {
+		PIN int `q:"pin,secret"`
+	}
+	err := NewDecoder("pin=12a4").Decode(&got)
+	if exp := `query: cannot decode "REDACTED" into pin of type int: invalid value, redacted`; err == nil || err.Error() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+}
+
+func TestDecode_rawInto(t *testing.T) {
+	type payment struct {
+		Amount    float64  `q:"amount,rawinto=AmountRaw"`
+		AmountRaw string   `q:"-"`
+		IDs       []int    `q:"id,rawinto=IDsRaw"`
+		IDsRaw    []string `q:"-"`
+		Code      int      `q:"code,default=7,rawinto=CodeRaw"`
+		CodeRaw   string   `q:"-"`
+	}
+	var got payment
+	ok(t, NewDecoder("amount=0012.50&id=007&id=010").Decode(&got))
+	exp := payment{Amount: 12.5, AmountRaw: "0012.50", IDs: []int{7, 10}, IDsRaw: []string{"007", "010"}, Code: 7}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+}
+
+func TestDecode_numberSpaces(t *testing.T) {
+	type params struct {
+		Int    int     `q:"int"`
+		Uint   uint8   `q:"uint"`
+		Float  float64 `q:"float"`
+		Limit  int     `q:"limit,max=10"`
+		Strict int     `q:"strict,strictnum"`
+		Ratio  float32 `q:"ratio,strictnum"`
+	}
+
+	var got params
+	ok(t, NewDecoder("int=+2&uint=%203%20&float=1.5%09&limit=9&strict=-12&ratio=0.50").Decode(&got))
+	if exp := (params{Int: 2, Uint: 3, Float: 1.5, Limit: 9, Strict: -12, Ratio: 0.5}); exp != got {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	for _, test := range []struct {
+		query string
+		err   string
+	}{
+		{"limit=+20", `query: value " 20" of limit is greater than 10`},
+		{"strict=+2", `query: cannot decode " 2" into strict of type int: not a number in canonical form`},
+		{"strict=%2B2", `query: cannot decode "+2" into strict of type int: not a number in canonical form`},
+		{"strict=02", `query: cannot decode "02" into strict of type int: not a number in canonical form`},
+		{"strict=2%20", `query: cannot decode "2 " into strict of type int: not a number in canonical form`},
+		{"ratio=0x1p-2", `query: cannot decode "0x1p-2" into ratio of type float32: not a number in canonical form`},
+		{"int=2%202", `query: cannot decode "2 2" into int of type int: strconv.ParseInt: parsing "2 2": invalid syntax`},
+	} {
+		err := NewDecoder(test.query).Decode(&got)
+		if err == nil || err.Error() != test.err {
+			t.Fatalf("%s\nexp: %v\ngot: %v", test.query, test.err, err)
+		}
+	}
+
+	err := CheckType(&struct {
+		Name string `q:"name,strictnum"`
+	}{})
+	if exp := "strictnum only applies to numbers"; err == nil || !strings.Contains(err.Error(), exp) {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+}
+
+func TestDecode_intBounds(t *testing.T) {
+	type widths struct {
+		I   int    `q:"i"`
+		I8  int8   `q:"i8"`
+		I16 int16  `q:"i16"`
+		I32 int32  `q:"i32"`
+		I64 int64  `q:"i64"`
+		U   uint   `q:"u"`
+		U8  uint8  `q:"u8"`
+		U16 uint16 `q:"u16"`
+		U32 uint32 `q:"u32"`
+		U64 uint64 `q:"u64"`
+	}
+	for _, test := range []struct {
+		query string
+		err   string
+		is    error
+	}{
+		{"i=9223372036854775808", "value exceeds maximum 9223372036854775807 for this parameter", strconv.ErrRange},
+		{"i=-9223372036854775809", "value is below minimum -9223372036854775808 for this parameter", strconv.ErrRange},
+		{"i8=128", "value exceeds maximum 127 for this parameter", strconv.ErrRange},
+		{"i8=-129", "value is below minimum -128 for this parameter", strconv.ErrRange},
+		{"i16=32768", "value exceeds maximum 32767 for this parameter", strconv.ErrRange},
+		{"i16=-32769", "value is below minimum -32768 for this parameter", strconv.ErrRange},
+		{"i32=2147483648", "value exceeds maximum 2147483647 for this parameter", strconv.ErrRange},
+		{"i32=-2147483649", "value is below minimum -2147483648 for this parameter", strconv.ErrRange},
+		{"i64=9223372036854775808", "value exceeds maximum 9223372036854775807 for this parameter", strconv.ErrRange},
+		{"i64=-9223372036854775809", "value is below minimum -9223372036854775808 for this parameter", strconv.ErrRange},
+		{"u=-1", "value must be a non-negative integer", strconv.ErrSyntax},
+		{"u=18446744073709551616", "value exceeds maximum 18446744073709551615 for this parameter", strconv.ErrRange},
+		{"u8=-1", "value must be a non-negative integer", strconv.ErrSyntax},
+		{"u8=256", "value exceeds maximum 255 for this parameter", strconv.ErrRange},
+		{"u16=-1", "value must be a non-negative integer", strconv.ErrSyntax},
+		{"u16=65536", "value exceeds maximum 65535 for this parameter", strconv.ErrRange},
+		{"u32=-99999999999", "value must be a non-negative integer", strconv.ErrSyntax},
+		{"u32=4294967296", "value exceeds maximum 4294967295 for this parameter", strconv.ErrRange},
+		{"u64=-1", "value must be a non-negative integer", strconv.ErrSyntax},
+		{"u64=18446744073709551616", "value exceeds maximum 18446744073709551615 for this parameter", strconv.ErrRange},
+		{"u8=-x", "invalid syntax", strconv.ErrSyntax},
+	} {
+		var got widths
+		err := NewDecoder(test.query).Decode(&got)
+
+		var typeErr *UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			t.Fatalf("exp: %T\ngot: %v", typeErr, err)
+		}
+		key, val, _ := strings.Cut(test.query, "=")
+		if typeErr.Key != key || typeErr.Value != val {
+			t.Fatalf("exp: %v=%v\ngot: %v=%v", key, val, typeErr.Key, typeErr.Value)
+		}
+		if !strings.HasSuffix(err.Error(), ": "+test.err) {
+			t.Fatalf("exp: %v\ngot: %v", test.err, err)
+		}
+		if !errors.Is(err, test.is) {
+			t.Fatalf("exp: %v\ngot: %v", test.is, err)
+		}
+	}
+
+	type rates struct {
+		Rate int16 `q:"rate,scale=2"`
+	}
+	err := NewDecoder("rate=327.68").Decode(&rates{})
+	if exp := "value exceeds maximum 327.67 for this parameter"; err == nil || !strings.HasSuffix(err.Error(), exp) {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+}
+
+func TestDecode_lenientInt(t *testing.T) {
+	type installments struct {
+		N     int8    `q:"n,lenientint"`
+		U     uint16  `q:"u,lenientint"`
+		R     int64   `q:"r,lenientint=1e-6"`
+		Items []int32 `q:"items,comma,lenientint"`
+	}
+	for _, test := range []struct {
+		query string
+		exp   installments
+		err   string
+	}{
+		{"n=3.0", installments{N: 3}, ""},
+		{"n=-3.", installments{N: -3}, ""},
+		{"n=127.000", installments{N: 127}, ""},
+		{"n=-128.0", installments{N: -128}, ""},
+		{"n=128.0", installments{}, `query: cannot decode "128.0" into n of type int8: strconv.ParseInt: parsing "128.0": value exceeds maximum 127 for this parameter`},
+		{"n=3.5", installments{}, `query: cannot decode "3.5" into n of type int8: strconv.ParseInt: parsing "3.5": invalid syntax`},
+		{"n=3.0000001", installments{}, `query: cannot decode "3.0000001" into n of type int8: strconv.ParseInt: parsing "3.0000001": invalid syntax`},
+		{"u=65535.0", installments{U: 65535}, ""},
+		{"u=-0.0", installments{}, `query: cannot decode "-0.0" into u of type uint16: strconv.ParseUint: parsing "-0.0": value must be a non-negative integer`},
+		{"r=9223372036854775807.0", installments{R: math.MaxInt64}, ""},
+		{"r=2.9999999", installments{R: 3}, ""},
+		{"r=2.5", installments{}, `query: cannot decode "2.5" into r of type int64: strconv.ParseInt: parsing "2.5": invalid syntax`},
+		{"r=1e3", installments{R: 1000}, ""},
+		{"r=9007199254740993.0000001", installments{R: 9007199254740993}, ""},
+		{"r=-9223372036854775807.9999999", installments{R: math.MinInt64}, ""},
+		{"r=99.9999999", installments{R: 100}, ""},
+		{"r=.0000001", installments{R: 0}, ""},
+		{"r=1.0000000000000001e16", installments{}, `query: cannot decode "1.0000000000000001e16" into r of type int64: strconv.ParseInt: parsing "1.0000000000000001e16": invalid syntax`},
+		{"items=1.0,2,3.00", installments{Items: []int32{1, 2, 3}}, ""},
+	} {
+		var got installments
+		err := NewDecoder(test.query).Decode(&got)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Fatalf("exp: %v\ngot: %v", test.err, err)
+			}
+			continue
+		}
+		ok(t, err)
+		if !reflect.DeepEqual(test.exp, got) {
+			t.Fatalf("exp: %+v\ngot: %+v", test.exp, got)
+		}
+	}
+}
+
+func TestDecode_scale(t *testing.T) {
+	type fx struct {
+		Rate   int64   `q:"rate,scale=4"`
+		Spread uint32  `q:"spread,scale=2"`
+		Rates  []int64 `q:"rates,comma,scale=3"`
+	}
+	for _, test := range []struct {
+		query string
+		exp   fx
+		err   string
+	}{
+		{"rate=0.1234", fx{Rate: 1234}, ""},
+		{"rate=-1.5", fx{Rate: -15000}, ""},
+		{"rate=12", fx{Rate: 120000}, ""},
+		{"rate=922337203685477.5807", fx{Rate: math.MaxInt64}, ""},
+		{"spread=0.05&rates=1.001,-0.5", fx{Spread: 5, Rates: []int64{1001, -500}}, ""},
+		{"rate=0.12345", fx{}, `query: cannot decode "0.12345" into rate of type int64: strconv.ParseInt: parsing "0.12345": more than 4 decimal places`},
+		{"rate=1e-4", fx{}, `query: cannot decode "1e-4" into rate of type int64: strconv.ParseInt: parsing "1e-4": invalid syntax`},
+		{"rate=%2B1", fx{}, `query: cannot decode "+1" into rate of type int64: strconv.ParseInt: parsing "+1": invalid syntax`},
+		{"rate=922337203685477.5808", fx{}, `query: cannot decode "922337203685477.5808" into rate of type int64: strconv.ParseInt: parsing "922337203685477.5808": value exceeds maximum 922337203685477.5807 for this parameter`},
+		{"spread=-0.01", fx{}, `query: cannot decode "-0.01" into spread of type uint32: strconv.ParseUint: parsing "-0.01": value must be a non-negative integer`},
+	} {
+		var got fx
+		err := NewDecoder(test.query).Decode(&got)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Fatalf("exp: %v\ngot: %v", test.err, err)
+			}
+			continue
+		}
+		ok(t, err)
+		if !reflect.DeepEqual(test.exp, got) {
+			t.Fatalf("exp: %+v\ngot: %+v", test.exp, got)
+		}
+	}
+}
+
+func TestSetDefaultOptions(t *testing.T) {
+	SetDefaultOptions(WithMaxQueryLength(8), WithEmptyAsMissing())
+	t.Cleanup(func() { SetDefaultOptions() })
+
+	err := NewDecoder("q=foo&page=2").Decode(&listOptions{})
+	if exp := (&QueryTooLongError{Length: 12, Max: 8}); !reflect.DeepEqual(exp, err) {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+	var got listOptions
+	ok(t, NewDecoder("q=foo&page=", WithMaxQueryLength(0)).Decode(&got))
+	if exp := (listOptions{Query: "foo"}); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	d := NewDecoder("q=foo&page=2")
+	SetDefaultOptions()
+	if err := d.Decode(&listOptions{}); !errors.Is(err, ErrTooLong) {
+		t.Fatalf("exp: %v\ngot: %v", ErrTooLong, err)
+	}
+	ok(t, NewDecoder("q=foo&page=2").Decode(&listOptions{}))
+}
+
+func TestSetDefaultOptions_race(t *testing.T) {
+	t.Cleanup(func() { SetDefaultOptions() })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				SetDefaultOptions(WithMaxDepth(i+j), WithEmptyAsMissing())
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				var got listOptions
+				if err := NewDecoder("q=foo&page=2").Decode(&got); err != nil {
+					t.Error(err)
+				}
+				if got.Query != "foo" || got.Page != 2 {
+					t.Errorf("exp: %v\ngot: %+v", "q=foo&page=2", got)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDecodeValue(t *testing.T) {
+	var got struct {
+		Opts    listOptions
+		private listOptions
+	}
+	rv := reflect.ValueOf(&got).Elem()
+	ok(t, NewDecoder("q=foo&page=2").DecodeValue(rv.Field(0)))
+	ok(t, NewDecoder("filter[status]=open").DecodeValue(reflect.ValueOf(&got.Opts)))
+	exp := listOptions{Query: "foo", Filter: filter{Status: "open"}, pagination: pagination{Page: 2}}
+	if !reflect.DeepEqual(exp, got.Opts) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got.Opts)
+	}
+
+	for _, test := range []struct {
+		rv  reflect.Value
+		err error
+	}{
+		{reflect.Value{}, &InvalidUnmarshalError{nil}},
+		{reflect.ValueOf(listOptions{}), &InvalidUnmarshalError{reflect.TypeOf(listOptions{})}},
+		{reflect.ValueOf((*listOptions)(nil)), &InvalidUnmarshalError{reflect.TypeOf((*listOptions)(nil))}},
+		{rv.Field(1), &InvalidUnmarshalError{reflect.TypeOf(listOptions{})}},
+	} {
+		err := NewDecoder("q=foo").DecodeValue(test.rv)
+		if !reflect.DeepEqual(test.err, err) {
+			t.Fatalf("exp: %v\ngot: %v", test.err, err)
+		}
+	}
+}
+
+func TestDecode_boolPresence(t *testing.T) {
+	type flat struct {
+		Flag bool `q:"flag"`
+	}
+	type params struct {
+		Flag   bool     `q:"flag"`
+		Opt    *bool    `q:"opt"`
+		Status []string `q:"status"`
+	}
+	yes, no := true, false
+
+	for _, test := range []struct {
+		query string
+		flag  bool
+		opt   *bool
+	}{
+		{"", false, nil},
+		{"flag&opt", true, &yes},
+		{"flag=&opt=", true, &yes},
+		{"flag=true&opt=true", true, &yes},
+		{"flag=false&opt=false", false, &no},
+		{"opt=0", false, &no},
+	} {
+		var f flat
+		ok(t, NewDecoder(test.query).Decode(&f))
+		if f.Flag != test.flag {
+			t.Fatalf("%q: exp: %v\ngot: %v", test.query, test.flag, f.Flag)
+		}
+
+		var got params
+		ok(t, NewDecoder(test.query).Decode(&got))
+		exp := params{Flag: test.flag, Opt: test.opt}
+		if !reflect.DeepEqual(exp, got) {
+			t.Fatalf("%q: exp: %+v\ngot: %+v", test.query, exp, got)
+		}
+	}
+
+	got := params{Opt: new(bool)}
+	ok(t, NewDecoder("opt").Decode(&got))
+	if got.Opt == nil || !*got.Opt {
+		t.Fatalf("exp: %v\ngot: %v", true, got.Opt)
+	}
+
+	got = params{}
+	skip := WithErrorHandler(func(string, error) error { return nil })
+	ok(t, NewDecoder("opt=maybe", skip).Decode(&got))
+	if got.Opt != nil {
+		t.Fatalf("exp: %v\ngot: %v", nil, *got.Opt)
+	}
+	if err := NewDecoder("opt=maybe").Decode(&got); !errors.Is(err, ErrInvalidValue) || got.Opt != nil {
+		t.Fatalf("exp: %v, %v\ngot: %v, %v", ErrInvalidValue, nil, err, got.Opt)
+	}
+
+	got = params{}
+	ok(t, NewDecoder("opt=", WithEmptyAsMissing()).Decode(&got))
+	if got.Opt == nil || !*got.Opt {
+		t.Fatalf("exp: %v\ngot: %v", true, got.Opt)
+	}
+}
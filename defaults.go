@@ -0,0 +1,111 @@
+package query
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+)
+
+// WithDefaults makes the decoder copy the fields of proto that are not zero
+// into the struct it decodes into, before decoding the query, so that the
+// keys present in the query override them field by field. proto is a struct
+// of the type decoded into, or a pointer to one; Decode fails when it is of
+// another type. Pointers, slices and maps are copied along with what they
+// hold, so decoding never modifies proto.
+//
+// The "default" tag options still apply to the keys absent from the query,
+// after proto is copied.
+func WithDefaults(proto interface{}) Option {
+	return func(d *Decoder) {
+		d.defaults = proto
+	}
+}
+
+// prepopulate copies the fields of the defaults of the decoder that are not
+// zero into the struct dst.
+func (d *Decoder) prepopulate(dst reflect.Value) error {
+	if d.defaults == nil || d.presentOnly {
+		return nil
+	}
+	proto := reflect.Indirect(reflect.ValueOf(d.defaults))
+	if !proto.IsValid() || proto.Type() != dst.Type() || dst.Kind() != reflect.Struct {
+		return fmt.Errorf("query: defaults of type %T for a value of type %s", d.defaults, dst.Type())
+	}
+	copyFields(dst, proto)
+	return nil
+}
+
+// copyFields copies the fields of the struct src that are not zero into the
+// struct dst, of the same type, and into the fields of its unexported
+// embedded structs.
+func copyFields(dst, src reflect.Value) {
+	for i := 0; i < src.NumField(); i++ {
+		fv, df := src.Field(i), dst.Field(i)
+		switch {
+		case fv.IsZero():
+		case df.CanSet():
+			df.Set(deepCopy(fv))
+		case src.Type().Field(i).Anonymous && fv.Kind() == reflect.Struct:
+			copyFields(df, fv)
+		}
+	}
+}
+
+// deepCopy returns a copy of v that shares no memory with it through its
+// pointers, slices and maps, or through the exported fields of its structs.
+// big.Int and big.Rat values, whose fields are unexported, are copied with
+// their Set methods. The unexported fields of other structs are copied as
+// they are.
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Type() {
+	case bigIntType:
+		b := v.Interface().(big.Int)
+		return reflect.ValueOf(new(big.Int).Set(&b)).Elem()
+	case bigRatType:
+		r := v.Interface().(big.Rat)
+		return reflect.ValueOf(new(big.Rat).Set(&r)).Elem()
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for it := v.MapRange(); it.Next(); {
+			c.SetMapIndex(it.Key(), deepCopy(it.Value()))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	}
+	return v
+}
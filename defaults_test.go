@@ -0,0 +1,81 @@
+package query
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestDecode_WithDefaults(t *testing.T) {
+	type options struct {
+		Query  string            `q:"q"`
+		Limit  *int              `q:"limit"`
+		Sort   string            `q:"sort,default=asc"`
+		Tags   []string          `q:"tag"`
+		Filter map[string]string `q:"filter"`
+		pagination
+	}
+	zero, ten := 0, 10
+	proto := options{
+		Query:      "all",
+		Limit:      &zero,
+		Sort:       "desc",
+		Tags:       []string{"a", "b"},
+		Filter:     map[string]string{"status": "open"},
+		pagination: pagination{PerPage: 20},
+	}
+
+	var got options
+	ok(t, NewDecoder("limit=10&tag=c&filter[owner]=me&page=2", WithDefaults(&proto)).Decode(&got))
+	exp := options{
+		Query:      "all",
+		Limit:      &ten,
+		Sort:       "asc",
+		Tags:       []string{"c"},
+		Filter:     map[string]string{"status": "open", "owner": "me"},
+		pagination: pagination{Page: 2, PerPage: 20},
+	}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+	if zero != 0 || !reflect.DeepEqual([]string{"a", "b"}, proto.Tags) || len(proto.Filter) != 1 {
+		t.Fatalf("exp: %v\ngot: %+v", "proto left untouched", proto)
+	}
+
+	got = options{}
+	ok(t, NewDecoder("", WithDefaults(proto)).Decode(&got))
+	if got.Query != "all" || *got.Limit != 0 || got.PerPage != 20 {
+		t.Fatalf("exp: %+v\ngot: %+v", proto, got)
+	}
+
+	err := NewDecoder("page=1", WithDefaults(pagination{})).Decode(&got)
+	if exp, got := "query: defaults of type query.pagination for a value of type query.options", err; got == nil || got.Error() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+}
+
+func TestDecode_WithDefaultsBig(t *testing.T) {
+	type params struct {
+		N *big.Int `q:"n"`
+		R big.Rat  `q:"r"`
+	}
+	proto := params{N: big.NewInt(123456789), R: *big.NewRat(1, 2)}
+	c := NewConfig(WithDefaults(&proto))
+
+	var got params
+	ok(t, c.Decode("n=7&r=0.25", &got))
+	if got.N.Int64() != 7 || got.R.String() != "1/4" {
+		t.Fatalf("exp: %v %v\ngot: %v %v", 7, "1/4", got.N, got.R.String())
+	}
+	if proto.N.Int64() != 123456789 || proto.R.String() != "1/2" {
+		t.Fatalf("exp: %v %v\ngot: %v %v", 123456789, "1/2", proto.N, proto.R.String())
+	}
+
+	// a pointer held before the call is replaced, not written through
+	n := big.NewInt(5)
+	got = params{N: n}
+	ok(t, NewDecoder("n=8").Decode(&got))
+	if n.Int64() != 5 || got.N.Int64() != 8 {
+		t.Fatalf("exp: %v %v\ngot: %v %v", 5, 8, n, got.N)
+	}
+}
@@ -0,0 +1,140 @@
+package query
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+)
+
+// A FieldChange describes a value of a query key that differs between the
+// two structs given to Diff.
+type FieldChange struct {
+	Key   string // query key, such as "filter[status]"
+	Index int    // position of the value among the values of the key
+	Old   string // value in the old struct, empty when added
+	New   string // value in the new struct, empty when removed
+
+	Added   bool // whether the value is only in the new struct
+	Removed bool // whether the value is only in the old struct
+}
+
+// Diff returns the changes between old and new, two structs of the same type
+// or pointers to them, such as the filters of a search before and after the
+// user refines it. Only the fields with a "q" tag are compared, and the
+// fields nested in them. Both are encoded as by Values, so that values are
+// formatted as in a query, a nil pointer as an empty value. The changes are listed by key in sorted order: nested
+// structs and maps are compared key by key, and slices element by element,
+// including those encoded as delimited lists. The values of fields tagged
+// with the "secret" option are reported as "REDACTED", so that a change of
+// a secret shows without the secret itself.
+func Diff(old, new interface{}) ([]FieldChange, error) {
+	t, nt := structType(old), structType(new)
+	if t == nil {
+		return nil, &UnsupportedTypeError{Type: reflect.TypeOf(old)}
+	}
+	if nt != t {
+		return nil, fmt.Errorf("query: Diff of values of types %v and %v", reflect.TypeOf(old), reflect.TypeOf(new))
+	}
+
+	ov, err := Values(old)
+	if err != nil {
+		return nil, err
+	}
+	nv, err := Values(new)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		d       Decoder
+		dels    = make(map[string]byte)
+		keys    []string
+		secrets []string
+	)
+	for _, info := range d.fieldInfos(nil, t, "", "", make(map[reflect.Type]bool)) {
+		keys = append(keys, info.Key)
+		opts := tagOptions(info.Options)
+		if del := opts.delimiter(); del != 0 && info.Slice {
+			dels[info.Key] = del
+		}
+		if opts.Contains("secret") {
+			secrets = append(secrets, info.Key)
+		}
+	}
+
+	var changes []FieldChange
+	for _, k := range unionKeys(ov, nv) {
+		if !matchesKey(k, keys) {
+			continue
+		}
+		o, n := ov[k], nv[k]
+		if del, ok := dels[k]; ok {
+			o, n = splitList(o, del), splitList(n, del)
+		}
+		for i := 0; i < len(o) || i < len(n); i++ {
+			c := FieldChange{Key: k, Index: i}
+			switch {
+			case i >= len(o):
+				c.New, c.Added = n[i], true
+			case i >= len(n):
+				c.Old, c.Removed = o[i], true
+			case o[i] != n[i]:
+				c.Old, c.New = o[i], n[i]
+			default:
+				continue
+			}
+			if matchesKey(k, secrets) {
+				c.Old, c.New = redact(c.Old), redact(c.New)
+			}
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+// redact returns the value of a secret field as Diff reports it: redacted,
+// unless empty.
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// structType returns the struct type of v, or of the value v points to, or
+// nil when v is neither.
+func structType(v interface{}) reflect.Type {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// unionKeys returns the keys of a and b in sorted order.
+func unionKeys(a, b url.Values) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// splitList returns the elements of the delimited list vals holds, the
+// single value the encoder writes for a slice with a delimiter.
+func splitList(vals []string, del byte) []string {
+	if len(vals) != 1 {
+		return vals
+	}
+	return splitElems(vals[0], del)
+}
@@ -0,0 +1,57 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	type filter struct {
+		Status string `q:"status"`
+		Owner  *int   `q:"owner"`
+	}
+	type search struct {
+		Query  string   `q:"q"`
+		Tags   []string `q:"tag"`
+		Labels []string `q:"labels,comma"`
+		Filter filter   `q:"filter"`
+		Secret string   `q:"-"`
+		Token  string   `q:"token,secret"`
+		Keys   []string `q:"key,secret"`
+		Notes  string
+	}
+
+	owner := 7
+	old := search{Query: "shoes", Tags: []string{"a", "b"}, Labels: []string{"x", "y,z"}, Filter: filter{Status: "open"}, Secret: "1", Token: "s3cret", Keys: []string{"k1"}, Notes: "a"}
+	new := search{Query: "shoes", Tags: []string{"a"}, Labels: []string{"x", "w"}, Filter: filter{Status: "closed", Owner: &owner}, Secret: "2", Token: "n3w", Keys: []string{"k1", "k2"}, Notes: "b"}
+
+	changes, err := Diff(old, &new)
+	ok(t, err)
+	exp := []FieldChange{
+		{Key: "filter[owner]", New: "7"},
+		{Key: "filter[status]", Old: "open", New: "closed"},
+		{Key: "key", Index: 1, New: "REDACTED", Added: true},
+		{Key: "labels", Index: 1, Old: "y,z", New: "w"},
+		{Key: "tag", Index: 1, Old: "b", Removed: true},
+		{Key: "token", Old: "REDACTED", New: "REDACTED"},
+	}
+	if !reflect.DeepEqual(changes, exp) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, changes)
+	}
+
+	t.Run("equal", func(t *testing.T) {
+		changes, err := Diff(&old, old)
+		ok(t, err)
+		if changes != nil {
+			t.Fatalf("exp: %v\ngot: %+v", nil, changes)
+		}
+	})
+
+	t.Run("types", func(t *testing.T) {
+		for _, vals := range [][2]interface{}{{2, 2}, {old, filter{}}} {
+			if _, err := Diff(vals[0], vals[1]); err == nil {
+				t.Fatalf("%T and %T\nexp: error\ngot: %v", vals[0], vals[1], err)
+			}
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// A DuplicateKeyError describes a struct type passed to Decode with two
+// fields reading the same query key, which would otherwise both be set, or
+// shadow one another depending on their order.
+type DuplicateKeyError struct {
+	Type   reflect.Type // struct type passed to Decode
+	Key    string       // query key
+	Fields []string     // paths of the two fields, such as "Page" and "Paging.Page"
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return "query: fields " + strings.Join(e.Fields, " and ") + " of " + e.Type.String() + " both read key " + e.Key
+}
+
+// Is reports whether target is ErrDuplicateKey.
+func (e *DuplicateKeyError) Is(target error) bool {
+	return target == ErrDuplicateKey
+}
+
+// Code returns CodeDuplicateKey.
+func (e *DuplicateKeyError) Code() string {
+	return CodeDuplicateKey
+}
+
+// Params returns the key and the paths of the fields reading it.
+func (e *DuplicateKeyError) Params() map[string]interface{} {
+	return map[string]interface{}{"key": e.Key, "fields": e.Fields}
+}
+
+// WithAllowDuplicateKeys makes the decoder accept struct types with several
+// fields reading the same query key, for the rare types meant to have them:
+// each of those fields is then set from the key.
+func WithAllowDuplicateKeys() Option {
+	return func(d *Decoder) {
+		d.allowDuplicates = true
+	}
+}
+
+type duplicatesKey struct {
+	t         reflect.Type
+	style     KeyStyle
+	canonical bool // whether keys are canonicalized, as by DecodeHeader
+}
+
+// duplicates caches the result of checkDuplicates for each struct type and
+// way of writing keys.
+var duplicates sync.Map // map[duplicatesKey]*DuplicateKeyError, nil for the types without one
+
+// checkDuplicates returns a *DuplicateKeyError when two fields of the struct
+// type t, including those of its embedded and nested structs, read the same
+// query key, unless the decoder allows it. Keys are compared as the decoder
+// reads them, so that two spellings of a header name that DecodeHeader maps
+// to the same one are caught as well.
+func (d *Decoder) checkDuplicates(t reflect.Type) error {
+	if d.allowDuplicates {
+		return nil
+	}
+	ck := duplicatesKey{t, d.keyStyle, d.canonicalKey != nil}
+	if err, ok := duplicates.Load(ck); ok {
+		return errorOrNil(err.(*DuplicateKeyError))
+	}
+	w := &keyWalker{d: d, keys: make(map[string]string), visiting: make(map[reflect.Type]bool)}
+	var err *DuplicateKeyError
+	if key, fields, dup := w.walk(t, "", ""); dup {
+		err = &DuplicateKeyError{Type: t, Key: key, Fields: fields}
+	}
+	duplicates.Store(ck, err)
+	return errorOrNil(err)
+}
+
+// errorOrNil returns err as an error, nil when err is nil.
+func errorOrNil(err *DuplicateKeyError) error {
+	if err == nil {
+		return nil
+	}
+	return err
+}
+
+// keyWalker collects the keys read by the fields of a struct type.
+type keyWalker struct {
+	d        *Decoder
+	keys     map[string]string // path of the field reading each key
+	visiting map[reflect.Type]bool
+}
+
+// walk adds the keys of the fields of the struct type t, scoped by scope and
+// named after path, and returns the first key read by two fields.
+func (w *keyWalker) walk(t reflect.Type, scope, path string) (string, []string, bool) {
+	if w.visiting[t] {
+		return "", nil, false
+	}
+	w.visiting[t] = true
+	defer delete(w.visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if fieldGroups(sf) != nil {
+			continue
+		}
+		key, opts, ok := w.d.fieldKey(sf, scope)
+		if !ok || opts.Contains("inline") {
+			continue
+		}
+		field := path + sf.Name
+
+		var keys []string
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch {
+		case ft.Kind() == reflect.Struct && isNested(ft):
+			if key != scope && w.d.keyStyle != FlatKeys {
+				keys = append(keys, key)
+			}
+			if k, fields, dup := w.walk(ft, key, field+"."); dup {
+				return k, fields, true
+			}
+		default:
+			if from, to, split := w.d.keyStyle.rangeKeys(scope, opts); split {
+				keys = append(keys, from, to)
+			} else if key != "" {
+				keys = append(keys, key)
+			}
+		}
+
+		for _, k := range keys {
+			if prev, dup := w.keys[k]; dup {
+				return k, []string{prev, field}, true
+			}
+			w.keys[k] = field
+		}
+	}
+	return "", nil, false
+}
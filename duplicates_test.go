@@ -0,0 +1,70 @@
+package query
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestDecode_DuplicateKeys(t *testing.T) {
+	type shadowed struct {
+		Page int `q:"page"`
+		pagination
+	}
+	type scoped struct {
+		Page   int        `q:"page"`
+		Paging pagination `q:"paging"`
+	}
+	type ranges struct {
+		From   string    `q:"from"`
+		Period TimeRange `q:"period,prefix"`
+	}
+	type flat struct {
+		Page   int        `q:"page"`
+		Paging pagination `q:"paging"`
+	}
+
+	err := NewDecoder("page=2").Decode(&shadowed{})
+	exp := &DuplicateKeyError{Type: reflect.TypeOf(shadowed{}), Key: "page", Fields: []string{"Page", "pagination.Page"}}
+	if !reflect.DeepEqual(exp, err) {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("exp: %v\ngot: %v", ErrDuplicateKey, err)
+	}
+	if exp := "query: fields Page and pagination.Page of query.shadowed both read key page"; err.Error() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+
+	err = NewDecoder("from=x").Decode(&ranges{})
+	exp = &DuplicateKeyError{Type: reflect.TypeOf(ranges{}), Key: "from", Fields: []string{"From", "Period"}}
+	if !reflect.DeepEqual(exp, err) {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+
+	ok(t, NewDecoder("page=2&paging[page]=3").Decode(&scoped{}))
+	err = NewDecoder("page=2", WithKeyStyle(FlatKeys)).Decode(&flat{})
+	exp = &DuplicateKeyError{Type: reflect.TypeOf(flat{}), Key: "page", Fields: []string{"Page", "Paging.Page"}}
+	if !reflect.DeepEqual(exp, err) {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+
+	var got shadowed
+	ok(t, NewDecoder("page=2", WithAllowDuplicateKeys()).Decode(&got))
+	if got.Page != 2 || got.pagination.Page != 2 {
+		t.Fatalf("exp: %v\ngot: %+v", "both pages set", got)
+	}
+}
+
+func TestDecodeHeader_DuplicateKeys(t *testing.T) {
+	var got struct {
+		Token  string `q:"x-token"`
+		Token2 string `q:"X-Token"`
+	}
+	err := DecodeHeader(http.Header{"X-Token": {"a"}}, &got)
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("exp: %v\ngot: %v", ErrDuplicateKey, err)
+	}
+	ok(t, NewDecoder("x-token=a").Decode(&got))
+}
@@ -0,0 +1,80 @@
+package query
+
+import (
+	"net/url"
+	"strings"
+)
+
+// An EmptyKeyRule is the way the decoder handles the pairs of a query with
+// an empty key, such as "=value" or a bare "=". Empty pairs, as between the
+// ampersands of "&&foo=1" or after a trailing one, aren't pairs at all, and
+// are always skipped.
+type EmptyKeyRule int
+
+const (
+	// EmptyKeyIgnore drops the pairs with an empty key silently. It is the
+	// default.
+	EmptyKeyIgnore EmptyKeyRule = iota
+
+	// EmptyKeyWarn drops the pairs with an empty key with a warning.
+	EmptyKeyWarn
+
+	// EmptyKeyReject makes Decode fail with an *EmptyKeyError.
+	EmptyKeyReject
+)
+
+// WithEmptyKeys sets the way the decoder handles the pairs of a query with an
+// empty key.
+func WithEmptyKeys(r EmptyKeyRule) Option {
+	return func(d *Decoder) {
+		d.emptyKeys = r
+	}
+}
+
+// An EmptyKeyError describes pairs with an empty key in a query, which the
+// decoder refuses with EmptyKeyReject.
+type EmptyKeyError struct {
+	Values []string // values of the pairs
+}
+
+func (e *EmptyKeyError) Error() string {
+	return "query: empty key with value " + strings.Join(e.Values, ",")
+}
+
+// Is reports whether target is ErrEmptyKey.
+func (e *EmptyKeyError) Is(target error) bool {
+	return target == ErrEmptyKey
+}
+
+// Code returns CodeEmptyKey.
+func (e *EmptyKeyError) Code() string {
+	return CodeEmptyKey
+}
+
+// Params returns the values of the pairs.
+func (e *EmptyKeyError) Params() map[string]interface{} {
+	return map[string]interface{}{"values": e.Values}
+}
+
+// dropEmptyKey returns src without its empty key, handled according to the
+// empty key rule of the decoder.
+func (d *Decoder) dropEmptyKey(src url.Values) (url.Values, error) {
+	vals, ok := src[""]
+	if !ok {
+		return src, nil
+	}
+	switch d.emptyKeys {
+	case EmptyKeyReject:
+		return nil, &EmptyKeyError{Values: vals}
+	case EmptyKeyWarn:
+		d.warn("", strings.Join(vals, ","), WarnEmptyKey, nil)
+	}
+
+	kept := make(url.Values, len(src)-1)
+	for k, vals := range src {
+		if k != "" {
+			kept[k] = vals
+		}
+	}
+	return kept, nil
+}
@@ -0,0 +1,43 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecode_WithEmptyKeys(t *testing.T) {
+	type params struct {
+		Foo int `q:"foo"`
+	}
+	for _, query := range []string{"&&foo=1", "&foo=1&", "foo=1&&&", "=value&foo=1", "foo=1&=", "=&=x&foo=1"} {
+		for _, rule := range []EmptyKeyRule{EmptyKeyIgnore, EmptyKeyWarn} {
+			var got params
+			d := NewDecoder(query, WithEmptyKeys(rule))
+			ok(t, d.Decode(&got))
+			if got.Foo != 1 {
+				t.Fatalf("%q: exp: %v\ngot: %v", query, 1, got.Foo)
+			}
+			if w := d.Warnings(); rule == EmptyKeyIgnore && w != nil {
+				t.Fatalf("%q: exp: %v\ngot: %v", query, nil, w)
+			}
+		}
+	}
+
+	d := NewDecoder("=&=x&foo=1", WithEmptyKeys(EmptyKeyWarn))
+	ok(t, d.Decode(&params{}))
+	expWarnings := []Warning{{Key: "", Value: ",x", Action: WarnEmptyKey}}
+	if got := d.Warnings(); !reflect.DeepEqual(expWarnings, got) {
+		t.Fatalf("exp: %v\ngot: %v", expWarnings, got)
+	}
+
+	err := NewDecoder("=value&foo=1", WithEmptyKeys(EmptyKeyReject)).Decode(&params{})
+	exp := &EmptyKeyError{Values: []string{"value"}}
+	if !reflect.DeepEqual(exp, err) {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+	if !errors.Is(err, ErrEmptyKey) || err.(CodedError).Code() != CodeEmptyKey {
+		t.Fatalf("exp: %v\ngot: %v", ErrEmptyKey, err)
+	}
+	ok(t, NewDecoder("&&foo=1&", WithEmptyKeys(EmptyKeyReject)).Decode(&params{}))
+}
@@ -23,18 +23,27 @@
 package query
 
 import (
+	"bufio"
 	"bytes"
+	"encoding"
 	"fmt"
+	"io"
 	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
-)
 
-var timeType = reflect.TypeOf(time.Time{})
+	"github.com/Finciero/go-queryparams/internal/tagspec"
+)
 
-var encoderType = reflect.TypeOf(new(Encoder)).Elem()
+var (
+	encoderType       = reflect.TypeOf(new(Encoder)).Elem()
+	marshalerType     = reflect.TypeOf(new(Marshaler)).Elem()
+	textMarshalerType = reflect.TypeOf(new(encoding.TextMarshaler)).Elem()
+	stringerType      = reflect.TypeOf(new(fmt.Stringer)).Elem()
+)
 
 // Encoder is an interface implemented by any type that wishes to encode
 // itself into URL values in a non-standard way.
@@ -42,6 +51,13 @@ type Encoder interface {
 	EncodeValues(key string, v *url.Values) error
 }
 
+// Marshaler is the interface implemented by types that encode themselves
+// into the values of a single query parameter. It is the counterpart of
+// Unmarshaler.
+type Marshaler interface {
+	MarshalQuery() ([]string, error)
+}
+
 // Values returns the url.Values encoding of v.
 //
 // Values expects to be passed a struct, and traverses it recursively using the
@@ -54,7 +70,14 @@ type Encoder interface {
 //
 // The empty values are false, 0, any nil pointer or interface value, any array
 // slice, map, or string of length zero, and any time.Time that returns true
-// for IsZero().
+// for IsZero(). A pointer to an empty value is not empty. The EncodeOmitEmpty
+// option applies "omitempty" to every field; a field tagged with the
+// "keepzero" option is always encoded regardless.
+//
+// A map is encoded as its entries, so that neither a nil nor an empty map
+// writes anything. With the "keepempty" option, an empty map that is not nil
+// is written as its key with an empty value, as in "tags=", which the
+// decoder reads back as an empty map, even with "omitempty".
 //
 // The URL parameter name defaults to the struct field name but can be
 // specified in the struct field's tag value.  The "url" key in the struct
@@ -80,65 +103,429 @@ type Encoder interface {
 //
 // Boolean values default to encoding as the strings "true" or "false".
 // Including the "int" option signals that the field should be encoded as the
-// strings "1" or "0".
+// strings "1" or "0". Including the "flag" option encodes a true value as a
+// parameter without value ("verbose" rather than "verbose=true", although
+// Values can only represent it as an empty value) and omits a false one; the
+// decoder reads a key without value as true.
 //
 // time.Time values default to encoding as RFC3339 timestamps.  Including the
 // "unix" option signals that the field should be encoded as a Unix time (see
-// time.Unix())
+// time.Unix()), "unixmilli" as a Unix time in milliseconds, and "layout="
+// followed by a reference layout as formatted by time.Format. "tz=" followed
+// by a location name, such as "tz=America/Santiago", formats them in that
+// location. Zero times are encoded like any other unless the
+// EncodeRejectZeroTime option is given.
+//
+// Date values are encoded in the form 2006-01-02, and TimeRange and Money
+// values as described by their types.
+//
+// time.Duration values default to encoding as Duration.String().  Including
+// the "unit=" option followed by one of ns, us, ms, s, m or h encodes them as
+// a bare number of that unit instead; any other unit fails encoding. The
+// decoder reads both forms, and also reads a bare integer without "unit=" as
+// a number of nanoseconds, as it did before durations were written as text.
+//
+// Integer values default to base 10. Including the "base=" option followed
+// by a base between 2 and 36 encodes them in that base, with upper case
+// letters, as "1F" for 31 with base=16, and the "pad=" option followed by a
+// number of digits pads them with leading zeros, as "007" for 7 with pad=3.
+// A negative integer is written as a minus sign followed by the digits of
+// its absolute value, padded as those of a positive one: "-1F", "-007". The
+// decoder reads integers in the base of the field.
+//
+// Float values default to their shortest representation. Including the
+// "prec=" option followed by a number of digits encodes them with that many
+// decimals, as "0.10" for 0.1 with prec=2, and the "fmt=" option followed
+// by f, e or g selects the format of strconv.FormatFloat. EncodeCanonical
+// uses the same per-field formats.
 //
 // Slice and Array values default to encoding as multiple URL values of the
 // same name.  Including the "comma" option signals that the field should be
 // encoded as a single comma-delimited value.  Including the "space" option
 // similarly encodes the value as a single space-delimited string. Including
 // the "semicolon" option will encode the value as a semicolon-delimited string.
-// Including the "brackets" option signals that the multiple URL values should
-// have "[]" appended to the value name. "numbered" will append a number to
-// the end of each incidence of the value name, example:
-// name0=value0&name1=value1, etc.
+// In those three formats a delimiter or backslash inside an element is
+// escaped with a backslash. Including the "brackets" option signals that the
+// multiple URL values should have "[]" appended to the value name. "numbered"
+// will append a number to the end of each incidence of the value name,
+// example: name0=value0&name1=value1, etc. "indexed" appends the index in
+// brackets instead: name[0]=value0&name[1]=value1. The decoder reads every
+// format back given the same option.
+// StringSet values are encoded like slices of their values.
 //
 // Anonymous struct fields are usually encoded as if their inner exported
 // fields were fields in the outer struct, subject to the standard Go
 // visibility rules.  An anonymous struct field with a name given in its URL
 // tag is treated as having that name, rather than being anonymous.
 //
+// Fields implementing Marshaler are encoded as the values it returns. Fields
+// implementing encoding.TextMarshaler, or fmt.Stringer when the
+// EncodeStringers option is given, are encoded as a single value holding
+// their text. Other values implementing fmt.Stringer, such as a named int,
+// are encoded with their String method unless the EncodeIgnoreStringers
+// option is given.
+//
+// A url.Values or map[string][]string field tagged with the "inline" option,
+// or named "*" as in `q:"*"`, has its entries encoded as parameters of their
+// own, unscoped, after every other field. Entries whose key another field
+// already encoded are skipped, or make Values fail with the
+// EncodeStrictInline option. The decoder fills such a field with the
+// parameters no other field reads.
+//
+// Fields tagged with the "secret" option, such as tokens, are encoded like
+// any other unless the EncodeOmitSecrets or EncodeRedactSecrets option is
+// given. The decoder redacts their values in its errors and warnings.
+//
 // Non-nil pointer values are encoded as the value pointed to.
 //
-// Nested structs are encoded including parent fields in value names for
-// scoping. e.g:
+// Nested structs and maps are encoded including parent fields in value names
+// for scoping. e.g:
 //
 // 	"user[name]=acme&user[addr][postcode]=1234&user[addr][city]=SFO"
 //
+// The EncodeKeyStyle option selects the dot ("user.addr.city=SFO") or flat
+// ("city=SFO") styles instead.
+//
 // All other values are encoded using their default string representation.
 //
 // Multiple fields that encode to the same URL parameter name will be included
 // as multiple URL values of the same name.
-func Values(v interface{}) (url.Values, error) {
+func Values(v interface{}, opts ...EncoderOption) (url.Values, error) {
+	return newEncoder(opts).values(v)
+}
+
+func (e *encoder) values(v interface{}) (url.Values, error) {
 	values := make(url.Values)
+	return values, e.encodeInto(values, v)
+}
+
+// encodeInto adds the encoding of v to dst.
+func (e *encoder) encodeInto(dst adder, v interface{}) error {
 	val := reflect.ValueOf(v)
 	for val.Kind() == reflect.Ptr {
 		if val.IsNil() {
-			return values, nil
+			return nil
 		}
 		val = val.Elem()
 	}
 
 	if v == nil {
-		return values, nil
+		return nil
 	}
 
 	if val.Kind() != reflect.Struct {
-		return nil, fmt.Errorf("query: Values() expects struct input. Got %v", val.Kind())
+		return fmt.Errorf("query: Values() expects struct input. Got %v", val.Kind())
+	}
+
+	def := indirect(e.defaults)
+	if def.IsValid() && def.Type() != val.Type() {
+		return fmt.Errorf("query: defaults of type %v for %v", def.Type(), val.Type())
 	}
+	if err := e.reflectValue(dst, val, def, ""); err != nil {
+		return err
+	}
+	return e.addInline(dst)
+}
 
-	err := reflectValue(values, val, "")
-	return values, err
+// addInline adds to dst the entries of the inline maps found while encoding,
+// skipping those whose key was already encoded from another field, or
+// failing on them with the EncodeStrictInline option.
+func (e *encoder) addInline(dst adder) error {
+	for _, m := range e.inline {
+		keys := make([]string, 0, m.Len())
+		for _, k := range m.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if dst.Has(k) {
+				if e.strictInline {
+					return fmt.Errorf("query: inline key %q conflicts with a field", k)
+				}
+				continue
+			}
+			vals := m.MapIndex(reflect.ValueOf(k).Convert(m.Type().Key()))
+			for i := 0; i < vals.Len(); i++ {
+				dst.Add(k, vals.Index(i).String())
+			}
+		}
+	}
+	return nil
+}
+
+// Marshal returns the URL query string encoding of v, built by Values with
+// its keys sorted. Fields tagged with the "flag" option are written as a bare
+// key.
+func Marshal(v interface{}, opts ...EncoderOption) (string, error) {
+	var b strings.Builder
+	if err := EncodeTo(&b, v, opts...); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// EncodeTo writes the URL query string encoding of v to w, byte for byte as
+// Marshal returns it, without building an intermediate url.Values or string.
+func EncodeTo(w io.Writer, v interface{}, opts ...EncoderOption) error {
+	e := newEncoder(opts)
+	var g groups
+	if err := e.encodeInto(&g, v); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	e.writeGroups(bw, &g, true)
+	return bw.Flush()
+}
+
+// writeGroups writes the pairs of g to w with their keys sorted, the keys of
+// fields tagged with the "flag" option bare when their value is empty. first
+// reports whether nothing was written to w before, so that no separator is
+// written ahead of the first pair.
+func (e *encoder) writeGroups(w *bufio.Writer, g *groups, first bool) {
+	sort.Slice(g.list, func(i, j int) bool { return g.list[i].key < g.list[j].key })
+	for _, kv := range g.list {
+		for _, v := range kv.values {
+			if !first {
+				w.WriteByte('&')
+			}
+			first = false
+			writeQueryEscape(w, kv.key)
+			if v != "" || !e.flags[kv.key] {
+				w.WriteByte('=')
+				writeQueryEscape(w, v)
+			}
+		}
+	}
+}
+
+// An adder collects encoded key/value pairs. url.Values is one.
+type adder interface {
+	Add(key, value string)
+	Has(key string) bool
+}
+
+// groups collects encoded values by key, keeping the order in which keys
+// are first added. It plays the role of url.Values for EncodeTo; the values
+// of a key are usually added together, so it checks the last key added before
+// looking the key up.
+type groups struct {
+	list  []group
+	index map[string]int
+}
+
+type group struct {
+	key    string
+	values []string
+}
+
+func (g *groups) Add(key, value string) {
+	if n := len(g.list); n > 0 && g.list[n-1].key == key {
+		g.list[n-1].values = append(g.list[n-1].values, value)
+		return
+	}
+
+	if g.index == nil {
+		g.index = make(map[string]int)
+	}
+	if i, ok := g.index[key]; ok {
+		g.list[i].values = append(g.list[i].values, value)
+		return
+	}
+	g.index[key] = len(g.list)
+	g.list = append(g.list, group{key, []string{value}})
+}
+
+func (g *groups) Has(key string) bool {
+	if n := len(g.list); n > 0 && g.list[n-1].key == key {
+		return true
+	}
+	_, ok := g.index[key]
+	return ok
+}
+
+// writeQueryEscape writes s to w escaped as url.QueryEscape does.
+func writeQueryEscape(w *bufio.Writer, s string) {
+	const hexUpper = "0123456789ABCDEF"
+
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			continue
+		}
+		w.WriteString(s[start:i])
+		if c == ' ' {
+			w.WriteByte('+')
+		} else {
+			w.WriteByte('%')
+			w.WriteByte(hexUpper[c>>4])
+			w.WriteByte(hexUpper[c&15])
+		}
+		start = i + 1
+	}
+	w.WriteString(s[start:])
+}
+
+// An EncoderOption configures how Values encodes a struct.
+type EncoderOption func(*encoder)
+
+// EncodeOmitEmpty makes Values skip every empty field, as if all of them
+// were tagged with the "omitempty" option. Fields tagged with "keepzero" are
+// still encoded.
+func EncodeOmitEmpty() EncoderOption {
+	return func(e *encoder) {
+		e.omitEmpty = true
+	}
+}
+
+// EncodeKeyStyle sets how the keys of nested structs and maps are written.
+// It defaults to BracketKeys; decode with the same style through
+// WithKeyStyle.
+func EncodeKeyStyle(s KeyStyle) EncoderOption {
+	return func(e *encoder) {
+		e.keyStyle = s
+	}
+}
+
+// EncodeRejectZeroTime makes Values fail on a zero time.Time field that is
+// not omitted, instead of encoding the zero time.
+func EncodeRejectZeroTime() EncoderOption {
+	return func(e *encoder) {
+		e.rejectZeroTime = true
+	}
+}
+
+// EncodeStringers makes Values encode fields implementing fmt.Stringer with
+// their String method, when they implement neither Marshaler nor
+// encoding.TextMarshaler, even structs, slices and maps, which are
+// otherwise encoded field by field or element by element.
+func EncodeStringers() EncoderOption {
+	return func(e *encoder) {
+		e.stringers = true
+	}
+}
+
+// EncodeIgnoreStringers makes Values encode values of basic kinds, such as
+// a named int, as their underlying value even when they implement
+// fmt.Stringer, so that a decoder can read them back: "1" rather than the
+// "high" of a level type. Tag options formatting numbers, such as "base" or
+// "prec", only apply to them with this option.
+func EncodeIgnoreStringers() EncoderOption {
+	return func(e *encoder) {
+		e.ignoreStringers = true
+	}
+}
+
+// EncodeAppend makes SetQuery and MergeQuery add the encoded values after
+// the existing values of the same keys, instead of replacing them.
+func EncodeAppend() EncoderOption {
+	return func(e *encoder) {
+		e.appendQuery = true
+	}
+}
+
+// EncodeStrictInline makes Values fail when an entry of an inline map has
+// the key of another field, instead of leaving that field's values alone.
+func EncodeStrictInline() EncoderOption {
+	return func(e *encoder) {
+		e.strictInline = true
+	}
+}
+
+// EncodeOmitSecrets makes Values skip the fields tagged with the "secret"
+// option, such as tokens, to build URLs meant for logs.
+func EncodeOmitSecrets() EncoderOption {
+	return func(e *encoder) {
+		e.omitSecrets = true
+	}
+}
+
+// EncodeRedactSecrets makes Values encode the fields tagged with the
+// "secret" option as a single "REDACTED" value, so that logs show they were
+// set without showing them.
+func EncodeRedactSecrets() EncoderOption {
+	return func(e *encoder) {
+		e.redactSecrets = true
+	}
+}
+
+// EncodeOmitDefaults makes Values skip the fields whose value deep-equals
+// that of the same field of proto, a struct of the type encoded or a pointer
+// to one, such as the defaults an API documents: with a proto holding
+// PerPage 25, "per_page=25" is left out of the URLs, which the API reads the
+// same without it. Nested structs are compared field by field, and left out
+// as a whole when equal to their default. Unlike omitempty, it leaves out
+// non-zero values, and encodes the zero values that differ from the
+// default.
+func EncodeOmitDefaults(proto interface{}) EncoderOption {
+	return func(e *encoder) {
+		e.defaults = reflect.ValueOf(proto)
+	}
+}
+
+// redacted replaces the values of secret fields with EncodeRedactSecrets.
+const redacted = "REDACTED"
+
+// Redacted returns the query string encoding v, as Values does, with the
+// fields tagged with the "secret" option redacted, for logging. It returns
+// the error message instead when v can't be encoded.
+//
+//	type Search struct {
+//		Query string `q:"q"`
+//		Token string `q:"token,secret"`
+//	}
+//	log.Printf("search %s", query.Redacted(s)) // search q=shoes&token=REDACTED
+func Redacted(v interface{}, opts ...EncoderOption) string {
+	vals, err := Values(v, append(opts[:len(opts):len(opts)], EncodeRedactSecrets())...)
+	if err != nil {
+		return err.Error()
+	}
+	return vals.Encode()
+}
+
+// encoder holds the options of a single Values call.
+type encoder struct {
+	omitEmpty       bool
+	keyStyle        KeyStyle
+	rejectZeroTime  bool
+	stringers       bool
+	ignoreStringers bool
+	appendQuery     bool
+	strictInline    bool
+	omitSecrets     bool
+	redactSecrets   bool
+
+	// defaults holds the prototype set by EncodeOmitDefaults.
+	defaults reflect.Value
+
+	// inline holds the maps of the fields tagged with the "inline" option.
+	inline []reflect.Value
+
+	// flags records the keys of the fields tagged with the "flag" option.
+	flags map[string]bool
+
+	// currencies records the currency written to each currency key shared by
+	// Money fields.
+	currencies map[string]string
+}
+
+func newEncoder(opts []EncoderOption) *encoder {
+	e := new(encoder)
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
 }
 
 // reflectValue populates the values parameter from the struct fields in val.
 // Embedded structs are followed recursively (using the rules defined in the
-// Values function documentation) breadth-first.
-func reflectValue(values url.Values, val reflect.Value, scope string) error {
-	var embedded []reflect.Value
+// Values function documentation) breadth-first. def holds the default of val
+// set by EncodeOmitDefaults, and is invalid when there is none.
+func (e *encoder) reflectValue(values adder, val, def reflect.Value, scope string) error {
+	var embedded, embeddedDefs []reflect.Value
 
 	typ := val.Type()
 	for i := 0; i < typ.NumField(); i++ {
@@ -148,26 +535,73 @@ func reflectValue(values url.Values, val reflect.Value, scope string) error {
 		}
 
 		sv := val.Field(i)
-		tag := sf.Tag.Get(tagKey)
+		var fd reflect.Value
+		if def.IsValid() {
+			fd = def.Field(i)
+		}
+		tag := sf.Tag.Get(TagKey)
 		if tag == "-" {
 			continue
 		}
 		name, opts := parseTag(tag)
+		if opts.Contains("inline") && sv.Kind() == reflect.Map {
+			// inline maps are added once every other field is encoded
+			e.inline = append(e.inline, sv)
+			continue
+		}
 		if name == "" {
 			if sf.Anonymous && sv.Kind() == reflect.Struct {
 				// save embedded struct for later processing
 				embedded = append(embedded, sv)
+				embeddedDefs = append(embeddedDefs, fd)
 				continue
 			}
 
 			name = sf.Name
 		}
 
-		if scope != "" {
-			name = scope + "[" + name + "]"
+		name = e.keyStyle.join(scope, name)
+
+		if fd.IsValid() && sv.CanInterface() && reflect.DeepEqual(sv.Interface(), fd.Interface()) {
+			continue
+		}
+
+		if sv.Type() == stringSetType {
+			sv = reflect.ValueOf(sv.Interface().(StringSet).Slice())
+		}
+
+		if e.omitSecrets && opts.Contains("secret") {
+			continue
+		}
+
+		omitEmpty := e.omitEmpty || opts.Contains("omitempty")
+		if omitEmpty && !opts.Contains("keepzero") && isEmptyValue(sv) && !keptEmpty(sv, opts) {
+			continue
+		}
+
+		if e.redactSecrets && opts.Contains("secret") {
+			values.Add(name, redacted)
+			continue
+		}
+
+		if _, err := durationUnit(opts); err != nil {
+			return err
+		}
+
+		if sv.Type() == timeRangeType {
+			e.timeRange(values, scope, name, sv.Interface().(TimeRange), opts)
+			continue
+		}
+
+		if sv.Type() == moneyType {
+			if err := e.money(values, scope, name, sv.Interface().(Money), opts); err != nil {
+				return err
+			}
+			continue
 		}
 
-		if opts.Contains("omitempty") && isEmptyValue(sv) {
+		if sv.Type() == flagType {
+			e.flag(values, name, sv.Interface().(Flag))
 			continue
 		}
 
@@ -177,21 +611,48 @@ func reflectValue(values url.Values, val reflect.Value, scope string) error {
 			}
 
 			m := sv.Interface().(Encoder)
-			if err := m.EncodeValues(name, &values); err != nil {
+			if err := encodeValues(values, name, m); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if opts.Contains("flag") && isBool(sv.Type()) {
+			if sv.Kind() == reflect.Ptr && !sv.IsNil() {
+				sv = sv.Elem()
+			}
+			if sv.Kind() == reflect.Bool && sv.Bool() {
+				if e.flags == nil {
+					e.flags = make(map[string]bool)
+				}
+				e.flags[name] = true
+				values.Add(name, "")
+			}
+			continue
+		}
+
+		if isBig(sv.Type()) {
+			values.Add(name, e.valueString(sv, opts))
+			continue
+		}
+
+		if ok, err := e.convert(values, name, sv); ok {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if ok, err := e.marshal(values, name, sv); ok {
+			if err != nil {
 				return err
 			}
 			continue
 		}
 
 		if sv.Kind() == reflect.Slice || sv.Kind() == reflect.Array {
-			var del byte
-			if opts.Contains("comma") {
-				del = ','
-			} else if opts.Contains("space") {
-				del = ' '
-			} else if opts.Contains("semicolon") {
-				del = ';'
-			} else if opts.Contains("brackets") {
+			del := opts.delimiter()
+			if del == 0 && opts.Contains("brackets") {
 				name = name + "[]"
 			}
 
@@ -204,7 +665,7 @@ func reflectValue(values url.Values, val reflect.Value, scope string) error {
 					} else {
 						s.WriteByte(del)
 					}
-					s.WriteString(valueString(sv.Index(i), opts))
+					s.WriteString(escapeElem(e.valueString(sv.Index(i), opts), del))
 				}
 				values.Add(name, s.String())
 			} else {
@@ -212,8 +673,10 @@ func reflectValue(values url.Values, val reflect.Value, scope string) error {
 					k := name
 					if opts.Contains("numbered") {
 						k = fmt.Sprintf("%s%d", name, i)
+					} else if opts.Contains("indexed") {
+						k = fmt.Sprintf("%s[%d]", name, i)
 					}
-					values.Add(k, valueString(sv.Index(i), opts))
+					values.Add(k, e.valueString(sv.Index(i), opts))
 				}
 			}
 			continue
@@ -227,20 +690,36 @@ func reflectValue(values url.Values, val reflect.Value, scope string) error {
 		}
 
 		if sv.Type() == timeType {
-			values.Add(name, valueString(sv, opts))
+			if e.rejectZeroTime && sv.Interface().(time.Time).IsZero() {
+				return fmt.Errorf("query: zero time for %s", name)
+			}
+			values.Add(name, e.valueString(sv, opts))
 			continue
 		}
 
 		if sv.Kind() == reflect.Struct {
-			reflectValue(values, sv, name)
+			if err := e.reflectValue(values, sv, indirect(fd), name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if sv.Kind() == reflect.Map {
+			if keptEmpty(sv, opts) {
+				values.Add(name, "")
+				continue
+			}
+			if err := e.reflectMap(values, sv, name, opts); err != nil {
+				return err
+			}
 			continue
 		}
 
-		values.Add(name, valueString(sv, opts))
+		values.Add(name, e.valueString(sv, opts))
 	}
 
-	for _, f := range embedded {
-		if err := reflectValue(values, f, scope); err != nil {
+	for i, f := range embedded {
+		if err := e.reflectValue(values, f, embeddedDefs[i], scope); err != nil {
 			return err
 		}
 	}
@@ -248,8 +727,201 @@ func reflectValue(values url.Values, val reflect.Value, scope string) error {
 	return nil
 }
 
+// encodeValues calls m.EncodeValues on values, through a temporary url.Values
+// if values is not one.
+func encodeValues(values adder, name string, m Encoder) error {
+	if v, ok := values.(url.Values); ok {
+		return m.EncodeValues(name, &v)
+	}
+
+	v := make(url.Values)
+	if err := m.EncodeValues(name, &v); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, val := range v[k] {
+			values.Add(k, val)
+		}
+	}
+	return nil
+}
+
+// marshal adds to values the encoding of sv under name if its type implements
+// Marshaler, encoding.TextMarshaler or, with the EncodeStringers option,
+// fmt.Stringer, checked in that order. Methods with pointer receivers are
+// found on non-addressable values too, and a nil pointer is encoded as an
+// empty value. It reports whether sv implements any of them. time.Time and
+// time.Duration are left to their own tag options.
+func (e *encoder) marshal(values adder, name string, sv reflect.Value) (bool, error) {
+	t := sv.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType || t == durationType {
+		return false, nil
+	}
+
+	var iface interface{}
+	for _, it := range []reflect.Type{marshalerType, textMarshalerType, stringerType} {
+		if it == stringerType && !e.stringers {
+			break
+		}
+		switch {
+		case sv.Kind() == reflect.Ptr && sv.IsNil():
+			if sv.Type().Implements(it) {
+				values.Add(name, "")
+				return true, nil
+			}
+		case sv.Type().Implements(it):
+			iface = sv.Interface()
+		case reflect.PtrTo(sv.Type()).Implements(it):
+			p := reflect.New(sv.Type())
+			p.Elem().Set(sv)
+			iface = p.Interface()
+		}
+		if iface != nil {
+			break
+		}
+	}
+
+	switch m := iface.(type) {
+	case Marshaler:
+		vals, err := m.MarshalQuery()
+		if err != nil {
+			return true, err
+		}
+		for _, v := range vals {
+			values.Add(name, v)
+		}
+	case encoding.TextMarshaler:
+		b, err := m.MarshalText()
+		if err != nil {
+			return true, err
+		}
+		values.Add(name, string(b))
+	case fmt.Stringer:
+		values.Add(name, m.String())
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// convert adds to values the encoding of sv under name if a Converter with
+// Encode is registered for its type, a nil pointer being encoded as an empty
+// value. It reports whether there is one.
+func (e *encoder) convert(values adder, name string, sv reflect.Value) (bool, error) {
+	t := sv.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	c := converter(t)
+	if c == nil || c.Encode == nil {
+		return false, nil
+	}
+	if sv.Kind() == reflect.Ptr {
+		if sv.IsNil() {
+			values.Add(name, "")
+			return true, nil
+		}
+		sv = sv.Elem()
+	}
+	vals, err := c.Encode(sv)
+	if err != nil {
+		return true, err
+	}
+	for _, v := range vals {
+		values.Add(name, v)
+	}
+	return true, nil
+}
+
+// reflectMap populates the values parameter from the entries of the map val,
+// scoping their keys by scope. Entries are read as the map is iterated, so
+// that keys never equal to themselves, such as a float NaN, are encoded too.
+func (e *encoder) reflectMap(values adder, val reflect.Value, scope string, opts tagOptions) error {
+	for it := val.MapRange(); it.Next(); {
+		name := e.keyStyle.joinMap(scope, fmt.Sprint(it.Key().Interface()))
+
+		sv := it.Value()
+		for sv.Kind() == reflect.Ptr || sv.Kind() == reflect.Interface {
+			if sv.IsNil() {
+				break
+			}
+			sv = sv.Elem()
+		}
+
+		switch {
+		case sv.Kind() == reflect.Struct && sv.Type() != timeType:
+			if err := e.reflectValue(values, sv, reflect.Value{}, name); err != nil {
+				return err
+			}
+		case sv.Kind() == reflect.Map:
+			if err := e.reflectMap(values, sv, name, opts); err != nil {
+				return err
+			}
+		case sv.Kind() == reflect.Slice || sv.Kind() == reflect.Array:
+			for i := 0; i < sv.Len(); i++ {
+				values.Add(name, e.valueString(sv.Index(i), opts))
+			}
+		default:
+			values.Add(name, e.valueString(sv, opts))
+		}
+	}
+	return nil
+}
+
+// keptEmpty reports whether sv is an empty map, but not a nil one, of a
+// field with the "keepempty" tag option, which is encoded as its key alone
+// with an empty value rather than left out.
+func keptEmpty(sv reflect.Value, opts tagOptions) bool {
+	return sv.Kind() == reflect.Map && !sv.IsNil() && sv.Len() == 0 && opts.Contains("keepempty")
+}
+
+// indirect returns v with its pointers followed, or an invalid value when v
+// is invalid or one of them is nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// isBool reports whether t is a bool or a pointer to one.
+func isBool(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Bool
+}
+
+// escapeElem escapes backslashes and del in an element of a delimited list,
+// so the list can be split back by the decoder.
+func escapeElem(s string, del byte) string {
+	if strings.IndexByte(s, del) < 0 && strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == del || s[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
 // valueString returns the string representation of a value.
-func valueString(v reflect.Value, opts tagOptions) string {
+func (e *encoder) valueString(v reflect.Value, opts tagOptions) string {
 	for v.Kind() == reflect.Ptr {
 		if v.IsNil() {
 			return ""
@@ -265,16 +937,65 @@ func valueString(v reflect.Value, opts tagOptions) string {
 	}
 
 	if v.Type() == timeType {
-		t := v.Interface().(time.Time)
-		if opts.Contains("unix") {
-			return strconv.FormatInt(t.Unix(), 10)
+		return formatTime(v.Interface().(time.Time), opts)
+	}
+
+	if v.Type() == durationType {
+		if s, err := formatDuration(v.Interface().(time.Duration), opts); err == nil {
+			return s
 		}
-		return t.Format(time.RFC3339)
+	}
+
+	if isBig(v.Type()) {
+		return bigString(v)
+	}
+
+	// A String method formats the value, as fmt.Sprint does, unless the
+	// EncodeIgnoreStringers option is given.
+	if !e.ignoreStringers && v.Type().Implements(stringerType) {
+		return v.Interface().(fmt.Stringer).String()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if places, ok := scale(opts); ok {
+			return unshiftDecimal(strconv.FormatInt(v.Int(), 10), places)
+		}
+		return formatInt(v.Int(), opts)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if places, ok := scale(opts); ok {
+			return unshiftDecimal(strconv.FormatUint(v.Uint(), 10), places)
+		}
+		return formatUint(v.Uint(), opts)
+	case reflect.Float32, reflect.Float64:
+		f, prec := floatFormat(opts)
+		return strconv.FormatFloat(v.Float(), f, prec, v.Type().Bits())
 	}
 
 	return fmt.Sprint(v.Interface())
 }
 
+// floatFormat returns the format and precision that strconv.FormatFloat
+// writes floats with, given by the "fmt=f|e|g" and "prec=n" tag options: the
+// shortest 'g' form by default, and the 'f' form when only the precision is
+// given, as in "rate=0.10" for prec=2.
+func floatFormat(opts tagOptions) (byte, int) {
+	f, prec := byte('g'), -1
+	if p, ok := opts.Value("prec"); ok {
+		if n, err := strconv.Atoi(p); err == nil && n >= 0 {
+			f, prec = 'f', n
+		}
+	}
+	if s, ok := opts.Value("fmt"); ok && (s == "f" || s == "e" || s == "g") {
+		f = s[0]
+	}
+	return f, prec
+}
+
 // isEmptyValue checks if a value should be considered empty for the purposes
 // of omitting fields with the "omitempty" option.
 func isEmptyValue(v reflect.Value) bool {
@@ -296,6 +1017,15 @@ func isEmptyValue(v reflect.Value) bool {
 	if v.Type() == timeType {
 		return v.Interface().(time.Time).IsZero()
 	}
+	if v.Type() == dateType {
+		return v.Interface().(Date).IsZero()
+	}
+	if v.Type() == timeRangeType {
+		return v.Interface().(TimeRange).IsZero()
+	}
+	if v.Type() == moneyType {
+		return v.Interface().(Money).IsZero()
+	}
 
 	return false
 }
@@ -305,12 +1035,44 @@ func isEmptyValue(v reflect.Value) bool {
 type tagOptions []string
 
 // parseTag splits a struct field's url tag into its name and comma-separated
-// options.
+// options. The name "*" stands for the "inline" option, and a name with
+// escaped dots, such as "utm\.source", for the "literal" option.
 func parseTag(tag string) (string, tagOptions) {
 	s := strings.Split(tag, ",")
+	if s[0] == "*" {
+		return "", append(tagOptions(s[1:len(s):len(s)]), "inline")
+	}
+	if name, escaped := tagspec.Unescape(s[0]); escaped {
+		return name, append(tagOptions(s[1:len(s):len(s)]), "literal")
+	}
 	return s[0], s[1:]
 }
 
+// delimiter returns the byte separating the elements of a slice encoded as a
+// single value, or 0 if its elements are encoded as separate values.
+func (o tagOptions) delimiter() byte {
+	switch {
+	case o.Contains("comma"):
+		return ','
+	case o.Contains("space"):
+		return ' '
+	case o.Contains("semicolon"):
+		return ';'
+	}
+	return 0
+}
+
+// Value returns the value of the option given as "name=value", and whether
+// it is present.
+func (o tagOptions) Value(name string) (string, bool) {
+	for _, s := range o {
+		if strings.HasPrefix(s, name+"=") {
+			return s[len(name)+1:], true
+		}
+	}
+	return "", false
+}
+
 // Contains checks whether the tagOptions contains the specified option.
 func (o tagOptions) Contains(option string) bool {
 	for _, s := range o {
@@ -5,9 +5,12 @@
 package query
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -81,6 +84,8 @@ func TestValues_types(t *testing.T) {
 				I []string  `q:",brackets"`
 				J []string  `q:",semicolon"`
 				K []string  `q:",numbered"`
+				L []string  `q:",indexed"`
+				M []string  `q:",comma"`
 			}{
 				A: []string{"a", "b"},
 				B: []string{"a", "b"},
@@ -93,20 +98,25 @@ func TestValues_types(t *testing.T) {
 				I: []string{"a", "b"},
 				J: []string{"a", "b"},
 				K: []string{"a", "b"},
+				L: []string{"a", "b"},
+				M: []string{"a,b", `c\d`},
 			},
 			url.Values{
-				"A":   {"a", "b"},
-				"B":   {"a,b"},
-				"C":   {"a b"},
-				"D":   {"a", "b"},
-				"E":   {"a,b"},
-				"F":   {"a b"},
-				"G":   {"string string"},
-				"H":   {"1 0"},
-				"I[]": {"a", "b"},
-				"J":   {"a;b"},
-				"K0":  {"a"},
-				"K1":  {"b"},
+				"A":    {"a", "b"},
+				"B":    {"a,b"},
+				"C":    {"a b"},
+				"D":    {"a", "b"},
+				"E":    {"a,b"},
+				"F":    {"a b"},
+				"G":    {"string string"},
+				"H":    {"1 0"},
+				"I[]":  {"a", "b"},
+				"J":    {"a;b"},
+				"K0":   {"a"},
+				"K1":   {"b"},
+				"L[0]": {"a"},
+				"L[1]": {"b"},
+				"M":    {`a\,b,c\\d`},
 			},
 		},
 		{
@@ -178,6 +188,45 @@ func TestValues_types(t *testing.T) {
 	}
 }
 
+func TestValues_keyStyles(t *testing.T) {
+	in := struct {
+		Nest Nested            `q:"nest"`
+		Map  map[string]string `q:"map"`
+	}{
+		Nest: Nested{A: SubNested{Value: "that"}},
+		Map:  map[string]string{"k": "v"},
+	}
+
+	tests := []struct {
+		style KeyStyle
+		want  url.Values
+	}{
+		{
+			BracketKeys,
+			url.Values{"nest[a][value]": {"that"}, "nest[b]": {""}, "map[k]": {"v"}},
+		},
+		{
+			DotKeys,
+			url.Values{"nest.a.value": {"that"}, "nest.b": {""}, "map.k": {"v"}},
+		},
+		{
+			FlatKeys,
+			url.Values{"value": {"that"}, "b": {""}, "map[k]": {"v"}},
+		},
+	}
+
+	for i, tt := range tests {
+		v, err := Values(in, EncodeKeyStyle(tt.style))
+		if err != nil {
+			t.Errorf("%d. Values(%v) returned error: %v", i, in, err)
+		}
+
+		if !reflect.DeepEqual(tt.want, v) {
+			t.Errorf("%d. Values(%v) returned %v, want %v", i, in, v, tt.want)
+		}
+	}
+}
+
 func TestValues_omitEmpty(t *testing.T) {
 	str := ""
 	s := struct {
@@ -191,7 +240,7 @@ func TestValues_omitEmpty(t *testing.T) {
 
 	v, err := Values(s)
 	if err != nil {
-		t.Errorf("Values(%q) returned error: %v", s, err)
+		t.Errorf("Values(%v) returned error: %v", s, err)
 	}
 
 	want := url.Values{
@@ -200,7 +249,66 @@ func TestValues_omitEmpty(t *testing.T) {
 		"E":         {""}, // E is included because the pointer is not empty, even though the string being pointed to is
 	}
 	if !reflect.DeepEqual(want, v) {
-		t.Errorf("Values(%q) returned %v, want %v", s, v, want)
+		t.Errorf("Values(%v) returned %v, want %v", s, v, want)
+	}
+}
+
+func TestValues_omitEmptyOption(t *testing.T) {
+	zero, text := 0, "text"
+	s := struct {
+		A string
+		B int
+		C *int
+		D *int
+		E []string
+		F map[string]string
+		G bool     `q:",keepzero"`
+		H string   `q:",omitempty,keepzero"`
+		I *string  `q:",omitempty"`
+		J []string `q:",keepzero"`
+	}{C: &zero, I: &text}
+
+	v, err := Values(s, EncodeOmitEmpty())
+	if err != nil {
+		t.Errorf("Values(%v) returned error: %v", s, err)
+	}
+
+	want := url.Values{
+		"C": {"0"}, // C is included because a pointer to a zero value is not empty
+		"G": {"false"},
+		"H": {""},
+		"I": {"text"},
+	}
+	if !reflect.DeepEqual(want, v) {
+		t.Errorf("Values(%v) returned %v, want %v", s, v, want)
+	}
+}
+
+func TestValues_keepEmptyMap(t *testing.T) {
+	type patch struct {
+		Tags   map[string]string `q:"tags,omitempty,keepempty"`
+		Labels map[string]string `q:"labels,keepempty"`
+		Meta   map[string]string `q:"meta,omitempty"`
+	}
+	for _, test := range []struct {
+		v   patch
+		exp string
+	}{
+		{patch{}, ""},
+		{patch{Tags: map[string]string{}, Labels: map[string]string{}, Meta: map[string]string{}}, "labels=&tags="},
+		{patch{Tags: map[string]string{"a": "1"}}, "tags%5Ba%5D=1"},
+	} {
+		got, err := Marshal(test.v)
+		ok(t, err)
+		if got != test.exp {
+			t.Fatalf("exp: %v\ngot: %v", test.exp, got)
+		}
+
+		var back patch
+		ok(t, NewDecoder(got).Decode(&back))
+		if back.Tags == nil != (test.v.Tags == nil) || len(back.Tags) != len(test.v.Tags) {
+			t.Fatalf("exp: %#v\ngot: %#v", test.v.Tags, back.Tags)
+		}
 	}
 }
 
@@ -279,7 +387,7 @@ func TestValues_Marshaler(t *testing.T) {
 	}{[]string{"a", "b", "c"}}
 	v, err := Values(s)
 	if err != nil {
-		t.Errorf("Values(%q) returned error: %v", s, err)
+		t.Errorf("Values(%v) returned error: %v", s, err)
 	}
 
 	want := url.Values{
@@ -288,7 +396,7 @@ func TestValues_Marshaler(t *testing.T) {
 		"arg.2": {"c"},
 	}
 	if !reflect.DeepEqual(want, v) {
-		t.Errorf("Values(%q) returned %v, want %v", s, v, want)
+		t.Errorf("Values(%v) returned %v, want %v", s, v, want)
 	}
 }
 
@@ -298,12 +406,237 @@ func TestValues_MarshalerWithNilPointer(t *testing.T) {
 	}{}
 	v, err := Values(s)
 	if err != nil {
-		t.Errorf("Values(%q) returned error: %v", s, err)
+		t.Errorf("Values(%v) returned error: %v", s, err)
 	}
 
 	want := url.Values{}
 	if !reflect.DeepEqual(want, v) {
-		t.Errorf("Values(%q) returned %v, want %v", s, v, want)
+		t.Errorf("Values(%v) returned %v, want %v", s, v, want)
+	}
+}
+
+type queryList []string
+
+func (l queryList) MarshalQuery() ([]string, error) {
+	return append([]string{"list"}, l...), nil
+}
+
+func (l *queryList) UnmarshalQuery(vals []string) error {
+	*l = vals[1:]
+	return nil
+}
+
+type textPoint struct{ X, Y int }
+
+func (p *textPoint) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%d:%d", p.X, p.Y)), nil
+}
+
+func (p *textPoint) UnmarshalText(b []byte) error {
+	_, err := fmt.Sscanf(string(b), "%d:%d", &p.X, &p.Y)
+	return err
+}
+
+type level int
+
+func (l level) String() string {
+	return [...]string{"low", "high"}[l]
+}
+
+func TestValues_Marshalers(t *testing.T) {
+	s := struct {
+		List   queryList  `q:"list"`
+		Point  textPoint  `q:"point"`
+		PtrNil *textPoint `q:"nil"`
+		Level  level      `q:"level"`
+	}{
+		List:  queryList{"a", "b"},
+		Point: textPoint{1, 2},
+		Level: 1,
+	}
+
+	for _, tt := range []struct {
+		opts []EncoderOption
+		want url.Values
+	}{
+		{
+			nil,
+			url.Values{"list": {"list", "a", "b"}, "point": {"1:2"}, "nil": {""}, "level": {"high"}},
+		},
+		{
+			[]EncoderOption{EncodeStringers()},
+			url.Values{"list": {"list", "a", "b"}, "point": {"1:2"}, "nil": {""}, "level": {"high"}},
+		},
+		{
+			[]EncoderOption{EncodeIgnoreStringers()},
+			url.Values{"list": {"list", "a", "b"}, "point": {"1:2"}, "nil": {""}, "level": {"1"}},
+		},
+	} {
+		v, err := Values(s, tt.opts...)
+		if err != nil {
+			t.Errorf("Values(%v) returned error: %v", s, err)
+		}
+		if !reflect.DeepEqual(tt.want, v) {
+			t.Errorf("Values(%v) returned %v, want %v", s, v, tt.want)
+		}
+	}
+
+	var got struct {
+		List  queryList  `q:"list"`
+		Point *textPoint `q:"point"`
+	}
+	enc, err := Marshal(s)
+	if err != nil {
+		t.Fatalf("Marshal(%v) returned error: %v", s, err)
+	}
+	if err := NewDecoder(enc).Decode(&got); err != nil {
+		t.Fatalf("Decode(%s) returned error: %v", enc, err)
+	}
+	if !reflect.DeepEqual(s.List, got.List) || *got.Point != s.Point {
+		t.Errorf("Decode(%s) returned %v, want %v", enc, got, s)
+	}
+}
+
+func TestMarshal_flags(t *testing.T) {
+	yes, no := true, false
+	type flags struct {
+		Verbose bool  `q:"verbose,flag"`
+		Quiet   bool  `q:"quiet,flag"`
+		Debug   *bool `q:"debug,flag"`
+		Trace   *bool `q:"trace,flag"`
+		Dry     bool  `q:"dry"`
+	}
+	in := flags{Verbose: true, Debug: &yes, Trace: &no, Dry: true}
+
+	v, err := Values(in)
+	if err != nil {
+		t.Fatalf("Values(%v) returned error: %v", in, err)
+	}
+	want := url.Values{"verbose": {""}, "debug": {""}, "dry": {"true"}}
+	if !reflect.DeepEqual(want, v) {
+		t.Errorf("Values(%v) returned %v, want %v", in, v, want)
+	}
+
+	s, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal(%v) returned error: %v", in, err)
+	}
+	if exp := "debug&dry=true&verbose"; s != exp {
+		t.Errorf("Marshal(%v) returned %q, want %q", in, s, exp)
+	}
+
+	var got flags
+	if err := NewDecoder(s).Decode(&got); err != nil {
+		t.Fatalf("Decode(%s) returned error: %v", s, err)
+	}
+	if !got.Verbose || got.Quiet || got.Debug == nil || !*got.Debug || got.Trace != nil || !got.Dry {
+		t.Errorf("Decode(%s) returned %+v, want %+v", s, got, in)
+	}
+}
+
+func TestEncodeTo(t *testing.T) {
+	str := "a b&c=d/ñ"
+	for i, in := range []interface{}{
+		struct {
+			A string
+			B *string
+			C []int `q:"c,comma"`
+			D []int `q:"d,brackets"`
+			E map[string]string
+			F EncodedArgs
+			G Nested `q:"g"`
+			H int
+		}{
+			A: str,
+			B: &str,
+			C: []int{1, 2},
+			D: []int{3, 4},
+			E: map[string]string{"x": "1", "y": "2"},
+			F: EncodedArgs{"a", "b"},
+			H: 5,
+		},
+		D{B: B{C: "bar"}, C: "foo"},
+		nil,
+	} {
+		v, err := Values(in)
+		if err != nil {
+			t.Fatalf("%d. Values(%v) returned error: %v", i, in, err)
+		}
+		want := v.Encode()
+
+		var b bytes.Buffer
+		if err := EncodeTo(&b, in); err != nil {
+			t.Fatalf("%d. EncodeTo(%v) returned error: %v", i, in, err)
+		}
+		if got := b.String(); got != want {
+			t.Errorf("%d. EncodeTo(%v) wrote %q, want %q", i, in, got, want)
+		}
+	}
+}
+
+func TestValues_inline(t *testing.T) {
+	type search struct {
+		Page  int        `q:"page"`
+		Extra url.Values `q:",inline"`
+	}
+	in := search{Page: 2, Extra: url.Values{"page": {"9"}, "utm_source": {"mail"}, "tag": {"a", "b"}}}
+
+	v, err := Values(in)
+	if err != nil {
+		t.Fatalf("Values(%v) returned error: %v", in, err)
+	}
+	want := url.Values{"page": {"2"}, "utm_source": {"mail"}, "tag": {"a", "b"}}
+	if !reflect.DeepEqual(want, v) {
+		t.Errorf("Values(%v) returned %v, want %v", in, v, want)
+	}
+
+	s, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal(%v) returned error: %v", in, err)
+	}
+	if exp := "page=2&tag=a&tag=b&utm_source=mail"; s != exp {
+		t.Errorf("Marshal(%v) returned %q, want %q", in, s, exp)
+	}
+
+	if _, err := Values(in, EncodeStrictInline()); err == nil {
+		t.Errorf("Values(%v, EncodeStrictInline()) returned no error", in)
+	}
+}
+
+type fanOut struct {
+	IDs    []int    `q:"id"`
+	Status []string `q:"status,comma"`
+	Page   int      `q:"page"`
+}
+
+func newFanOut(n int) fanOut {
+	f := fanOut{Page: 1}
+	for i := 0; i < n; i++ {
+		f.IDs = append(f.IDs, 100000+i)
+		f.Status = append(f.Status, "open")
+	}
+	return f
+}
+
+func BenchmarkValuesEncode(b *testing.B) {
+	in := newFanOut(5000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		v, err := Values(in)
+		if err != nil {
+			b.Fatal(err)
+		}
+		io.WriteString(io.Discard, v.Encode())
+	}
+}
+
+func BenchmarkEncodeTo(b *testing.B) {
+	in := newFanOut(5000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := EncodeTo(io.Discard, in); err != nil {
+			b.Fatal(err)
+		}
 	}
 }
 
@@ -326,3 +659,161 @@ func TestTagParsing(t *testing.T) {
 		}
 	}
 }
+
+func TestValues_secret(t *testing.T) {
+	type search struct {
+		Query string   `q:"q"`
+		Token string   `q:"token,secret"`
+		Docs  []string `q:"doc,secret,omitempty"`
+	}
+	s := search{Query: "shoes", Token: "abc123"}
+
+	got, err := Values(s)
+	ok(t, err)
+	if exp := "q=shoes&token=abc123"; got.Encode() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, got.Encode())
+	}
+	got, err = Values(s, EncodeOmitSecrets())
+	ok(t, err)
+	if exp := "q=shoes"; got.Encode() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, got.Encode())
+	}
+	if exp, got := "q=shoes&token=REDACTED", Redacted(s); exp != got {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+	if exp, got := "query: Values() expects struct input. Got int", Redacted(2); exp != got {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+}
+
+func TestValues_scale(t *testing.T) {
+	type fx struct {
+		Rate   int64   `q:"rate,scale=4"`
+		Spread uint32  `q:"spread,scale=2"`
+		Rates  []int64 `q:"rates,comma,scale=3"`
+	}
+	got, err := Values(fx{Rate: -15, Spread: 1200, Rates: []int64{1001, 0}})
+	ok(t, err)
+	if exp := "rate=-0.0015&rates=1.001%2C0.000&spread=12.00"; got.Encode() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, got.Encode())
+	}
+}
+
+func TestValues_omitDefaults(t *testing.T) {
+	type Paging struct {
+		PerPage int `q:"per_page"`
+		Page    int `q:"page"`
+	}
+	type search struct {
+		Paging
+		Query  string   `q:"q"`
+		Sort   string   `q:"sort"`
+		Fields []string `q:"field"`
+		Filter *struct {
+			Status string `q:"status"`
+		} `q:"filter"`
+	}
+	proto := search{Paging: Paging{PerPage: 25, Page: 1}, Sort: "relevance", Fields: []string{"id"}}
+
+	for _, test := range []struct {
+		v   search
+		exp string
+	}{
+		{proto, ""},
+		{search{Paging: Paging{PerPage: 25, Page: 2}, Query: "shoes", Sort: "relevance", Fields: []string{"id", "name"}}, "field=id&field=name&page=2&q=shoes"},
+		{search{}, "page=0&per_page=0&sort="},
+	} {
+		got, err := Marshal(&test.v, EncodeOmitDefaults(&proto))
+		ok(t, err)
+		if got != test.exp {
+			t.Fatalf("exp: %v\ngot: %v", test.exp, got)
+		}
+	}
+
+	_, err := Values(proto, EncodeOmitDefaults(Paging{}))
+	if err == nil {
+		t.Fatalf("exp: %v\ngot: %v", "defaults type error", err)
+	}
+}
+
+func TestValues_floatFormat(t *testing.T) {
+	type quote struct {
+		Rate   float64   `q:"rate,prec=2"`
+		Ratio  float32   `q:"ratio,fmt=e,prec=3"`
+		Amount *float64  `q:"amount,fmt=f"`
+		Levels []float64 `q:"level,comma,prec=1"`
+		Plain  float64   `q:"plain"`
+	}
+	amount := 1e21
+	v := quote{Rate: 0.1, Ratio: 1234.5, Amount: &amount, Levels: []float64{1, 2.25}, Plain: 0.1}
+	got, err := EncodeCanonical(v)
+	ok(t, err)
+	if exp := "amount=1000000000000000000000&level=1.0%2C2.2&plain=0.1&rate=0.10&ratio=1.234e%2B03"; got != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+
+	var back quote
+	ok(t, NewDecoder(got).Decode(&back))
+	again, err := EncodeCanonical(back)
+	ok(t, err)
+	if again != got {
+		t.Fatalf("exp: %v\ngot: %v", got, again)
+	}
+
+	err = CheckType(struct {
+		N int     `q:"n,prec=2"`
+		F float64 `q:"f,fmt=x"`
+	}{})
+	if err == nil || !strings.Contains(err.Error(), "N: prec only applies to floats; F: fmt \"x\" is not one of f, e and g") {
+		t.Fatalf("exp: %v\ngot: %v", "prec and fmt problems", err)
+	}
+}
+
+func TestValues_intBase(t *testing.T) {
+	type device struct {
+		Mask   uint16  `q:"mask,base=16"`
+		Branch int     `q:"branch,pad=3"`
+		Offset int8    `q:"offset,base=16,pad=4"`
+		Bits   []uint8 `q:"bits,comma,base=2,pad=8"`
+	}
+	v := device{Mask: 31, Branch: -7, Offset: -128, Bits: []uint8{5, 255}}
+	got, err := Values(v)
+	ok(t, err)
+	if exp := "bits=00000101%2C11111111&branch=-007&mask=1F&offset=-0080"; got.Encode() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, got.Encode())
+	}
+
+	var back device
+	ok(t, NewDecoder(got.Encode()).Decode(&back))
+	if !reflect.DeepEqual(v, back) {
+		t.Fatalf("exp: %+v\ngot: %+v", v, back)
+	}
+	ok(t, NewDecoder("mask=ff&branch=12").Decode(&back))
+	if back.Mask != 255 || back.Branch != 12 {
+		t.Fatalf("exp: %v\ngot: %+v", "255 12", back)
+	}
+
+	for _, test := range []struct {
+		query, exp string
+	}{
+		{"mask=-1F", "value must be a non-negative integer"},
+		{"mask=10000", "value exceeds maximum FFFF for this parameter"},
+		{"offset=-81", "value is below minimum -0080 for this parameter"},
+		{"mask=1G", "invalid syntax"},
+	} {
+		err := NewDecoder(test.query).Decode(&back)
+		if err == nil || !strings.HasSuffix(err.Error(), test.exp) {
+			t.Fatalf("%s\nexp: %v\ngot: %v", test.query, test.exp, err)
+		}
+	}
+
+	err = CheckType(struct {
+		A int     `q:"a,base=37"`
+		B float64 `q:"b,pad=2"`
+		C int     `q:"c,base=16,scale=2"`
+	}{})
+	exp := `A: base "37" is not a number between 2 and 36; B: pad only applies to integers; C: base cannot be combined with scale`
+	if err == nil || !strings.HasSuffix(err.Error(), exp) {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+}
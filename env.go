@@ -0,0 +1,52 @@
+package query
+
+import (
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// DecodeEnv decodes environment variables into the value pointed by v, as
+// Decode does for a query string. The key of each field is mapped to the
+// variable named by prefix and the key in upper case, joined by "_", with
+// any other character than letters and digits replaced by "_": with prefix
+// "JOBS", `q:"per_page"` is read from JOBS_PER_PAGE, and the field
+// `q:"page"` of a nested struct `q:"range"` from JOBS_RANGE_PAGE.
+//
+// Slice fields without a delimited format read comma-separated values. Map
+// fields are not read from the environment.
+func DecodeEnv(prefix string, v interface{}, opts ...Option) error {
+	d := NewDecoder("", opts...)
+	d.keyStyle = DotKeys
+	d.splitLists = true
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+
+	src := make(url.Values)
+	for _, key := range d.fieldKeys(nil, rv.Elem().Type(), "", make(map[reflect.Type]bool)) {
+		if val, ok := os.LookupEnv(envName(prefix, key)); ok {
+			src[key] = []string{val}
+		}
+	}
+	return d.unmarshal(src, v)
+}
+
+// envName returns the name of the environment variable holding key.
+func envName(prefix, key string) string {
+	if prefix != "" {
+		key = prefix + "_" + key
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z':
+			return r - 'a' + 'A'
+		case 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+}
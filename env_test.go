@@ -0,0 +1,40 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeEnv(t *testing.T) {
+	t.Setenv("JOBS_PER_PAGE", "20")
+	t.Setenv("JOBS_STATUS", "open,closed")
+	t.Setenv("JOBS_RANGE_PAGE", "3")
+	t.Setenv("PER_PAGE", "99")
+
+	type options struct {
+		PerPage int         `q:"per_page,default=10"`
+		Sort    string      `q:"sort,default=name"`
+		Status  []string    `q:"status"`
+		Range   *pagination `q:"range"`
+		Owner   string      `q:"owner"`
+	}
+
+	var got options
+	ok(t, DecodeEnv("JOBS", &got))
+	exp := options{
+		PerPage: 20,
+		Sort:    "name",
+		Status:  []string{"open", "closed"},
+		Range:   &pagination{Page: 3},
+	}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	t.Setenv("JOBS_PER_PAGE", "many")
+	err := DecodeEnv("jobs", &got)
+	exp2 := map[string]string{"per_page": "must be an integer"}
+	if fe, isField := err.(FieldError); !isField || !reflect.DeepEqual(exp2, fe.Fields()) {
+		t.Fatalf("exp: %v\ngot: %v", exp2, err)
+	}
+}
@@ -0,0 +1,174 @@
+package query
+
+import (
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// exampleTime is the time written in example queries.
+var exampleTime = time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)
+
+// Example returns an example query string for the struct v, or pointed by v.
+// The fields of v that are set keep their values, and the others are given
+// the value of their "default" tag option, the first value of their "enum"
+// tag option, or a placeholder of their type within their "min" and "max"
+// limits. Slices and maps get a single element, and nested structs are filled
+// the same way. The query decodes back into a zero value of the type of v.
+func Example(v interface{}, opts ...EncoderOption) (string, error) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return "", &UnsupportedTypeError{Type: reflect.TypeOf(v)}
+	}
+
+	rv := reflect.New(t).Elem()
+	if val := reflect.Indirect(reflect.ValueOf(v)); val.IsValid() {
+		rv.Set(val)
+	}
+	if err := fillExample(rv, make(map[reflect.Type]bool)); err != nil {
+		return "", err
+	}
+	return Marshal(rv.Interface(), opts...)
+}
+
+// fillExample sets the unset fields of the struct rv to example values.
+// visiting holds the structs being filled, to stop at recursive types.
+func fillExample(rv reflect.Value, visiting map[reflect.Type]bool) error {
+	t := rv.Type()
+	if visiting[t] {
+		return nil
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	var d Decoder
+	for i := 0; i < t.NumField(); i++ {
+		sf, fv := t.Field(i), rv.Field(i)
+		_, opts, ok := d.fieldKey(sf, "")
+		if !ok || opts.Contains("inline") || sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch {
+		case ft.Kind() == reflect.Struct && isNested(ft):
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					if visiting[ft] {
+						continue
+					}
+					fv.Set(reflect.New(ft))
+				}
+				fv = fv.Elem()
+			}
+			if err := fillExample(fv, visiting); err != nil {
+				return err
+			}
+		case ft.Kind() == reflect.Map:
+			if !fv.IsNil() || !mapKeyKind(ft.Key().Kind()) {
+				continue
+			}
+			if err := exampleMap(fv); err != nil {
+				return withField(err, sf.Name, "")
+			}
+		case fv.IsZero():
+			if err := exampleValue(fv, opts); err != nil {
+				return withField(err, sf.Name, "")
+			}
+		}
+	}
+	return nil
+}
+
+// exampleMap stores in the map fv a single entry holding an example value,
+// or an example map for maps of maps.
+func exampleMap(fv reflect.Value) error {
+	t := fv.Type()
+	elem := reflect.New(t.Elem()).Elem()
+	var err error
+	if t.Elem().Kind() == reflect.Map && mapKeyKind(t.Elem().Key().Kind()) {
+		err = exampleMap(elem)
+	} else {
+		err = exampleValue(elem, nil)
+	}
+	if err != nil {
+		return err
+	}
+	key := reflect.New(t.Key())
+	if t.Key().Kind() == reflect.String {
+		key.Elem().SetString("key")
+	} else if err := value("1", key, nil, nil); err != nil {
+		return err
+	}
+	fv.Set(reflect.MakeMap(t))
+	fv.SetMapIndex(key.Elem(), elem)
+	return nil
+}
+
+// exampleValue stores in fv, which must be addressable, the example value of
+// a field with the tag options opts.
+func exampleValue(fv reflect.Value, opts tagOptions) error {
+	if def, ok := opts.Value("default"); ok {
+		return new(Decoder).field(defaultValues(def, fv.Type(), opts), fv, opts)
+	}
+	if enum, ok := opts.Value("enum"); ok {
+		return new(Decoder).field(enumValues(enum)[:1], fv, opts)
+	}
+
+	t := fv.Type()
+	for t.Kind() == reflect.Ptr || (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && !unmarshaler(t) {
+		t = t.Elem()
+	}
+	if unmarshaler(t) && t != timeType {
+		return nil
+	}
+
+	var val string
+	switch {
+	case t == timeType:
+		val = formatTime(exampleTime, opts)
+	case t == durationType:
+		s, err := formatDuration(time.Minute, opts)
+		if err != nil {
+			return err
+		}
+		val = s
+	default:
+		switch t.Kind() {
+		case reflect.String:
+			val = "example"
+		case reflect.Bool:
+			val = "true"
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			val = strconv.FormatFloat(exampleNumber(opts, 1), 'f', 0, 64)
+		case reflect.Float32, reflect.Float64:
+			val = strconv.FormatFloat(exampleNumber(opts, 1.5), 'f', -1, 64)
+		default:
+			return &UnsupportedTypeError{Type: t}
+		}
+	}
+	return new(Decoder).field([]string{val}, fv, opts)
+}
+
+// exampleNumber returns n, or the closest limit of the "min" and "max" tag
+// options when n is out of them.
+func exampleNumber(opts tagOptions, n float64) float64 {
+	if min, ok := opts.Value("min"); ok {
+		if lim, err := strconv.ParseFloat(min, 64); err == nil && n < lim {
+			n = lim
+		}
+	}
+	if max, ok := opts.Value("max"); ok {
+		if lim, err := strconv.ParseFloat(max, 64); err == nil && n > lim {
+			n = lim
+		}
+	}
+	return n
+}
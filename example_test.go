@@ -0,0 +1,42 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExample(t *testing.T) {
+	type params struct {
+		Query   string            `q:"q"`
+		Sort    string            `q:"sort,enum=asc|desc"`
+		Limit   int               `q:"limit,min=10,max=100,default=50"`
+		Offset  uint              `q:"offset,max=0"`
+		Ratio   float64           `q:"ratio"`
+		Status  []string          `q:"status,comma,enum=open|closed"`
+		Since   *time.Time        `q:"since,layout=2006-01-02"`
+		Timeout time.Duration     `q:"timeout,unit=s"`
+		Verbose bool              `q:"verbose,flag"`
+		Range   *pagination       `q:"range"`
+		Meta    map[string]string `q:"meta"`
+		pagination
+	}
+
+	got, err := Example(params{Query: "shoes"})
+	ok(t, err)
+	exp := "limit=50&meta%5Bkey%5D=example&offset=0&page=1&per_page=1&q=shoes&range%5Bpage%5D=1&range%5Bper_page%5D=1" +
+		"&ratio=1.5&since=2006-01-02&sort=asc&status=open&timeout=60&verbose"
+	if got != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+
+	var decoded params
+	ok(t, NewDecoder(got).Decode(&decoded))
+	if decoded.Query != "shoes" || decoded.Limit != 50 || decoded.Timeout != time.Minute || !reflect.DeepEqual([]string{"open"}, decoded.Status) {
+		t.Fatalf("got: %+v", decoded)
+	}
+
+	if _, err := Example("q"); err == nil {
+		t.Fatalf("exp: error\ngot: %v", err)
+	}
+}
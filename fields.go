@@ -0,0 +1,105 @@
+package query
+
+import (
+	"reflect"
+)
+
+// A FieldInfo describes a field of a struct as Decode reads it, for tools
+// documenting the queries a handler accepts. The package has no key
+// aliases: each field reads the single key given by its tag.
+type FieldInfo struct {
+	Key      string       // query key, scoped by the keys of the structs it is nested in
+	Field    string       // path of the field, such as "Page" or "Filter.Status"
+	Type     reflect.Type // type of the field
+	Required bool         // whether the field has the "required" tag option
+	Default  string       // value of the "default" tag option
+	Enum     []string     // values allowed by the "enum" tag option
+	Min      string       // value of the "min" tag option
+	Max      string       // value of the "max" tag option
+	Options  []string     // all the tag options, as written
+
+	HasDefault bool // whether the field has a "default" tag option
+	Slice      bool // whether the field reads several values, as a slice or array
+	Map        bool // whether the field reads the keys nested in Key into a map
+	Nested     bool // whether the field is a struct read from the keys nested in Key
+}
+
+// Fields returns the fields of the struct v, or pointed by v, read by
+// Decode with its default options, in the order Decode reads them. The
+// fields of a nested struct follow the field holding it, their keys scoped
+// by its key, while those of untagged embedded structs are listed as fields
+// of v. Inline fields and fields tagged with "-" are left out, and a
+// recursive struct is listed once, without its fields.
+//
+// The FieldInfo values are built from the struct tags alone: Fields doesn't
+// check that the fields can be decoded, which CheckType does.
+func Fields(v interface{}) ([]FieldInfo, error) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, &UnsupportedTypeError{Type: reflect.TypeOf(v)}
+	}
+
+	var d Decoder
+	return d.fieldInfos(nil, t, "", "", make(map[reflect.Type]bool)), nil
+}
+
+// fieldInfos appends to infos the fields of the struct type t, scoped by
+// scope and named after path. visiting holds the structs being listed, to
+// stop at recursive types.
+func (d *Decoder) fieldInfos(infos []FieldInfo, t reflect.Type, scope, path string, visiting map[reflect.Type]bool) []FieldInfo {
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if fieldGroups(sf) != nil {
+			continue
+		}
+		key, opts, ok := d.fieldKey(sf, scope)
+		if !ok || opts.Contains("inline") {
+			continue
+		}
+		field := path + sf.Name
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		nested := ft.Kind() == reflect.Struct && isNested(ft)
+		if nested && key == scope {
+			if !visiting[ft] {
+				infos = d.fieldInfos(infos, ft, scope, field+".", visiting)
+			}
+			continue
+		}
+		if key == "" {
+			continue
+		}
+
+		info := FieldInfo{
+			Key:      key,
+			Field:    field,
+			Type:     sf.Type,
+			Required: opts.Contains("required"),
+			Options:  append([]string(nil), opts...),
+			Nested:   nested,
+			Map:      ft.Kind() == reflect.Map,
+			Slice:    (ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array) && !unmarshaler(ft),
+		}
+		info.Default, info.HasDefault = opts.Value("default")
+		if enum, ok := opts.Value("enum"); ok {
+			info.Enum = enumValues(enum)
+		}
+		info.Min, _ = opts.Value("min")
+		info.Max, _ = opts.Value("max")
+		infos = append(infos, info)
+
+		if nested && !visiting[ft] {
+			infos = d.fieldInfos(infos, ft, key, field+".", visiting)
+		}
+	}
+	return infos
+}
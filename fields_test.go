@@ -0,0 +1,60 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFields(t *testing.T) {
+	type filter struct {
+		Status string `q:"status,enum=open|closed"`
+	}
+	type params struct {
+		Query  string            `q:"q,required"`
+		Limit  uint              `q:"limit,min=1,max=100,default=20"`
+		Tags   []string          `q:"tag,comma"`
+		Filter *filter           `q:"filter"`
+		Meta   map[string]string `q:"meta"`
+		Ignore string            `q:"-"`
+		pagination
+	}
+
+	infos, err := Fields(&params{})
+	ok(t, err)
+
+	exp := []FieldInfo{
+		{Key: "q", Field: "Query", Type: reflect.TypeOf(""), Required: true, Options: []string{"required"}},
+		{Key: "limit", Field: "Limit", Type: reflect.TypeOf(uint(0)), Default: "20", Min: "1", Max: "100", Options: []string{"min=1", "max=100", "default=20"}, HasDefault: true},
+		{Key: "tag", Field: "Tags", Type: reflect.TypeOf([]string{}), Options: []string{"comma"}, Slice: true},
+		{Key: "filter", Field: "Filter", Type: reflect.TypeOf(&filter{}), Nested: true},
+		{Key: "filter[status]", Field: "Filter.Status", Type: reflect.TypeOf(""), Enum: []string{"open", "closed"}, Options: []string{"enum=open|closed"}},
+		{Key: "meta", Field: "Meta", Type: reflect.TypeOf(map[string]string{}), Map: true},
+		{Key: "page", Field: "pagination.Page", Type: reflect.TypeOf(0)},
+		{Key: "per_page", Field: "pagination.PerPage", Type: reflect.TypeOf(0)},
+	}
+	if !reflect.DeepEqual(infos, exp) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, infos)
+	}
+
+	t.Run("recursive", func(t *testing.T) {
+		type node struct {
+			Name string `q:"name"`
+			Next *node  `q:"next"`
+		}
+		infos, err := Fields(node{})
+		ok(t, err)
+		var keys []string
+		for _, info := range infos {
+			keys = append(keys, info.Key)
+		}
+		if exp := []string{"name", "next"}; !reflect.DeepEqual(keys, exp) {
+			t.Fatalf("exp: %v\ngot: %v", exp, keys)
+		}
+	})
+
+	t.Run("not a struct", func(t *testing.T) {
+		if _, err := Fields(2); err == nil {
+			t.Fatalf("exp: error\ngot: %v", err)
+		}
+	})
+}
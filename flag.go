@@ -0,0 +1,72 @@
+package query
+
+import (
+	"reflect"
+	"strconv"
+)
+
+var flagType = reflect.TypeOf(Flag{})
+
+// A Flag is a boolean that records the form its key was given in, for
+// feature toggles that are forwarded as they came: absent, bare as in
+// "?beta", or with a value as in "?beta=false". Its zero value is an absent
+// flag.
+//
+// As a field, a Flag is decoded from its key given bare, which is true, or
+// with a value accepted by strconv.ParseBool, which it keeps as given. It is
+// encoded in the form it was decoded from: bare, with its value unchanged,
+// or not at all when absent.
+type Flag struct {
+	Set   bool   // whether the key was given
+	Value string // value of the key as given, empty when bare
+}
+
+// Bare reports whether f was given without a value.
+func (f Flag) Bare() bool {
+	return f.Set && f.Value == ""
+}
+
+// Bool returns the value of f: true when bare, false when absent, and
+// otherwise its value as parsed by strconv.ParseBool.
+func (f Flag) Bool() bool {
+	if !f.Set {
+		return false
+	}
+	if f.Value == "" {
+		return true
+	}
+	b, _ := strconv.ParseBool(f.Value)
+	return b
+}
+
+// MarshalText returns the value of f, empty when bare or absent.
+func (f Flag) MarshalText() ([]byte, error) {
+	return []byte(f.Value), nil
+}
+
+// UnmarshalText sets f as given with the value text, bare when empty, if it
+// is a boolean.
+func (f *Flag) UnmarshalText(text []byte) error {
+	if len(text) > 0 {
+		if _, err := strconv.ParseBool(string(text)); err != nil {
+			return err
+		}
+	}
+	*f = Flag{Set: true, Value: string(text)}
+	return nil
+}
+
+// flag adds to values the encoding of f under name: the bare key, the key
+// with its value, or nothing when f is absent.
+func (e *encoder) flag(values adder, name string, f Flag) {
+	if !f.Set {
+		return
+	}
+	if f.Value == "" {
+		if e.flags == nil {
+			e.flags = make(map[string]bool)
+		}
+		e.flags[name] = true
+	}
+	values.Add(name, f.Value)
+}
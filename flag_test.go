@@ -0,0 +1,47 @@
+package query
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecode_Flag(t *testing.T) {
+	type toggles struct {
+		Beta    Flag `q:"beta"`
+		Preview Flag `q:"preview"`
+		Debug   Flag `q:"debug"`
+	}
+
+	var got toggles
+	ok(t, NewDecoder("beta&preview=0").Decode(&got))
+	exp := toggles{
+		Beta:    Flag{Set: true},
+		Preview: Flag{Set: true, Value: "0"},
+	}
+	if exp != got {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+	if !got.Beta.Bare() || !got.Beta.Bool() {
+		t.Fatalf("exp: bare true\ngot: %+v", got.Beta)
+	}
+	if got.Preview.Bare() || got.Preview.Bool() {
+		t.Fatalf("exp: explicit false\ngot: %+v", got.Preview)
+	}
+	if got.Debug.Set || got.Debug.Bool() {
+		t.Fatalf("exp: absent\ngot: %+v", got.Debug)
+	}
+
+	s, err := Marshal(got)
+	ok(t, err)
+	if exp := "beta&preview=0"; s != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, s)
+	}
+
+	err = NewDecoder("debug=maybe").Decode(&got)
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Fatalf("exp: %v\ngot: %v", ErrInvalidValue, err)
+	}
+	if exp, got := CodeInvalidBoolean, err.(CodedError).Code(); exp != got {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+}
@@ -0,0 +1,254 @@
+package query
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxFlatFields is the largest number of fields of a flat plan.
+const maxFlatFields = 64
+
+// A flatPlan describes how to decode a struct whose fields all hold a single
+// scalar value read from a key of their own, straight from the raw query.
+type flatPlan struct {
+	fields []flatField
+	index  map[string]int // field of each key
+}
+
+type flatField struct {
+	index    int    // index of the struct field
+	key      string // query key
+	opts     tagOptions
+	validate bool // whether the field has enum, pattern, min or max options
+}
+
+// flatPlans caches the flat plan of each struct type, nil for the types that
+// don't have one.
+var flatPlans sync.Map // map[reflect.Type]*flatPlan
+
+// flatPlanFor returns the flat plan of the struct type t, or nil when it
+// can't be decoded flat.
+func flatPlanFor(t reflect.Type) *flatPlan {
+	if p, ok := flatPlans.Load(t); ok {
+		return p.(*flatPlan)
+	}
+	p := newFlatPlan(t)
+	flatPlans.Store(t, p)
+	return p
+}
+
+func newFlatPlan(t reflect.Type) *flatPlan {
+	var d Decoder
+	p := &flatPlan{index: make(map[string]int)}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key, opts, ok := d.fieldKey(sf, "")
+		if !ok {
+			continue
+		}
+		_, raw := opts.Value("rawinto")
+		if key == "" || sf.PkgPath != "" || opts.Contains("inline") || opts.Contains("enumlenient") || raw || hasTransforms(opts) || !flatKind(sf.Type) ||
+			len(p.fields) == maxFlatFields {
+			return nil
+		}
+		if _, dup := p.index[key]; dup {
+			return nil
+		}
+		_, enum := opts.Value("enum")
+		_, pattern := opts.Value("pattern")
+		_, min := opts.Value("min")
+		_, max := opts.Value("max")
+		p.index[key] = len(p.fields)
+		p.fields = append(p.fields, flatField{i, key, opts, enum || pattern || min || max})
+	}
+	return p
+}
+
+// flatKind reports whether fields of type t can be decoded flat: they hold
+// a single scalar value, and don't decode themselves.
+func flatKind(t reflect.Type) bool {
+	return scalarKind(t.Kind()) && !unmarshaler(t)
+}
+
+// decodeFlat decodes the query of the decoder into v through the flat plan
+// of its type, without parsing it into url.Values. It reports false, having
+// left v untouched, when v has no flat plan, the decoder has options the
+// plan does not handle, or the query has anything unusual: keys no field
+// reads, repeated keys or escapes that url.ParseQuery rejects. Decode then
+// goes the regular way, which reports these as it always does.
+//
+// The scan stops as soon as every key of the plan is found, so that the
+// pairs following them, such as the tracking parameters appended to links,
+// cost nothing: the keys no field reads among them are not reported as
+// warnings, and the repeated keys keep their first value, as Decode does.
+func (d *Decoder) decodeFlat(v interface{}) (bool, error) {
+	if d.emptyAsMissing || d.canonicalKey != nil || d.splitLists || d.hooks != nil || d.errorHandler != nil || d.fallback != nil || d.defaults != nil ||
+		d.allowedKeys != nil || d.deniedKeys != nil || d.groups != nil ||
+		d.validUTF8 || d.noControlChars || d.keyConflict != ConflictByField || d.expectedSignature != nil ||
+		d.presentOnly || d.disallowUnknown || d.noRepeats || d.exactArrays || d.finiteFloats ||
+		d.skipMalformed || d.lastValue || d.given != nil {
+		return false, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return false, nil
+	}
+	p := flatPlanFor(rv.Elem().Type())
+	if p == nil || !wellFormed(d.q) {
+		return false, nil
+	}
+
+	var (
+		raw  [maxFlatFields]string
+		seen uint64
+		all  = uint64(1)<<len(p.fields) - 1
+	)
+	err := scanPairs(d.q, func(key, val string) error {
+		i, ok := p.index[key]
+		if !ok || seen&(1<<i) != 0 {
+			return errNotFlat
+		}
+		raw[i], seen = val, seen|1<<i
+		if seen == all {
+			return errAllSeen
+		}
+		return nil
+	})
+	if err != nil && err != errAllSeen {
+		return false, nil
+	}
+
+	dst := rv.Elem()
+	for i, f := range p.fields {
+		fv := dst.Field(f.index)
+		val := raw[i]
+		if fv.Kind() == reflect.String {
+			val = d.own(val)
+		}
+		if seen&(1<<i) == 0 {
+			def, ok := f.opts.Value("default")
+			if !ok {
+				if f.opts.Contains("required") {
+					return true, &MissingRequiredError{Key: f.key}
+				}
+				continue
+			}
+			val = def
+		}
+		if f.validate {
+			vals := [1]string{val}
+			if err := validate(f.key, vals[:], f.opts); err != nil {
+				if _, ok := err.(*strconv.NumError); ok {
+					return true, typeError(f.key, []string{d.own(val)}, fv.Type(), f.opts, err)
+				}
+				if ve, ok := err.(*ValidationError); ok {
+					ve.Value = d.own(ve.Value)
+				}
+				return true, err
+			}
+		}
+		if err := value(val, fv.Addr(), f.opts, nil); err != nil {
+			if _, ok := err.(*UnsupportedTypeError); ok {
+				return true, withField(err, dst.Type().Field(f.index).Name, f.key)
+			}
+			return true, typeError(f.key, []string{d.own(val)}, fv.Type(), f.opts, err)
+		}
+	}
+	return true, nil
+}
+
+// own returns s, copied when it may share the memory of the []byte given to
+// NewDecoderBytes.
+func (d *Decoder) own(s string) string {
+	if d.borrowed {
+		return strings.Clone(s)
+	}
+	return s
+}
+
+// errNotFlat stops the scan of a query that the flat path can't decode.
+var errNotFlat = errors.New("query: not flat")
+
+// errAllSeen stops the scan of a query once every key of a flat plan is
+// found in it.
+var errAllSeen = errors.New("query: all keys seen")
+
+// wellFormed reports whether the query string q holds neither a ';' nor an
+// invalid escape, for which url.ParseQuery returns an error. It is cheaper
+// than unescaping q.
+func wellFormed(q string) bool {
+	for i := 0; i < len(q); i++ {
+		switch q[i] {
+		case ';':
+			return false
+		case '%':
+			if i+2 >= len(q) || !isHex(q[i+1]) || !isHex(q[i+2]) {
+				return false
+			}
+			i += 2
+		}
+	}
+	return true
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// errSemicolon is the error of url.ParseQuery for pairs holding a ';'.
+var errSemicolon = errors.New("invalid semicolon separator in query")
+
+// scanPairs calls fn with the key and value of each pair of the query string
+// q, in order, unescaped as url.ParseQuery does. Like url.ParseQuery, it
+// skips the pairs holding a ';' or invalid escapes, and returns the first of
+// their errors once every pair is scanned. An error returned by fn stops the scan
+// and is returned instead.
+func scanPairs(q string, fn func(key, val string) error) error {
+	return scanPairsWith(q, unescape, fn)
+}
+
+// scanPairsWith is scanPairs, with keys and values unescaped by unescape.
+func scanPairsWith(q string, unescape func(string) (string, error), fn func(key, val string) error) error {
+	var err error
+	for q != "" {
+		var pair string
+		pair, q, _ = strings.Cut(q, "&")
+		if strings.IndexByte(pair, ';') >= 0 {
+			if err == nil {
+				err = errSemicolon
+			}
+			continue
+		}
+		if pair == "" {
+			continue
+		}
+		key, val, _ := strings.Cut(pair, "=")
+		key, uerr := unescape(key)
+		if uerr == nil {
+			val, uerr = unescape(val)
+		}
+		if uerr != nil {
+			if err == nil {
+				err = uerr
+			}
+			continue
+		}
+		if ferr := fn(key, val); ferr != nil {
+			return ferr
+		}
+	}
+	return err
+}
+
+// unescape unescapes s as url.ParseQuery does, without allocating when there
+// is nothing to unescape.
+func unescape(s string) (string, error) {
+	if strings.IndexByte(s, '%') < 0 && strings.IndexByte(s, '+') < 0 {
+		return s, nil
+	}
+	return url.QueryUnescape(s)
+}
@@ -0,0 +1,168 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type flatParams struct {
+	Query   string        `q:"q"`
+	Page    int           `q:"page,default=1"`
+	PerPage uint8         `q:"per_page,max=100"`
+	Ratio   float64       `q:"ratio"`
+	Active  bool          `q:"active"`
+	Sort    string        `q:"sort,enum=asc|desc"`
+	Timeout time.Duration `q:"timeout"`
+	Ignored string
+}
+
+func TestDecode_Flat(t *testing.T) {
+	if flatPlanFor(reflect.TypeOf(flatParams{})) == nil {
+		t.Fatal("exp: a flat plan")
+	}
+	for _, v := range []interface{}{listOptions{}, struct {
+		IDs []int `q:"id"`
+	}{}, struct {
+		T time.Time `q:"t"`
+	}{}} {
+		if flatPlanFor(reflect.TypeOf(v)) != nil {
+			t.Fatalf("exp: no flat plan for %T", v)
+		}
+	}
+
+	// The regular path, which any hook forces, is the reference.
+	identity := WithDecodeHook(func(key, raw string, target reflect.Type) (string, error) { return raw, nil })
+	for _, query := range []string{
+		"",
+		"&&",
+		"q=shoes&page=2&per_page=50&ratio=0.5&active&sort=desc&timeout=1m",
+		"q=red+shoes%21&page=%32",
+		"page=x",
+		"per_page=300",
+		"per_page=101",
+		"sort=up",
+		"q=a&q=b",
+		"q=a;b",
+		"q=%zz",
+		"unknown=1&page=3",
+		"=x",
+		"Ignored=x",
+		fullFlat + "&utm_source=x&q=boots",
+		fullFlat + "&utm_source=%zz",
+		fullFlat + "&utm_source=a;b",
+		"utm_source=x&" + fullFlat,
+	} {
+		exp, got := flatParams{Ratio: 9}, flatParams{Ratio: 9}
+		expErr := NewDecoder(query, identity).Decode(&exp)
+		gotErr := NewDecoder(query).Decode(&got)
+		if !reflect.DeepEqual(exp, got) || !reflect.DeepEqual(expErr, gotErr) {
+			t.Fatalf("%s\nexp: %+v %v\ngot: %+v %v", query, exp, expErr, got, gotErr)
+		}
+	}
+}
+
+// fullFlat holds every key of flatParams.
+const fullFlat = "q=shoes&page=2&per_page=50&ratio=0.5&active&sort=desc&timeout=1m"
+
+// trackingParams holds 200 keys that no field reads, as appended to links.
+var trackingParams = func() string {
+	var b strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&b, "&utm_%d=campaign%%20%d", i, i)
+	}
+	return b.String()
+}()
+
+func TestDecode_FlatEarlyExit(t *testing.T) {
+	var got flatParams
+	d := NewDecoder(fullFlat + trackingParams)
+	ok(t, d.Decode(&got))
+	exp := flatParams{Query: "shoes", Page: 2, PerPage: 50, Ratio: 0.5, Active: true, Sort: "desc", Timeout: time.Minute}
+	if got != exp {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+	if w := d.Warnings(); w != nil {
+		t.Fatalf("exp: %v\ngot: %v", nil, w)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := NewDecoder(fullFlat + trackingParams).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > 2 {
+		t.Fatalf("exp: at most 2 allocs\ngot: %v", allocs)
+	}
+}
+
+func TestDecode_FlatAllocs(t *testing.T) {
+	var v flatParams
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := NewDecoder("q=shoes&page=2&per_page=50&ratio=0.5&active&sort=desc").Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > 2 {
+		t.Fatalf("exp: at most 2 allocs\ngot: %v", allocs)
+	}
+}
+
+func BenchmarkDecodeFlat(b *testing.B) {
+	b.ReportAllocs()
+	var v flatParams
+	for i := 0; i < b.N; i++ {
+		if err := NewDecoder("q=shoes&page=2&per_page=50&ratio=0.5&active&sort=desc").Decode(&v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeFlat_tracking(b *testing.B) {
+	for _, test := range []struct {
+		name, query string
+	}{
+		{"keys first", fullFlat + trackingParams},
+		{"keys last", trackingParams[1:] + "&" + fullFlat},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			b.ReportAllocs()
+			var v flatParams
+			for i := 0; i < b.N; i++ {
+				if err := NewDecoder(test.query).Decode(&v); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestNewDecoderBytes(t *testing.T) {
+	for _, query := range []string{
+		"",
+		"q=shoes&page=2&per_page=50&ratio=0.5&active&sort=desc&timeout=1m",
+		"q=red+shoes%21&page=x",
+		"sort=up",
+		"q=a&q=b",
+		"q=%zz",
+		"q=foo&filter[status]=open&filter[meta][a]=1&page=1",
+	} {
+		for _, newV := range []func() interface{}{
+			func() interface{} { return &flatParams{} },
+			func() interface{} { return &listOptions{} },
+		} {
+			b := []byte(query)
+			exp, got := newV(), newV()
+			expErr := NewDecoder(query).Decode(exp)
+			gotErr := UnmarshalBytes(b, got)
+			for i := range b {
+				b[i] = 'x'
+			}
+			if !reflect.DeepEqual(exp, got) || !reflect.DeepEqual(expErr, gotErr) {
+				t.Fatalf("%s\nexp: %+v %v\ngot: %+v %v", query, exp, expErr, got, gotErr)
+			}
+		}
+	}
+}
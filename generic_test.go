@@ -0,0 +1,59 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+type listOpts[F any] struct {
+	Filter F `q:"filter"`
+	pagination
+}
+
+type flatOpts[T any] struct {
+	Value T   `q:"value"`
+	Limit int `q:"limit"`
+}
+
+type orderFilter struct {
+	Status string `q:"status"`
+	Min    int    `q:"min"`
+}
+
+type userFilter struct {
+	Name  string   `q:"name"`
+	Roles []string `q:"role"`
+}
+
+func TestDecode_Generic(t *testing.T) {
+	const query = "filter[status]=open&filter[min]=3&filter[name]=ann&filter[role]=a&filter[role]=b&page=2"
+
+	var orders listOpts[orderFilter]
+	ok(t, NewDecoder(query).Decode(&orders))
+	expOrders := listOpts[orderFilter]{Filter: orderFilter{Status: "open", Min: 3}, pagination: pagination{Page: 2}}
+	if !reflect.DeepEqual(expOrders, orders) {
+		t.Fatalf("exp: %+v\ngot: %+v", expOrders, orders)
+	}
+
+	var users listOpts[*userFilter]
+	ok(t, NewDecoder(query).Decode(&users))
+	expUsers := listOpts[*userFilter]{Filter: &userFilter{Name: "ann", Roles: []string{"a", "b"}}, pagination: pagination{Page: 2}}
+	if !reflect.DeepEqual(expUsers, users) {
+		t.Fatalf("exp: %+v\ngot: %+v", expUsers, users)
+	}
+
+	// Instantiations share a generic type but not their flat plans.
+	var n flatOpts[int]
+	ok(t, NewDecoder("value=7&limit=1").Decode(&n))
+	var s flatOpts[string]
+	ok(t, NewDecoder("value=7&limit=1").Decode(&s))
+	if n.Value != 7 || s.Value != "7" || n.Limit != 1 || s.Limit != 1 {
+		t.Fatalf("exp: %v\ngot: %+v, %+v", "value 7 and limit 1", n, s)
+	}
+	if err := NewDecoder("value=x").Decode(&flatOpts[int]{}); err == nil {
+		t.Fatalf("exp: %v\ngot: %v", "error decoding x into an int", err)
+	}
+
+	ok(t, CheckType(listOpts[orderFilter]{}))
+	ok(t, CheckType(listOpts[map[string]string]{}))
+}
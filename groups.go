@@ -0,0 +1,185 @@
+package query
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// A Group constrains which keys of a group of query keys may be present
+// together, as returned by Exclusive and Together.
+type Group struct {
+	rule string     // "exclusive" or "together"
+	sets [][]string // sets of keys, a single one for "together"
+}
+
+// Exclusive returns a group of sets of query keys of which at most one set
+// may have keys present in the query, such as page and per_page against
+// offset and limit:
+//
+//	query.Exclusive([]string{"page", "per_page"}, []string{"offset", "limit"})
+func Exclusive(sets ...[]string) Group {
+	return Group{"exclusive", sets}
+}
+
+// Together returns a group of query keys that must all be present in the
+// query when one of them is, such as from and to.
+func Together(keys ...string) Group {
+	return Group{"together", [][]string{keys}}
+}
+
+// WithGroups makes the decoder check the query against groups before
+// decoding it, failing with a *GroupError on the first one it breaks.
+//
+// Groups can also be declared by the "exclusive" and "together" tag options
+// of a blank struct field without a key, with the sets of keys separated by
+// "|" and the keys of a set by "+". They are checked when the fields of the
+// struct are decoded, with keys scoped as its fields':
+//
+//	type ListOptions struct {
+//		_ struct{} `q:",exclusive=page+per_page|offset+limit,together=from+to"`
+//		...
+//	}
+//
+// "together" can list several groups, each separated by "|".
+func WithGroups(groups ...Group) Option {
+	return func(d *Decoder) {
+		d.groups = append(d.groups, groups...)
+	}
+}
+
+// A GroupError describes query keys that break a Group.
+type GroupError struct {
+	Rule    string   // "exclusive" or "together"
+	Keys    []string // keys of the group present in the query
+	Missing []string // keys of a "together" group absent from the query
+}
+
+func (e *GroupError) Error() string {
+	if e.Rule == "exclusive" {
+		return "query: keys " + strings.Join(e.Keys, ", ") + " cannot be used together"
+	}
+	return "query: keys " + strings.Join(e.Keys, ", ") + " require keys " + strings.Join(e.Missing, ", ")
+}
+
+// Is reports whether target is ErrGroup.
+func (e *GroupError) Is(target error) bool {
+	return target == ErrGroup
+}
+
+// Fields returns the keys present in the query, for an "exclusive" group,
+// or absent from it, for a "together" group, each with a message naming the
+// other keys.
+func (e *GroupError) Fields() map[string]string {
+	fields := make(map[string]string)
+	if e.Rule == "exclusive" {
+		for i, k := range e.Keys {
+			others := append(append([]string{}, e.Keys[:i]...), e.Keys[i+1:]...)
+			fields[k] = "cannot be used with " + strings.Join(others, ", ")
+		}
+		return fields
+	}
+	for _, k := range e.Missing {
+		fields[k] = "is required with " + strings.Join(e.Keys, ", ")
+	}
+	return fields
+}
+
+// MarshalJSON encodes the fields of the error.
+func (e *GroupError) MarshalJSON() ([]byte, error) {
+	return marshalFields(e.Fields())
+}
+
+// Code returns CodeConflictingKeys for an "exclusive" group, and
+// CodeMissingTogether for a "together" group.
+func (e *GroupError) Code() string {
+	if e.Rule == "exclusive" {
+		return CodeConflictingKeys
+	}
+	return CodeMissingTogether
+}
+
+// Params returns the keys present in the query, and the missing ones of a
+// "together" group.
+func (e *GroupError) Params() map[string]interface{} {
+	params := map[string]interface{}{"keys": e.Keys}
+	if e.Rule == "together" {
+		params["missing"] = e.Missing
+	}
+	return params
+}
+
+// tagGroups returns the groups declared by the tag options opts.
+func tagGroups(opts tagOptions) []Group {
+	var groups []Group
+	if v, ok := opts.Value("exclusive"); ok {
+		var sets [][]string
+		for _, set := range strings.Split(v, "|") {
+			sets = append(sets, strings.Split(set, "+"))
+		}
+		groups = append(groups, Group{"exclusive", sets})
+	}
+	if v, ok := opts.Value("together"); ok {
+		for _, set := range strings.Split(v, "|") {
+			groups = append(groups, Group{"together", [][]string{strings.Split(set, "+")}})
+		}
+	}
+	return groups
+}
+
+// fieldGroups returns the groups declared by the tag of the struct field sf,
+// or nil when it is not a blank field declaring groups.
+func fieldGroups(sf reflect.StructField) []Group {
+	if sf.Name != "_" {
+		return nil
+	}
+	name, opts := cachedTag(sf.Tag.Get(TagKey))
+	if name != "" {
+		return nil
+	}
+	return tagGroups(opts)
+}
+
+// checkGroups checks src against groups, whose keys are scoped by scope.
+func (d *Decoder) checkGroups(src url.Values, groups []Group, scope string) error {
+	for _, g := range groups {
+		var present, missing []string
+		sets := 0
+		for _, set := range g.sets {
+			n := len(present)
+			for _, k := range set {
+				k = d.keyStyle.join(scope, k)
+				if d.canonicalKey != nil {
+					k = d.canonicalKey(k)
+				}
+				if d.hasKey(src, k) {
+					present = append(present, k)
+				} else {
+					missing = append(missing, k)
+				}
+			}
+			if len(present) > n {
+				sets++
+			}
+		}
+		switch {
+		case g.rule == "exclusive" && sets > 1:
+			return &GroupError{Rule: g.rule, Keys: present}
+		case g.rule == "together" && len(present) > 0 && len(missing) > 0:
+			return &GroupError{Rule: g.rule, Keys: present, Missing: missing}
+		}
+	}
+	return nil
+}
+
+// hasKey reports whether src holds key, as a scalar, a list written with
+// brackets or a scope of nested keys.
+func (d *Decoder) hasKey(src url.Values, key string) bool {
+	if _, ok := src[key]; ok {
+		return true
+	}
+	if _, ok := src[key+"[]"]; ok {
+		return true
+	}
+	return d.keyStyle != FlatKeys && d.scopes(src, key)
+}
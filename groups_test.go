@@ -0,0 +1,84 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecode_Groups(t *testing.T) {
+	type filter struct {
+		_    struct{} `q:",together=from+to"`
+		From int      `q:"from"`
+		To   int      `q:"to"`
+	}
+	type list struct {
+		_       struct{} `q:",exclusive=page+per_page|offset+limit"`
+		Page    int      `q:"page"`
+		PerPage int      `q:"per_page"`
+		Offset  int      `q:"offset"`
+		Limit   int      `q:"limit"`
+		Filter  filter   `q:"filter"`
+	}
+
+	tests := []struct {
+		query string
+		err   error
+	}{
+		{"page=2&per_page=10", nil},
+		{"offset=20&limit=10&filter[from]=1&filter[to]=2", nil},
+		{"page=2&limit=10", &GroupError{Rule: "exclusive", Keys: []string{"page", "limit"}}},
+		{"filter[from]=1", &GroupError{Rule: "together", Keys: []string{"filter[from]"}, Missing: []string{"filter[to]"}}},
+	}
+	for _, test := range tests {
+		var got list
+		err := NewDecoder(test.query).Decode(&got)
+		if !reflect.DeepEqual(test.err, err) {
+			t.Fatalf("%s\nexp: %v\ngot: %v", test.query, test.err, err)
+		}
+	}
+
+	var got list
+	err := NewDecoder("page=2&offset=20").Decode(&got)
+	if !errors.Is(err, ErrGroup) {
+		t.Fatalf("exp: %v\ngot: %v", ErrGroup, err)
+	}
+	if exp, got := "query: keys page, offset cannot be used together", err.Error(); exp != got {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+	exp := map[string]string{"page": "cannot be used with offset", "offset": "cannot be used with page"}
+	if got := err.(*GroupError).Fields(); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+}
+
+func TestDecode_WithGroups(t *testing.T) {
+	type search struct {
+		From   string   `q:"from"`
+		To     string   `q:"to"`
+		Cursor string   `q:"cursor"`
+		Tags   []string `q:"tag,brackets"`
+	}
+	d := NewDecoder("from=a&tag[]=x", WithGroups(
+		Exclusive([]string{"cursor"}, []string{"tag"}),
+		Together("from", "to"),
+	))
+
+	var got search
+	err := d.Decode(&got)
+	exp := &GroupError{Rule: "together", Keys: []string{"from"}, Missing: []string{"to"}}
+	if !reflect.DeepEqual(exp, err) {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+	if exp, got := "query: keys from require keys to", err.Error(); exp != got {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+	if exp, got := CodeMissingTogether, err.(CodedError).Code(); exp != got {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+
+	err = NewDecoder("cursor=c&tag[]=x", WithGroups(Exclusive([]string{"cursor"}, []string{"tag"}))).Decode(&got)
+	if exp := (&GroupError{Rule: "exclusive", Keys: []string{"cursor", "tag"}}); !reflect.DeepEqual(exp, err) {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+}
@@ -0,0 +1,316 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// DecodeHeader decodes the header h into the value pointed by v, as Decode
+// does for a query string. Header names are matched case-insensitively with
+// the keys of the "q" tags, and a header with several values is stored in a
+// slice field.
+func DecodeHeader(h http.Header, v interface{}, opts ...Option) error {
+	d := NewDecoder("", opts...)
+	d.canonicalKey = textproto.CanonicalMIMEHeaderKey
+
+	src := make(url.Values, len(h))
+	for k, vals := range h {
+		k = textproto.CanonicalMIMEHeaderKey(k)
+		src[k] = append(src[k], vals...)
+	}
+	d.given = src
+	return d.Decode(v)
+}
+
+// DecodeCookies decodes the cookies of r into the value pointed by v, as
+// Decode does for a query string, matching cookie names with the keys of the
+// "q" tags. Cookie values are unescaped as URL paths when they are valid
+// escapes, leaving '+' as it is, since it stands for itself in a cookie
+// rather than for a space, and several cookies with the same name are
+// stored in a slice field.
+func DecodeCookies(r *http.Request, v interface{}, opts ...Option) error {
+	src := make(url.Values)
+	for _, c := range r.Cookies() {
+		val, err := url.PathUnescape(c.Value)
+		if err != nil {
+			val = c.Value
+		}
+		src[c.Name] = append(src[c.Name], val)
+	}
+	d := NewDecoder("", opts...)
+	d.given = src
+	return d.Decode(v)
+}
+
+// maxFormSize is the largest body DecodeForm reads without a maximum length
+// set by WithMaxQueryLength, as http.Request.ParseForm does.
+const maxFormSize = 10 << 20
+
+// ErrFormContentType is returned by DecodeForm for a request whose body is
+// not application/x-www-form-urlencoded.
+var ErrFormContentType = errors.New("query: body is not application/x-www-form-urlencoded")
+
+// DecodeForm reads the application/x-www-form-urlencoded body of r and
+// decodes it into the value pointed by v, as Decode does for a query string.
+// It returns the body as it was read, byte for byte, such as for verifying
+// the signature of a webhook, and leaves r.Body reading it again for the
+// next handlers. The body may be no longer than the maximum length set by
+// WithMaxQueryLength, or else 10 MB, failing with a *QueryTooLongError. A
+// request with another Content-Type fails with ErrFormContentType, before its
+// body is read.
+func DecodeForm(r *http.Request, v interface{}, opts ...Option) ([]byte, error) {
+	if mt := mediaType(r); mt != "application/x-www-form-urlencoded" {
+		return nil, fmt.Errorf("%w: %q", ErrFormContentType, r.Header.Get("Content-Type"))
+	}
+	d := NewDecoder("", opts...)
+	raw, err := d.readBody(r)
+	if err != nil {
+		return raw, err
+	}
+	d.q = string(raw)
+	return raw, d.Decode(v)
+}
+
+// defaultMultipartMemory is the number of bytes of a multipart body that
+// DecodeRequest keeps in memory without WithMultipartMemory, the rest of
+// its files being stored in temporary files, as http.Request.FormFile does.
+const defaultMultipartMemory = 32 << 20
+
+// WithMultipartMemory sets the number of bytes of a multipart/form-data body
+// that DecodeRequest keeps in memory, as the maxMemory argument of
+// http.Request.ParseMultipartForm. It defaults to 32 MB.
+func WithMultipartMemory(n int64) Option {
+	return func(d *Decoder) {
+		d.multipartMemory = n
+	}
+}
+
+// DecodeRequest decodes into the value pointed by v the query string of r
+// and its form body, as Decode does for a query string. Bodies other than
+// application/x-www-form-urlencoded and multipart/form-data are left alone.
+// The values of the body come before those of the query string, as in
+// http.Request.Form: a field holding a single value takes the one of the
+// body, and a slice field holds both. The query string is checked against
+// the maximum length set by WithMaxQueryLength, the keys missing from both
+// take the values of WithFallback, and WithSkipMalformed skips the malformed
+// pairs of both.
+//
+// An application/x-www-form-urlencoded body is read as DecodeForm reads it,
+// and left for the next handlers to read again. A multipart/form-data body
+// is parsed by http.Request.ParseMultipartForm, keeping as many bytes in
+// memory as WithMultipartMemory sets. Its files are stored in the fields of
+// type []*multipart.FileHeader or *multipart.FileHeader tagged with the
+// "files" option, as in q:"file,files", and otherwise ignored.
+func DecodeRequest(r *http.Request, v interface{}, opts ...Option) error {
+	d := NewDecoder("", opts...)
+	var body url.Values
+	switch mediaType(r) {
+	case "application/x-www-form-urlencoded":
+		raw, err := d.readBody(r)
+		if err != nil {
+			return err
+		}
+		if body, err = url.ParseQuery(string(raw)); err != nil && !d.skipMalformed {
+			return err
+		}
+	case "multipart/form-data":
+		max := d.multipartMemory
+		if max <= 0 {
+			max = defaultMultipartMemory
+		}
+		if err := r.ParseMultipartForm(max); err != nil {
+			return err
+		}
+		body, d.files = r.MultipartForm.Value, r.MultipartForm.File
+	}
+
+	if r.URL != nil {
+		d.q = r.URL.RawQuery
+	}
+	d.given = body
+	return d.Decode(v)
+}
+
+var (
+	fileHeadersType = reflect.TypeOf([]*multipart.FileHeader(nil))
+	fileHeaderType  = reflect.TypeOf((*multipart.FileHeader)(nil))
+)
+
+// setFiles stores in fv, a []*multipart.FileHeader or *multipart.FileHeader,
+// the files of the key key of the multipart body read by DecodeRequest.
+func (d *Decoder) setFiles(fv reflect.Value, key string) {
+	files := d.files[key]
+	if len(files) == 0 {
+		return
+	}
+	switch fv.Type() {
+	case fileHeadersType:
+		fv.Set(reflect.ValueOf(slices.Clone(files)))
+	case fileHeaderType:
+		fv.Set(reflect.ValueOf(files[0]))
+	}
+}
+
+// mediaType returns the media type of the body of r, without parameters,
+// or "" when it has none or an invalid one.
+func mediaType(r *http.Request) string {
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return mt
+}
+
+// readBody reads the body of r, no longer than the maximum length of the
+// decoder or else maxFormSize, and replaces it with a reader of the bytes
+// read.
+func (d *Decoder) readBody(r *http.Request) ([]byte, error) {
+	max := d.maxLength
+	if max <= 0 {
+		max = maxFormSize
+	}
+	var raw []byte
+	if r.Body != nil {
+		var err error
+		raw, err = io.ReadAll(io.LimitReader(r.Body, int64(max)+1))
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(raw))
+		if err != nil {
+			return raw, err
+		}
+	}
+	if len(raw) > max {
+		n := int64(len(raw))
+		if r.ContentLength > n {
+			n = r.ContentLength
+		}
+		return raw, &QueryTooLongError{Length: int(n), Max: max}
+	}
+	return raw, nil
+}
+
+// SetQuery encodes v into the query string of the URL of req, as MergeQuery
+// does.
+func SetQuery(req *http.Request, v interface{}, opts ...EncoderOption) error {
+	if req.URL == nil {
+		return errors.New("query: SetQuery on a request without URL")
+	}
+	return MergeQuery(req.URL, v, opts...)
+}
+
+// MergeQuery encodes v and merges it into the query string of u. The values
+// of v replace the existing values of the same keys, or are added after them
+// with the EncodeAppend option. The other existing pairs are kept as they
+// are written, in their order and form, such as a bare "flag" key, and the
+// pairs of v follow them, encoded as by Marshal.
+func MergeQuery(u *url.URL, v interface{}, opts ...EncoderOption) error {
+	e := newEncoder(opts)
+	var g groups
+	if err := e.encodeInto(&g, v); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	bw := bufio.NewWriter(&b)
+	first := true
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		if !e.appendQuery {
+			rawKey, _, _ := strings.Cut(pair, "=")
+			if key, err := url.QueryUnescape(rawKey); err == nil && g.Has(key) {
+				continue
+			}
+		}
+		if !first {
+			bw.WriteByte('&')
+		}
+		first = false
+		bw.WriteString(pair)
+	}
+	e.writeGroups(bw, &g, first)
+	bw.Flush()
+	u.RawQuery = b.String()
+	return nil
+}
+
+type contextKey[T any] struct{}
+
+// requestError reports whether err, an error of Decode, is the fault of the
+// request rather than of the program: a FieldError, or a query string that
+// is too long, malformed, signed wrong or holds an empty key.
+func requestError(err error) bool {
+	var fe FieldError
+	var ee url.EscapeError
+	switch {
+	case errors.As(err, &fe), errors.As(err, &ee):
+		return true
+	case errors.Is(err, ErrTooLong), errors.Is(err, ErrEmptyKey), errors.Is(err, ErrInvalidSignature):
+		return true
+	}
+	// url.ParseQuery reports semicolons with an unexported error of the same
+	// text as errSemicolon.
+	return err.Error() == errSemicolon.Error()
+}
+
+// Middleware returns a handler that decodes the query string of each request
+// into a new T, stores it in the request context and calls next. Handlers
+// retrieve it with FromContext. A request whose query does not decode is
+// answered with 400 Bad Request, and next is not called. The body is the
+// JSON encoding of the error when it is a FieldError, or its text otherwise.
+// Errors that are not the fault of the request, such as an
+// *UnsupportedTypeError for a field of T, are answered with 500 Internal
+// Server Error and a generic body, which doesn't reveal them.
+func Middleware[T any](next http.Handler, opts ...Option) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v T
+		if err := NewDecoder(r.URL.RawQuery, opts...).Decode(&v); err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey[T]{}, v)))
+	})
+}
+
+// FromContext returns the value decoded by Middleware for T, and whether
+// there is one.
+func FromContext[T any](ctx context.Context) (T, bool) {
+	v, ok := ctx.Value(contextKey[T]{}).(T)
+	return v, ok
+}
+
+// writeDecodeError answers a request whose query does not decode with err,
+// as Middleware describes.
+func writeDecodeError(w http.ResponseWriter, err error) {
+	if !requestError(err) {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	var fe FieldError
+	if !errors.As(err, &fe) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	body, merr := fe.MarshalJSON()
+	if merr != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write(append(body, '\n'))
+}
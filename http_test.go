@@ -0,0 +1,292 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set("X-Page", "2")
+	h.Add("x-trace", "a")
+	h.Add("X-Trace", "b")
+	h["x-raw"] = []string{"raw"}
+
+	var got struct {
+		Page    int      `q:"x-page"`
+		PerPage int      `q:"X-Per-Page"`
+		Trace   []string `q:"X-TRACE"`
+		Raw     string   `q:"X-Raw"`
+	}
+	ok(t, DecodeHeader(h, &got))
+	if got.Page != 2 || got.PerPage != 0 || !reflect.DeepEqual([]string{"a", "b"}, got.Trace) || got.Raw != "raw" {
+		t.Fatalf("got: %+v", got)
+	}
+
+	h.Set("X-Page", "two")
+	var fe FieldError
+	if err := DecodeHeader(h, &got); !errors.As(err, &fe) {
+		t.Fatalf("exp: %T\ngot: %v", fe, err)
+	}
+}
+
+func TestDecodeForm(t *testing.T) {
+	type payment struct {
+		ID     string `q:"id"`
+		Amount int    `q:"amount"`
+	}
+	const body = "id=pay%5F1&amount=1050"
+	r := httptest.NewRequest("POST", "/webhook", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	var got payment
+	raw, err := DecodeForm(r, &got)
+	ok(t, err)
+	if string(raw) != body || got != (payment{"pay_1", 1050}) {
+		t.Fatalf("exp: %v %+v\ngot: %s %+v", body, payment{"pay_1", 1050}, raw, got)
+	}
+	again, err := io.ReadAll(r.Body)
+	ok(t, err)
+	if string(again) != body {
+		t.Fatalf("exp: %v\ngot: %s", body, again)
+	}
+
+	r = httptest.NewRequest("POST", "/webhook", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if _, err := DecodeForm(r, &got); !errors.Is(err, ErrFormContentType) {
+		t.Fatalf("exp: %v\ngot: %v", ErrFormContentType, err)
+	}
+
+	r = httptest.NewRequest("POST", "/webhook", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, err = DecodeForm(r, &got, WithMaxQueryLength(10))
+	exp := &QueryTooLongError{Length: len(body), Max: 10}
+	if !reflect.DeepEqual(exp, err) {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+}
+
+func TestDecodeRequest(t *testing.T) {
+	type upload struct {
+		Name  string                  `q:"name"`
+		Tags  []string                `q:"tag"`
+		Page  int                     `q:"page"`
+		Files []*multipart.FileHeader `q:"file,files"`
+		Cover *multipart.FileHeader   `q:"cover,files"`
+	}
+
+	r := httptest.NewRequest("POST", "/?name=query&tag=q&page=2", strings.NewReader("name=body&tag=b"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var got upload
+	ok(t, DecodeRequest(r, &got))
+	exp := upload{Name: "body", Tags: []string{"b", "q"}, Page: 2}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("name", "body")
+	w.WriteField("tag", "b")
+	for _, name := range []string{"a.txt", "b.txt"} {
+		fw, err := w.CreateFormFile("file", name)
+		ok(t, err)
+		fw.Write([]byte(name))
+	}
+	ok(t, w.Close())
+	r = httptest.NewRequest("POST", "/?name=query&tag=q&page=2", &body)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+
+	got = upload{}
+	ok(t, DecodeRequest(r, &got, WithMultipartMemory(1<<10)))
+	if got.Name != "body" || !reflect.DeepEqual([]string{"b", "q"}, got.Tags) || got.Page != 2 || got.Cover != nil ||
+		len(got.Files) != 2 || got.Files[0].Filename != "a.txt" || got.Files[1].Filename != "b.txt" {
+		t.Fatalf("got: %+v", got)
+	}
+
+	r = httptest.NewRequest("POST", "/?name=query", strings.NewReader(`{"name":"json"}`))
+	r.Header.Set("Content-Type", "application/json")
+	got = upload{}
+	ok(t, DecodeRequest(r, &got))
+	if got.Name != "query" {
+		t.Fatalf("exp: %v\ngot: %v", "query", got.Name)
+	}
+
+	r = httptest.NewRequest("POST", "/?name=query&tag=q&page=2", strings.NewReader("name=body"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	err := DecodeRequest(r, &upload{}, WithMaxQueryLength(16))
+	var tl *QueryTooLongError
+	if !errors.As(err, &tl) {
+		t.Fatalf("exp: %T\ngot: %v", tl, err)
+	}
+
+	r = httptest.NewRequest("POST", "/?tag=q&bad=%zz", strings.NewReader("name=body&x=%zz"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	got = upload{}
+	ok(t, DecodeRequest(r, &got, WithFallback(url.Values{"page": {"3"}, "name": {"fallback"}}), WithSkipMalformed()))
+	exp = upload{Name: "body", Tags: []string{"q"}, Page: 3}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+}
+
+func TestDecodeCookies(t *testing.T) {
+	type prefs struct {
+		Theme  string   `q:"theme,default=light"`
+		Lang   string   `q:"lang,required"`
+		Recent []string `q:"recent,comma"`
+		Size   int      `q:"size"`
+		Token  string   `q:"token"`
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "lang", Value: "es-CL"})
+	req.AddCookie(&http.Cookie{Name: "recent", Value: url.PathEscape("a b,c")})
+	req.AddCookie(&http.Cookie{Name: "size", Value: "100%"})
+
+	var got prefs
+	err := DecodeCookies(req, &got)
+	var fe FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("exp: %T\ngot: %v", fe, err)
+	}
+
+	req.Header.Del("Cookie")
+	req.AddCookie(&http.Cookie{Name: "lang", Value: "es-CL"})
+	req.AddCookie(&http.Cookie{Name: "recent", Value: url.PathEscape("a b,c")})
+	req.AddCookie(&http.Cookie{Name: "token", Value: "a+b/c=="})
+	got = prefs{}
+	ok(t, DecodeCookies(req, &got))
+	exp := prefs{Theme: "light", Lang: "es-CL", Recent: []string{"a b", "c"}, Token: "a+b/c=="}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	err = DecodeCookies(httptest.NewRequest(http.MethodGet, "/", nil), &got)
+	if _, isMissing := err.(*MissingRequiredError); !isMissing {
+		t.Fatalf("exp: %T\ngot: %v", &MissingRequiredError{}, err)
+	}
+}
+
+func TestMergeQuery(t *testing.T) {
+	in := struct {
+		Page int      `q:"page"`
+		Tags []string `q:"tag"`
+	}{Page: 2, Tags: []string{"b"}}
+
+	for _, tt := range []struct {
+		opts []EncoderOption
+		want url.Values
+	}{
+		{nil, url.Values{"page": {"2"}, "tag": {"b"}, "token": {"x"}}},
+		{[]EncoderOption{EncodeAppend()}, url.Values{"page": {"1", "2"}, "tag": {"a", "b"}, "token": {"x"}}},
+	} {
+		u, err := url.Parse("https://example.com/items?page=1&tag=a&token=x")
+		ok(t, err)
+		ok(t, MergeQuery(u, in, tt.opts...))
+
+		if got := u.Query(); !reflect.DeepEqual(tt.want, got) {
+			t.Fatalf("exp: %v\ngot: %v", tt.want, got)
+		}
+		if u.Path != "/items" {
+			t.Fatalf("exp: %v\ngot: %v", "/items", u.Path)
+		}
+	}
+}
+
+func TestMergeQuery_raw(t *testing.T) {
+	in := struct {
+		Page  int  `q:"page"`
+		Debug bool `q:"debug,flag"`
+	}{Page: 2, Debug: true}
+
+	for _, tt := range []struct {
+		opts []EncoderOption
+		want string
+	}{
+		{nil, "z=1&flag&a=%2F&debug&page=2"},
+		{[]EncoderOption{EncodeAppend()}, "z=1&flag&page=1&a=%2F&debug&page=2"},
+	} {
+		u, err := url.Parse("https://example.com/items?z=1&flag&page=1&a=%2F")
+		ok(t, err)
+		ok(t, MergeQuery(u, in, tt.opts...))
+		if u.RawQuery != tt.want {
+			t.Fatalf("exp: %v\ngot: %v", tt.want, u.RawQuery)
+		}
+	}
+}
+
+func TestSetQuery(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/items?page=1", nil)
+	ok(t, err)
+
+	ok(t, SetQuery(req, struct {
+		Page int `q:"page"`
+	}{3}))
+	if exp, got := "page=3", req.URL.RawQuery; exp != got {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	type params struct {
+		Page int `q:"page"`
+	}
+	var got params
+	var called bool
+	h := Middleware[params](http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, called = FromContext[params](r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?page=2", nil))
+	if !called || got.Page != 2 || rec.Code != http.StatusOK {
+		t.Fatalf("exp: %v\ngot: %v %+v (%d)", params{Page: 2}, called, got, rec.Code)
+	}
+
+	called = false
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?page=two", nil))
+	if called || rec.Code != http.StatusBadRequest {
+		t.Fatalf("exp: %v\ngot: %v (%d)", http.StatusBadRequest, called, rec.Code)
+	}
+	if exp, body := `{"errors":{"page":"must be an integer"}}`+"\n", rec.Body.String(); body != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, body)
+	}
+
+	if _, ok := FromContext[params](context.Background()); ok {
+		t.Fatal("exp: no value in an empty context")
+	}
+
+	for _, target := range []string{"/items?page=%zz", "/items?page=1;2"} {
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s\nexp: %v\ngot: %v", target, http.StatusBadRequest, rec.Code)
+		}
+	}
+
+	type unsupported struct {
+		Func func() `q:"func"`
+	}
+	called = false
+	rec = httptest.NewRecorder()
+	Middleware[unsupported](http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items?func=1", nil))
+	if called || rec.Code != http.StatusInternalServerError {
+		t.Fatalf("exp: %v\ngot: %v (%d)", http.StatusInternalServerError, called, rec.Code)
+	}
+	if exp, body := "Internal Server Error\n", rec.Body.String(); body != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, body)
+	}
+}
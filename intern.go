@@ -0,0 +1,41 @@
+package query
+
+import "net/url"
+
+// WithInterning makes the decoder share a single string between the keys and
+// values of its query written alike, such as the values of
+// "status=in%20progress&status=in%20progress", which are then only unescaped
+// once. It saves the allocations of the values repeated many times over in
+// large queries, and costs a map lookup per pair.
+func WithInterning() Option {
+	return func(d *Decoder) {
+		d.interning = true
+	}
+}
+
+// An interner maps the escaped form of the keys and values of a query to
+// their unescaped form.
+type interner map[string]string
+
+// unescape returns s unescaped, as unescape does, once for every s.
+func (in interner) unescape(s string) (string, error) {
+	if v, ok := in[s]; ok {
+		return v, nil
+	}
+	v, err := unescape(s)
+	if err == nil {
+		in[s] = v
+	}
+	return v, err
+}
+
+// parseInterned parses the query string q as url.ParseQuery does, with the
+// keys and values written alike sharing the same string.
+func parseInterned(q string) (url.Values, error) {
+	src := make(url.Values)
+	err := scanPairsWith(q, make(interner).unescape, func(key, val string) error {
+		src[key] = append(src[key], val)
+		return nil
+	})
+	return src, err
+}
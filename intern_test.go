@@ -0,0 +1,60 @@
+package query
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestDecode_WithInterning(t *testing.T) {
+	for _, query := range []string{
+		"",
+		"status=in%20progress&status=in+progress&status=open&q=a%2Bb",
+		"&&a=1&a=1&b&=x&",
+		"a=1;b=2&c=3",
+		"a=%zz&b=%20&b=%20",
+	} {
+		exp, expErr := url.ParseQuery(query)
+		got, err := parseInterned(query)
+		if !reflect.DeepEqual(exp, got) || !reflect.DeepEqual(expErr, err) {
+			t.Fatalf("%q: exp: %v, %v\ngot: %v, %v", query, exp, expErr, got, err)
+		}
+	}
+
+	var got struct {
+		Status []string `q:"status"`
+	}
+	ok(t, NewDecoder("status=in%20progress&status=in+progress&status=in%20progress", WithInterning()).Decode(&got))
+	if exp := []string{"in progress", "in progress", "in progress"}; !reflect.DeepEqual(exp, got.Status) {
+		t.Fatalf("exp: %v\ngot: %v", exp, got.Status)
+	}
+	if unsafe.StringData(got.Status[0]) != unsafe.StringData(got.Status[2]) {
+		t.Fatalf("exp: %v\ngot: %v", "shared strings", "distinct strings")
+	}
+}
+
+func BenchmarkDecode_WithInterning(b *testing.B) {
+	type params struct {
+		Status []string `q:"status"`
+	}
+	query := strings.Repeat("status=in%20progress&", 9999) + "status=in%20progress"
+	for _, test := range []struct {
+		name string
+		opts []Option
+	}{
+		{"plain", nil},
+		{"interning", []Option{WithInterning()}},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var v params
+				if err := NewDecoder(query, test.opts...).Decode(&v); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+// Package tagspec describes the grammar of q struct tags. It is shared by
+// package query and its static analyzer so both agree on what a valid tag is.
+package tagspec
+
+import "strings"
+
+// Key is the struct tag key read by package query.
+const Key = "q"
+
+// Options lists every option understood after the key in a q tag, by either
+// the encoder or the decoder. Options taking a value, written "name=value",
+// are listed by name.
+var Options = map[string]bool{
+	"omitempty":   true,
+	"keepzero":    true,
+	"allowempty":  true,
+	"int":         true,
+	"lenientint":  true,
+	"strictnum":   true,
+	"scale":       true,
+	"maxdigits":   true,
+	"base":        true,
+	"pad":         true,
+	"prec":        true,
+	"fmt":         true,
+	"flag":        true,
+	"keepempty":   true,
+	"unix":        true,
+	"unixmilli":   true,
+	"layout":      true,
+	"unit":        true,
+	"tz":          true,
+	"comma":       true,
+	"space":       true,
+	"semicolon":   true,
+	"brackets":    true,
+	"numbered":    true,
+	"indexed":     true,
+	"fold":        true,
+	"inline":      true,
+	"files":       true,
+	"literal":     true,
+	"secret":      true,
+	"nohash":      true,
+	"constcmp":    true,
+	"rawinto":     true,
+	"required":    true,
+	"default":     true,
+	"enum":        true,
+	"enumlenient": true,
+	"pattern":     true,
+	"min":         true,
+	"max":         true,
+	"prefix":      true,
+	"maxspan":     true,
+	"currency":    true,
+	"compact":     true,
+	"exclusive":   true,
+	"together":    true,
+	"trim":        true,
+	"upper":       true,
+	"lower":       true,
+	"maxlen":      true,
+}
+
+// Name returns the name of the tag option opt, which may carry a value as in
+// "layout=2006-01-02".
+func Name(opt string) string {
+	if i := strings.IndexByte(opt, '='); i >= 0 {
+		return opt[:i]
+	}
+	return opt
+}
+
+// Unescape returns the key name of a tag with its escaped dots unescaped,
+// and whether it had any: the name utm\.source, written q:"utm\\.source" in
+// Go source, is the key utm.source, whose dot never nests it.
+func Unescape(name string) (string, bool) {
+	if !strings.Contains(name, `\.`) {
+		return name, false
+	}
+	return strings.ReplaceAll(name, `\.`, "."), true
+}
+
+// Groups reports whether the struct field named field, whose tag has the
+// name name and the options opts, declares groups of keys: it is a blank
+// field without a key, with "exclusive" or "together" options.
+func Groups(field, name string, opts []string) bool {
+	if field != "_" || name != "" {
+		return false
+	}
+	for _, opt := range opts {
+		if n := Name(opt); n == "exclusive" || n == "together" {
+			return true
+		}
+	}
+	return false
+}
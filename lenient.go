@@ -0,0 +1,80 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+)
+
+// WithSkipMalformed makes Decode skip the pairs of the query that
+// url.ParseQuery rejects, such as those holding invalid escapes like "%zz"
+// or a ';', and decode the others, instead of failing.
+func WithSkipMalformed() Option {
+	return func(d *Decoder) {
+		d.skipMalformed = true
+	}
+}
+
+// WithLastValueWins makes the fields reading a single value, and the entries
+// of maps, take the last value of a key given several times, as a form with
+// two inputs of the same name sends, rather than the first one.
+func WithLastValueWins() Option {
+	return func(d *Decoder) {
+		d.lastValue = true
+	}
+}
+
+// TrimHook is a DecodeHook removing the spaces around the values of string
+// fields, and of the elements of string slices, as typed in form inputs.
+func TrimHook(key, raw string, target reflect.Type) (string, error) {
+	if target.Kind() != reflect.String || unmarshaler(target) {
+		return raw, nil
+	}
+	return strings.TrimSpace(raw), nil
+}
+
+// FormBoolHook is a DecodeHook reading the values HTML forms send for bool
+// fields: "on", which a checked checkbox without a value sends, and "yes"
+// as true, and "off" and "no" as false, in any case. Other values are left
+// to strconv.ParseBool.
+func FormBoolHook(key, raw string, target reflect.Type) (string, error) {
+	if target.Kind() != reflect.Bool {
+		return raw, nil
+	}
+	switch strings.ToLower(raw) {
+	case "on", "yes":
+		return "true", nil
+	case "off", "no":
+		return "false", nil
+	}
+	return raw, nil
+}
+
+// LenientConfig returns a Config decoding queries as browsers send HTML
+// forms, for internal tools whose forms are typed by people rather than
+// built by programs. Its options are, and will remain:
+//
+//   - WithDecodeHook(FormBoolHook)
+//   - WithDecodeHook(TrimHook)
+//   - WithEmptyAsMissing
+//   - WithSkipMalformed
+//   - WithLastValueWins
+//
+// It leaves out the options set by SetDefaultOptions. Every call returns the
+// same Config, which can't be changed; its With method extends it with the
+// options of a program.
+func LenientConfig() *Config {
+	return lenientConfig
+}
+
+var lenientConfig = &Config{opts: NewDecoder("", WithDecodeHook(FormBoolHook), WithDecodeHook(TrimHook),
+	WithEmptyAsMissing(), WithSkipMalformed(), WithLastValueWins()).decoderOptions}
+
+// lastValues returns vals, the values of a key for a field or map entry of
+// type t, reduced to the last one with WithLastValueWins when t reads a
+// single value.
+func (d *Decoder) lastValues(vals []string, t reflect.Type) []string {
+	if !d.lastValue || len(vals) < 2 || readsAll(t) {
+		return vals
+	}
+	return vals[len(vals)-1:]
+}
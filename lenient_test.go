@@ -0,0 +1,52 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLenientConfig(t *testing.T) {
+	type form struct {
+		Name    string            `q:"name"`
+		Notify  bool              `q:"notify"`
+		Archive bool              `q:"archive"`
+		Age     int               `q:"age"`
+		Page    int               `q:"page"`
+		Tags    []string          `q:"tag"`
+		Meta    map[string]string `q:"meta"`
+	}
+
+	got := form{Age: 7}
+	ok(t, LenientConfig().Decode("name=+Ada+&notify=on&archive=Off&age=&page=1&page=3&tag=+a&tag=b&bad=%zz&meta[k]=1&meta[k]=2", &got))
+	exp := form{Name: "Ada", Notify: true, Age: 7, Page: 3, Tags: []string{"a", "b"}, Meta: map[string]string{"k": "2"}}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	if err := NewDecoder("name=Ada&bad=%zz").Decode(&form{}); err == nil {
+		t.Fatalf("exp: %v\ngot: %v", "invalid escape", err)
+	}
+
+	var seen []string
+	c := LenientConfig().With(WithErrorHandler(func(key string, err error) error {
+		seen = append(seen, key)
+		return nil
+	}))
+	ok(t, c.Decode("age=old&notify=yes", &got))
+	if exp := []string{"age"}; !reflect.DeepEqual(exp, seen) {
+		t.Fatalf("exp: %v\ngot: %v", exp, seen)
+	}
+	err := LenientConfig().Decode("age=old", &got)
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Fatalf("exp: %v\ngot: %v", ErrInvalidValue, err)
+	}
+
+	var page struct {
+		Page int `q:"page"`
+	}
+	ok(t, NewDecoder("page=1&page=2", WithLastValueWins()).Decode(&page))
+	if page.Page != 2 {
+		t.Fatalf("exp: %v\ngot: %v", 2, page.Page)
+	}
+}
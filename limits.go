@@ -0,0 +1,79 @@
+package query
+
+import (
+	"io"
+	"math"
+	"strconv"
+	"unsafe"
+)
+
+// A QueryTooLongError describes a query string longer than the maximum
+// length of the decoder, set by WithMaxQueryLength, or than the limit given
+// to NewDecoderReader, which stops reading one byte past it.
+type QueryTooLongError struct {
+	Length int // length of the query string, in bytes, or the bytes read by NewDecoderReader
+	Max    int // maximum length
+}
+
+func (e *QueryTooLongError) Error() string {
+	return "query: query string of " + strconv.Itoa(e.Length) + " bytes is longer than " + strconv.Itoa(e.Max)
+}
+
+// Is reports whether target is ErrTooLong.
+func (e *QueryTooLongError) Is(target error) bool {
+	return target == ErrTooLong
+}
+
+// Code returns CodeTooLong.
+func (e *QueryTooLongError) Code() string {
+	return CodeTooLong
+}
+
+// Params returns the length of the query string and the maximum length.
+func (e *QueryTooLongError) Params() map[string]interface{} {
+	return map[string]interface{}{"length": e.Length, "max": e.Max}
+}
+
+// WithMaxQueryLength makes Decode and ForEach fail with a *QueryTooLongError
+// when the query string is longer than n bytes, as written, before parsing
+// anything. A length of 0 or less removes the limit, the default.
+func WithMaxQueryLength(n int) Option {
+	return func(d *Decoder) {
+		d.maxLength = n
+	}
+}
+
+// checkLength returns a *QueryTooLongError when the query string of the
+// decoder is longer than its maximum length.
+func (d *Decoder) checkLength() error {
+	if d.maxLength > 0 && len(d.q) > d.maxLength {
+		return &QueryTooLongError{Length: len(d.q), Max: d.maxLength}
+	}
+	return nil
+}
+
+// NewDecoderReader returns a new decoder that reads the query string from r,
+// such as an urlencoded payload taken from a message queue, as NewDecoder
+// does with the string read. It reads at most limit bytes, and fails with a
+// *QueryTooLongError, which callers can map to a 413 status, when r holds
+// more. A limit of 0 or less reads r to its end. Errors reading r are
+// returned as they are.
+func NewDecoderReader(r io.Reader, limit int64, opts ...Option) (*Decoder, error) {
+	if limit > 0 && limit < math.MaxInt64 {
+		r = io.LimitReader(r, limit+1)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && int64(len(b)) > limit {
+		return nil, &QueryTooLongError{Length: len(b), Max: int(limit)}
+	}
+
+	d := NewDecoder("", opts...)
+	if len(b) > 0 {
+		// b is never written to again, so that q can share its memory.
+		d.q = unsafe.String(&b[0], len(b))
+	}
+	return d, nil
+}
@@ -0,0 +1,69 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecode_MaxQueryLength(t *testing.T) {
+	for _, test := range []struct {
+		query string
+		err   error
+	}{
+		{"q=foo&page=2", nil},
+		{"q=foo&page=23", &QueryTooLongError{Length: 13, Max: 12}},
+		{"q=%41%42%43", nil},
+		{"q=%41%42%43%44", &QueryTooLongError{Length: 14, Max: 12}},
+		{"q=%zz%zz%zz%zz", &QueryTooLongError{Length: 14, Max: 12}},
+	} {
+		var got listOptions
+		err := NewDecoder(test.query, WithMaxQueryLength(12)).Decode(&got)
+		if !reflect.DeepEqual(test.err, err) {
+			t.Fatalf("exp: %v\ngot: %v", test.err, err)
+		}
+		if test.err != nil && !errors.Is(err, ErrTooLong) {
+			t.Fatalf("exp: %v\ngot: %v", ErrTooLong, err)
+		}
+	}
+
+	err := NewDecoder("q=foo&page=23", WithMaxQueryLength(12)).ForEach(func(key, value string) error {
+		t.Fatalf("exp: no pair\ngot: %s=%s", key, value)
+		return nil
+	})
+	if !errors.Is(err, ErrTooLong) {
+		t.Fatalf("exp: %v\ngot: %v", ErrTooLong, err)
+	}
+	ok(t, NewDecoder("q=foo&page=23", WithMaxQueryLength(0)).Decode(&listOptions{}))
+}
+
+func TestNewDecoderReader(t *testing.T) {
+	d, err := NewDecoderReader(strings.NewReader("q=foo&page=2"), 12)
+	ok(t, err)
+	var got listOptions
+	ok(t, d.Decode(&got))
+	if got.Query != "foo" || got.Page != 2 {
+		t.Fatalf("exp: %v\ngot: %+v", "q=foo&page=2", got)
+	}
+
+	_, err = NewDecoderReader(strings.NewReader("q=foo&page=23"), 12)
+	if exp := (&QueryTooLongError{Length: 13, Max: 12}); !reflect.DeepEqual(exp, err) {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+	if !errors.Is(err, ErrTooLong) {
+		t.Fatalf("exp: %v\ngot: %v", ErrTooLong, err)
+	}
+
+	d, err = NewDecoderReader(strings.NewReader("q=foo&page=23"), 0, WithMaxQueryLength(12))
+	ok(t, err)
+	if err := d.Decode(&got); !errors.Is(err, ErrTooLong) {
+		t.Fatalf("exp: %v\ngot: %v", ErrTooLong, err)
+	}
+
+	_, err = NewDecoderReader(iotest.ErrReader(iotest.ErrTimeout), 12)
+	if err != iotest.ErrTimeout {
+		t.Fatalf("exp: %v\ngot: %v", iotest.ErrTimeout, err)
+	}
+}
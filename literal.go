@@ -0,0 +1,84 @@
+package query
+
+import (
+	"net/url"
+	"reflect"
+	"sync"
+)
+
+// literals caches the result of literalKeys for each struct type and way of
+// writing keys.
+var literals sync.Map // map[duplicatesKey]map[string]string
+
+// literalKeys returns the keys read by the fields of the struct type t with
+// the "literal" tag option, including those of its nested structs, mapped to
+// the scope each one is nested in, or nil when there are none. Only DotKeys
+// nest keys with the dots such keys hold, as in "utm.source".
+func (d *Decoder) literalKeys(t reflect.Type) map[string]string {
+	if d.keyStyle != DotKeys {
+		return nil
+	}
+	ck := duplicatesKey{t, d.keyStyle, d.canonicalKey != nil}
+	if m, ok := literals.Load(ck); ok {
+		return m.(map[string]string)
+	}
+	m := make(map[string]string)
+	d.collectLiterals(t, "", m, make(map[reflect.Type]bool))
+	if len(m) == 0 {
+		m = nil
+	}
+	literals.Store(ck, m)
+	return m
+}
+
+// collectLiterals adds to m the literal keys of the fields of the struct
+// type t, scoped by scope.
+func (d *Decoder) collectLiterals(t reflect.Type, scope string, m map[string]string, visiting map[reflect.Type]bool) {
+	if visiting[t] {
+		return
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if fieldGroups(sf) != nil {
+			continue
+		}
+		key, opts, ok := d.fieldKey(sf, scope)
+		if !ok || opts.Contains("inline") {
+			continue
+		}
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch {
+		case ft.Kind() == reflect.Struct && isNested(ft):
+			d.collectLiterals(ft, key, m, visiting)
+		case opts.Contains("literal"):
+			m[key] = scope
+		}
+	}
+}
+
+// literal reports whether key is read by a literal field of a scope outside
+// scope, so that it is not nested in scope even though it looks it.
+func (d *Decoder) literal(key, scope string) bool {
+	s, ok := d.literals[key]
+	return ok && len(scope) > len(s)
+}
+
+// scopes reports whether any key of src may be nested in scope, as the key
+// style of the decoder has it, leaving out literal keys.
+func (d *Decoder) scopes(src url.Values, scope string) bool {
+	if d.literals == nil || scope == "" {
+		return d.keyStyle.scopes(src, scope)
+	}
+	for k := range src {
+		if d.keyStyle.nests(k, scope) && !d.literal(k, scope) {
+			return true
+		}
+	}
+	return false
+}
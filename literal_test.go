@@ -0,0 +1,67 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type tracking struct {
+	Campaign string `q:"campaign"`
+	Ref      string `q:"ref\\.id"`
+}
+
+type literalParams struct {
+	Source string            `q:"utm\\.source"`
+	Medium string            `q:"utm.medium,literal"`
+	UTM    map[string]string `q:"utm"`
+	Track  *tracking         `q:"track"`
+}
+
+func TestDecode_literalKeys(t *testing.T) {
+	var got literalParams
+	d := NewDecoder("utm.source=a&utm.medium=b&utm.term=c&track.ref.id=r", WithKeyStyle(DotKeys))
+	ok(t, d.Decode(&got))
+	exp := literalParams{Source: "a", Medium: "b", UTM: map[string]string{"term": "c"}, Track: &tracking{Ref: "r"}}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+	if w := d.Warnings(); w != nil {
+		t.Fatalf("exp: %v\ngot: %v", nil, w)
+	}
+
+	got = literalParams{}
+	ok(t, NewDecoder("utm.source=a&utm[term]=c&track[ref.id]=r").Decode(&got))
+	exp = literalParams{Source: "a", UTM: map[string]string{"term": "c"}, Track: &tracking{Ref: "r"}}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	got = literalParams{}
+	ok(t, NewDecoder("utm.source=a", WithKeyStyle(DotKeys), WithKeyConflict(ConflictError)).Decode(&got))
+	if got.Source != "a" || got.UTM != nil || got.Track != nil {
+		t.Fatalf("got: %+v", got)
+	}
+	err := NewDecoder("track=x&track.ref.id=r", WithKeyStyle(DotKeys), WithKeyConflict(ConflictError)).Decode(&got)
+	if !errors.Is(err, ErrKeyConflict) {
+		t.Fatalf("exp: %v\ngot: %v", ErrKeyConflict, err)
+	}
+
+	type clash struct {
+		Source string `q:"utm\\.source"`
+		UTM    struct {
+			Source string `q:"source"`
+		} `q:"utm"`
+	}
+	if err := NewDecoder("", WithKeyStyle(DotKeys)).Decode(&clash{}); !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("exp: %v\ngot: %v", ErrDuplicateKey, err)
+	}
+}
+
+func TestValues_literalKeys(t *testing.T) {
+	got, err := Marshal(literalParams{Source: "a", Track: &tracking{Ref: "r"}}, EncodeKeyStyle(DotKeys), EncodeOmitEmpty())
+	ok(t, err)
+	if exp := "track.ref.id=r&utm.source=a"; exp != got {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+}
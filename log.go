@@ -0,0 +1,51 @@
+package query
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+)
+
+// LogAttrs returns the attributes describing err, an error returned by
+// Decode, for structured logging:
+//
+//	slog.LogAttrs(ctx, slog.LevelWarn, "bad query", query.LogAttrs(err)...)
+//
+// They are "error", the text of err, followed by "code", "key", "value" and
+// "field" when err, or an error it wraps, is a CodedError that has them. An
+// error joining several errors, as errors.Join does, gives a group of
+// attributes for each of them, named after its index. LogAttrs returns nil
+// for a nil err. The values of secret fields are redacted in the errors
+// already, and so in their attributes.
+func LogAttrs(err error) []slog.Attr {
+	if err == nil {
+		return nil
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		multi, ok := e.(interface{ Unwrap() []error })
+		if !ok {
+			continue
+		}
+		var attrs []slog.Attr
+		for _, e := range multi.Unwrap() {
+			if e != nil {
+				attrs = append(attrs, slog.Attr{Key: strconv.Itoa(len(attrs)), Value: slog.GroupValue(LogAttrs(e)...)})
+			}
+		}
+		return attrs
+	}
+
+	attrs := []slog.Attr{slog.String("error", err.Error())}
+	var coded CodedError
+	if !errors.As(err, &coded) {
+		return attrs
+	}
+	attrs = append(attrs, slog.String("code", coded.Code()))
+	params := coded.Params()
+	for _, name := range []string{"key", "value", "field"} {
+		if v, ok := params[name]; ok {
+			attrs = append(attrs, slog.Any(name, v))
+		}
+	}
+	return attrs
+}
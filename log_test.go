@@ -0,0 +1,52 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+)
+
+func TestLogAttrs(t *testing.T) {
+	var v struct {
+		Page  int    `q:"page"`
+		Token string `q:"token,required"`
+	}
+	err := NewDecoder("page=x&token=a").Decode(&v)
+	decodeErr := []slog.Attr{
+		slog.String("error", err.Error()),
+		slog.String("code", CodeInvalidInteger),
+		slog.Any("key", "page"),
+		slog.Any("value", "x"),
+	}
+	missing := &MissingRequiredError{Key: "token"}
+	missingAttrs := []slog.Attr{
+		slog.String("error", missing.Error()),
+		slog.String("code", CodeRequired),
+		slog.Any("key", "token"),
+	}
+
+	for _, test := range []struct {
+		err error
+		exp []slog.Attr
+	}{
+		{nil, nil},
+		{errors.New("boom"), []slog.Attr{slog.String("error", "boom")}},
+		{err, decodeErr},
+		{fmt.Errorf("listing: %w", missing), append([]slog.Attr{slog.String("error", "listing: "+missing.Error())}, missingAttrs[1:]...)},
+		{fmt.Errorf("listing: %w", errors.Join(err, missing)), []slog.Attr{
+			{Key: "0", Value: slog.GroupValue(decodeErr...)},
+			{Key: "1", Value: slog.GroupValue(missingAttrs...)},
+		}},
+	} {
+		got := LogAttrs(test.err)
+		if len(got) != len(test.exp) {
+			t.Fatalf("exp: %v\ngot: %v", test.exp, got)
+		}
+		for i := range got {
+			if !got[i].Equal(test.exp[i]) {
+				t.Fatalf("exp: %v\ngot: %v", test.exp, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package query
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// A ForbiddenKeyError describes a query key that the decoder does not allow,
+// as set by WithAllowedKeys and WithDeniedKeys.
+type ForbiddenKeyError struct {
+	Key string // query key
+}
+
+func (e *ForbiddenKeyError) Error() string {
+	return "query: key " + e.Key + " is not allowed"
+}
+
+// Is reports whether target is ErrForbiddenKey.
+func (e *ForbiddenKeyError) Is(target error) bool {
+	return target == ErrForbiddenKey
+}
+
+// Fields returns the key with a message stating it is not allowed.
+func (e *ForbiddenKeyError) Fields() map[string]string {
+	return map[string]string{e.Key: "is not allowed"}
+}
+
+// MarshalJSON encodes the fields of the error.
+func (e *ForbiddenKeyError) MarshalJSON() ([]byte, error) {
+	return marshalFields(e.Fields())
+}
+
+// Code returns CodeForbiddenKey.
+func (e *ForbiddenKeyError) Code() string {
+	return CodeForbiddenKey
+}
+
+// Params returns the forbidden key.
+func (e *ForbiddenKeyError) Params() map[string]interface{} {
+	return map[string]interface{}{"key": e.Key}
+}
+
+// WithAllowedKeys restricts the query keys the decoder accepts to keys, and
+// the keys nested in them, such as "filter[status]" or "tag[]" for "filter"
+// and "tag". Decode fails with a *ForbiddenKeyError when the query holds
+// another key, unless WithDropForbiddenKeys is set. Keys are matched as they
+// are written in the query, before any canonicalization.
+func WithAllowedKeys(keys ...string) Option {
+	return func(d *Decoder) {
+		d.allowedKeys = append(make([]string, 0, len(keys)), keys...)
+	}
+}
+
+// WithDeniedKeys makes the decoder refuse the query keys keys, and the keys
+// nested in them, as WithAllowedKeys refuses the keys it doesn't list.
+func WithDeniedKeys(keys ...string) Option {
+	return func(d *Decoder) {
+		d.deniedKeys = append(make([]string, 0, len(keys)), keys...)
+	}
+}
+
+// WithDropForbiddenKeys makes the decoder ignore the keys refused by
+// WithAllowedKeys and WithDeniedKeys, with a warning, instead of failing.
+func WithDropForbiddenKeys() Option {
+	return func(d *Decoder) {
+		d.dropForbidden = true
+	}
+}
+
+// mask returns src without the keys forbidden by the decoder, or fails with
+// a *ForbiddenKeyError for the first of them in sorted order.
+func (d *Decoder) mask(src url.Values) (url.Values, error) {
+	if d.allowedKeys == nil && d.deniedKeys == nil {
+		return src, nil
+	}
+
+	var keys []string
+	for k := range src {
+		if d.forbids(k) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return src, nil
+	}
+	sort.Strings(keys)
+	if !d.dropForbidden {
+		return nil, &ForbiddenKeyError{Key: keys[0]}
+	}
+
+	kept := make(url.Values, len(src)-len(keys))
+	for k, vals := range src {
+		kept[k] = vals
+	}
+	for _, k := range keys {
+		delete(kept, k)
+		d.warn(k, strings.Join(src[k], ","), WarnForbiddenKey, nil)
+	}
+	return kept, nil
+}
+
+func (d *Decoder) forbids(key string) bool {
+	return d.allowedKeys != nil && !matchesKey(key, d.allowedKeys) || matchesKey(key, d.deniedKeys)
+}
+
+// matchesKey reports whether key is one of keys, or is nested in one of
+// them by brackets or a dot.
+func matchesKey(key string, keys []string) bool {
+	for _, k := range keys {
+		if strings.HasPrefix(key, k) && (len(key) == len(k) || key[len(k)] == '[' || key[len(k)] == '.') {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecode_AllowedAndDeniedKeys(t *testing.T) {
+	const query = "q=foo&filter[status]=open&filter[meta][a]=1&page=2&internal_only=true"
+	for _, test := range []struct {
+		opts []Option
+		err  error
+	}{
+		{[]Option{WithDeniedKeys("internal_only")}, &ForbiddenKeyError{Key: "internal_only"}},
+		{[]Option{WithDeniedKeys("filter")}, &ForbiddenKeyError{Key: "filter[meta][a]"}},
+		{[]Option{WithAllowedKeys("q", "filter", "page")}, &ForbiddenKeyError{Key: "internal_only"}},
+		{[]Option{WithAllowedKeys("q", "filter[status]", "page", "internal_only")}, &ForbiddenKeyError{Key: "filter[meta][a]"}},
+		{[]Option{WithAllowedKeys("q", "filter", "page", "internal_only"), WithDeniedKeys("filter_x")}, nil},
+	} {
+		err := NewDecoder(query, test.opts...).Decode(&listOptions{})
+		if !reflect.DeepEqual(test.err, err) {
+			t.Fatalf("exp: %v\ngot: %v", test.err, err)
+		}
+		if test.err != nil && !errors.Is(err, ErrForbiddenKey) {
+			t.Fatalf("exp: %v\ngot: %v", ErrForbiddenKey, err)
+		}
+	}
+
+	var got listOptions
+	d := NewDecoder(query, WithAllowedKeys("q", "filter", "page"), WithDeniedKeys("filter[meta]"), WithDropForbiddenKeys())
+	ok(t, d.Decode(&got))
+	exp := listOptions{Query: "foo", Filter: filter{Status: "open"}, pagination: pagination{Page: 2}}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+	expWarnings := []Warning{
+		{Key: "filter[meta][a]", Value: "1", Action: WarnForbiddenKey},
+		{Key: "internal_only", Value: "true", Action: WarnForbiddenKey},
+	}
+	if got := d.Warnings(); !reflect.DeepEqual(expWarnings, got) {
+		t.Fatalf("exp: %v\ngot: %v", expWarnings, got)
+	}
+
+	allowed, denied := []string{"q", "page", "internal_only"}, []string{"internal_only"}
+	d = NewDecoder(query, WithAllowedKeys(allowed...), WithDeniedKeys(denied...), WithDropForbiddenKeys())
+	allowed[0], denied[0] = "filter", "q"
+	got = listOptions{}
+	ok(t, d.Decode(&got))
+	if exp := (listOptions{Query: "foo", pagination: pagination{Page: 2}}); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+}
@@ -0,0 +1,169 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var moneyType = reflect.TypeOf(Money{})
+
+// A Money is an amount of money in the minor units of its currency, such as
+// cents, with the ISO 4217 code of the currency.
+//
+// As a field, a Money is decoded from two keys: its own key holds the amount
+// in minor units, and the key named by the "currency" tag option, or else
+// "currency", holds the currency, as in "amount=1050&currency=USD". Several
+// Money fields may share the currency key, and are then encoded only when
+// they have the same currency. The "compact" tag option reads a
+// single value instead, holding the amount in major units followed by the
+// currency, such as "amount=10.50USD": the amount may not have more decimal
+// places than the currency allows, and only the currencies of a small
+// built-in table are accepted. Amounts are never parsed as floats.
+//
+// A Money is encoded the way it is decoded.
+type Money struct {
+	Amount   int64  // amount in minor units
+	Currency string // ISO 4217 code, such as "USD"
+}
+
+// currencyExponents holds the number of decimal places of the currencies
+// accepted in compact form, following ISO 4217.
+var currencyExponents = map[string]int{
+	"ARS": 2, "AUD": 2, "BHD": 3, "BOB": 2, "BRL": 2, "CAD": 2, "CHF": 2,
+	"CLF": 4, "CLP": 0, "CNY": 2, "COP": 2, "CZK": 2, "DKK": 2, "EUR": 2,
+	"GBP": 2, "HKD": 2, "INR": 2, "ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0,
+	"KWD": 3, "MXN": 2, "NOK": 2, "NZD": 2, "OMR": 3, "PEN": 2, "PLN": 2,
+	"PYG": 0, "SEK": 2, "SGD": 2, "TND": 3, "USD": 2, "UYU": 2, "VND": 0,
+}
+
+// ParseMoney parses s in compact form: an amount in major units followed by
+// the code of a currency of the built-in table, such as "10.50USD" or
+// "-3CLP". The amount may not have more decimal places than the currency
+// allows.
+func ParseMoney(s string) (Money, error) {
+	if len(s) < 4 {
+		return Money{}, errors.New("missing amount or currency")
+	}
+	num, cur := s[:len(s)-3], s[len(s)-3:]
+	exp, ok := currencyExponents[cur]
+	if !ok {
+		return Money{}, fmt.Errorf("unknown currency %q", cur)
+	}
+	minor, err := shiftDecimal(num, exp)
+	if err == errDecimal {
+		return Money{}, fmt.Errorf("invalid amount %q", num)
+	} else if err != nil {
+		return Money{}, fmt.Errorf("%s allows %d decimal places", cur, exp)
+	}
+	amount, err := strconv.ParseInt(minor, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("amount %q out of range", num)
+	}
+	return Money{amount, cur}, nil
+}
+
+// String returns m in compact form, such as "10.50USD". The amount of a
+// currency missing from the built-in table is written in minor units.
+func (m Money) String() string {
+	return unshiftDecimal(strconv.FormatInt(m.Amount, 10), currencyExponents[m.Currency]) + m.Currency
+}
+
+// IsZero reports whether m is the zero Money.
+func (m Money) IsZero() bool {
+	return m == Money{}
+}
+
+// MarshalText returns m in compact form.
+func (m Money) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText parses m in compact form, as ParseMoney does.
+func (m *Money) UnmarshalText(text []byte) error {
+	v, err := ParseMoney(string(text))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// decode parses vals into m: a single value in compact form with the
+// "compact" tag option in opts, or else the amount in minor units and the
+// currency.
+func (m *Money) decode(vals []string, opts tagOptions) error {
+	if opts.Contains("compact") {
+		return m.UnmarshalText([]byte(vals[0]))
+	}
+	amount, err := strconv.ParseInt(vals[0], 10, 64)
+	if err != nil {
+		return err
+	}
+	var cur string
+	if len(vals) > 1 {
+		cur = vals[1]
+	}
+	if cur != "" && (len(cur) != 3 || strings.Trim(cur, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "") {
+		return fmt.Errorf("invalid currency %q", cur)
+	}
+	*m = Money{amount, cur}
+	return nil
+}
+
+// currencyKey returns the key of the currency of a Money field of type t
+// without the "compact" tag option, scoped by scope, and whether the field
+// is one.
+func (s KeyStyle) currencyKey(scope string, t reflect.Type, opts tagOptions) (string, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t != moneyType || opts.Contains("compact") {
+		return "", false
+	}
+	name, ok := opts.Value("currency")
+	if !ok {
+		name = "currency"
+	}
+	return s.join(scope, name), true
+}
+
+// lookupMoney returns the values of a Money field read from two keys: the
+// amount of the key key and the currency of the key cur. It reports false
+// when the amount is absent from src.
+func lookupMoney(src map[string][]string, key, cur string) ([]string, bool) {
+	vals := src[key]
+	if len(vals) == 0 {
+		return nil, false
+	}
+	var currency string
+	if c := src[cur]; len(c) > 0 {
+		currency = c[0]
+	}
+	return []string{vals[0], currency}, true
+}
+
+// money adds to values the encoding of the Money m, the field with key name
+// scoped by scope. It fails when m shares its currency key with a Money of
+// another currency, which the key can't hold both of.
+func (e *encoder) money(values adder, scope, name string, m Money, opts tagOptions) error {
+	cur, split := e.keyStyle.currencyKey(scope, moneyType, opts)
+	if !split {
+		values.Add(name, m.String())
+		return nil
+	}
+	if prev, ok := e.currencies[cur]; ok && m.Currency != "" && m.Currency != prev {
+		return fmt.Errorf("query: currency %s of %s differs from %s, written to the shared key %s", m.Currency, name, prev, cur)
+	}
+	values.Add(name, strconv.FormatInt(m.Amount, 10))
+	if m.Currency != "" && !values.Has(cur) {
+		values.Add(cur, m.Currency)
+		if e.currencies == nil {
+			e.currencies = make(map[string]string)
+		}
+		e.currencies[cur] = m.Currency
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	for _, test := range []struct {
+		s   string
+		exp Money
+		err string
+	}{
+		{"10.50USD", Money{1050, "USD"}, ""},
+		{"10.5USD", Money{1050, "USD"}, ""},
+		{"-0.05EUR", Money{-5, "EUR"}, ""},
+		{"1500CLP", Money{1500, "CLP"}, ""},
+		{"1.2345CLF", Money{12345, "CLF"}, ""},
+		{"92233720368547758.07USD", Money{9223372036854775807, "USD"}, ""},
+		{"10.50CLP", Money{}, "CLP allows 0 decimal places"},
+		{"1.005USD", Money{}, "USD allows 2 decimal places"},
+		{"10XYZ", Money{}, `unknown currency "XYZ"`},
+		{"1e3USD", Money{}, `invalid amount "1e3"`},
+		{"+1USD", Money{}, `invalid amount "+1"`},
+		{".5USD", Money{}, `invalid amount ".5"`},
+		{"92233720368547758.08USD", Money{}, `amount "92233720368547758.08" out of range`},
+		{"USD", Money{}, "missing amount or currency"},
+	} {
+		got, err := ParseMoney(test.s)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Fatalf("%s\nexp: %v\ngot: %v", test.s, test.err, err)
+			}
+			continue
+		}
+		ok(t, err)
+		if got != test.exp {
+			t.Fatalf("exp: %v\ngot: %v", test.exp, got)
+		}
+		if s := got.String(); s != test.s && test.s != "10.5USD" {
+			t.Fatalf("exp: %v\ngot: %v", test.s, s)
+		}
+	}
+	if exp, got := "-0.05EUR", (Money{-5, "EUR"}).String(); exp != got {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+	if exp, got := "-9223372036854775808XYZ", (Money{-9223372036854775808, "XYZ"}).String(); exp != got {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+}
+
+func TestDecode_Money(t *testing.T) {
+	type payment struct {
+		Amount Money  `q:"amount"`
+		Fee    *Money `q:"fee"`
+		Refund Money  `q:"refund,currency=refund_currency"`
+		Tip    Money  `q:"tip,compact"`
+	}
+	var got payment
+	d := NewDecoder("amount=1050&fee=30&currency=CLP&refund=-200&refund_currency=USD&tip=2.50USD&strict=1")
+	ok(t, d.Decode(&got))
+	exp := payment{
+		Amount: Money{1050, "CLP"},
+		Fee:    &Money{30, "CLP"},
+		Refund: Money{-200, "USD"},
+		Tip:    Money{250, "USD"},
+	}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+	if exp, got := []Warning{{"strict", "1", WarnUnknownKey, nil}}, d.Warnings(); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+
+	for _, query := range []string{"amount=10.50&currency=USD", "amount=1050&currency=usd", "tip=2.505USD"} {
+		err := NewDecoder(query).Decode(&payment{})
+		if !errors.Is(err, ErrInvalidValue) {
+			t.Fatalf("%s\nexp: %v\ngot: %v", query, ErrInvalidValue, err)
+		}
+	}
+}
+
+func TestValues_Money(t *testing.T) {
+	type payment struct {
+		Amount Money  `q:"amount"`
+		Fee    Money  `q:"fee,omitempty"`
+		Refund Money  `q:"refund,currency=refund_currency"`
+		Tip    *Money `q:"tip,compact"`
+	}
+	got, err := Values(payment{
+		Amount: Money{1050, "CLP"},
+		Refund: Money{-200, "USD"},
+		Tip:    &Money{250, "USD"},
+	})
+	ok(t, err)
+	if exp := "amount=1050&currency=CLP&refund=-200&refund_currency=USD&tip=2.50USD"; got.Encode() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, got.Encode())
+	}
+
+	got, err = Values(payment{Amount: Money{1050, "CLP"}, Fee: Money{30, "CLP"}})
+	ok(t, err)
+	if exp := "amount=1050&currency=CLP&fee=30&refund=0&tip="; got.Encode() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, got.Encode())
+	}
+	_, err = Values(payment{Amount: Money{1050, "CLP"}, Fee: Money{30, "USD"}})
+	if exp := "query: currency USD of fee differs from CLP, written to the shared key currency"; err == nil || err.Error() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+}
@@ -0,0 +1,100 @@
+package query
+
+import (
+	"errors"
+	"hash/fnv"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Normalize returns the canonical form of the query raw, such as a cache key
+// for a list endpoint: raw is decoded into a new struct of the type of proto,
+// a struct or a pointer to one, and encoded back with its keys sorted, its
+// values in the format of the encoder, and without the keys holding the
+// default value of their field, or its zero value when it has no default.
+// Two queries decoding to the same struct thus have the same canonical form,
+// such as "per_page=50&page=1" and "page=1" for a field per_page defaulting
+// to 50. Decoding errors are returned as Decoder.Decode returns them.
+func Normalize(raw string, proto interface{}) (string, error) {
+	t := structType(proto)
+	if t == nil {
+		return "", &UnsupportedTypeError{Type: reflect.TypeOf(proto)}
+	}
+
+	v := reflect.New(t).Interface()
+	if err := NewDecoder(raw).Decode(v); err != nil {
+		return "", err
+	}
+	vals, err := withoutDefaults(v, t)
+	if err != nil {
+		return "", err
+	}
+	return vals.Encode(), nil
+}
+
+// Hash returns a hash of the canonical form of the struct v, or of the
+// struct v points to, as given by Normalize, leaving out the fields tagged
+// with the "nohash" option, such as the page of a list. It is the 64-bit
+// FNV-1a hash of the canonical query, and so only changes with the values of
+// v or the definition of its type, never from one process to another.
+func Hash(v interface{}) (uint64, error) {
+	t := structType(v)
+	if t == nil {
+		return 0, &UnsupportedTypeError{Type: reflect.TypeOf(v)}
+	}
+
+	vals, err := withoutDefaults(v, t)
+	if err != nil {
+		return 0, err
+	}
+	var d Decoder
+	for _, info := range d.fieldInfos(nil, t, "", "", make(map[reflect.Type]bool)) {
+		if !tagOptions(info.Options).Contains("nohash") {
+			continue
+		}
+		for k := range vals {
+			if k == info.Key || strings.HasPrefix(k, info.Key+"[") {
+				delete(vals, k)
+			}
+		}
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(vals.Encode()))
+	return h.Sum64(), nil
+}
+
+// withoutDefaults returns the encoding of v, a struct of type t or a pointer
+// to one, without the keys holding the same values as in a struct of type t
+// decoded from an empty query, which are those of the defaults of its
+// fields, or their zero values.
+func withoutDefaults(v interface{}, t reflect.Type) (url.Values, error) {
+	vals, err := Values(v)
+	if err != nil {
+		return nil, err
+	}
+
+	def := reflect.New(t).Interface()
+	err = NewDecoder("", WithErrorHandler(func(key string, err error) error {
+		if errors.Is(err, ErrMissingKey) {
+			return nil
+		}
+		return err
+	})).Decode(def)
+	if err != nil {
+		return nil, err
+	}
+	defs, err := Values(def)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, dv := range defs {
+		if slices.Equal(vals[k], dv) {
+			delete(vals, k)
+		}
+	}
+	return vals, nil
+}
@@ -0,0 +1,73 @@
+package query
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	type list struct {
+		Query   string   `q:"q,required"`
+		Page    int      `q:"page,default=1"`
+		PerPage int      `q:"per_page,default=50"`
+		Tags    []string `q:"tag,comma"`
+		Active  *bool    `q:"active"`
+	}
+
+	for _, tc := range []struct{ raw, exp string }{
+		{"q=shoes", "q=shoes"},
+		{"per_page=50&q=shoes&page=1", "q=shoes"},
+		{"page=2&q=shoes&per_page=50", "page=2&q=shoes"},
+		{"tag=b&tag=a&q=shoes", "q=shoes&tag=b%2Ca"},
+		{"q=shoes&tag=b,a&active=1", "active=true&q=shoes&tag=b%2Ca"},
+		{"q=shoes&page=0", "page=0&q=shoes"},
+	} {
+		got, err := Normalize(tc.raw, &list{})
+		ok(t, err)
+		if got != tc.exp {
+			t.Fatalf("%s\nexp: %v\ngot: %v", tc.raw, tc.exp, got)
+		}
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := Normalize("page=1", list{}); !errors.Is(err, ErrMissingKey) {
+			t.Fatalf("exp: %v\ngot: %v", ErrMissingKey, err)
+		}
+		if _, err := Normalize("q=a", 2); err == nil {
+			t.Fatalf("exp: error\ngot: %v", err)
+		}
+	})
+}
+
+func TestHash(t *testing.T) {
+	type list struct {
+		Query   string            `q:"q"`
+		Page    int               `q:"page,default=1,nohash"`
+		PerPage int               `q:"per_page,default=50"`
+		Meta    map[string]string `q:"meta,nohash"`
+	}
+
+	h, err := Hash(list{Query: "shoes", Page: 1, PerPage: 50})
+	ok(t, err)
+	// FNV-1a of "q=shoes", which must not change between releases.
+	if exp := uint64(0x3965931b52d01f79); h != exp {
+		t.Fatalf("exp: %#x\ngot: %#x", exp, h)
+	}
+
+	for _, v := range []interface{}{
+		list{Query: "shoes", PerPage: 50},
+		&list{Query: "shoes", Page: 3, PerPage: 50, Meta: map[string]string{"a": "b"}},
+	} {
+		got, err := Hash(v)
+		ok(t, err)
+		if got != h {
+			t.Fatalf("%+v\nexp: %#x\ngot: %#x", v, h, got)
+		}
+	}
+
+	other, err := Hash(list{Query: "shoes", PerPage: 20})
+	ok(t, err)
+	if other == h {
+		t.Fatalf("exp: a hash other than %#x\ngot: %#x", h, other)
+	}
+}
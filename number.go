@@ -0,0 +1,78 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var numberType = reflect.TypeOf(Number(""))
+
+// numberPattern matches the values a Number is decoded from.
+const numberPattern = `^[-+]?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`
+
+// errNotNumber is the error of a value that is not a decimal number.
+var errNotNumber = errors.New("not a decimal number")
+
+// A Number is a decimal number kept as the text it was decoded from, for
+// fields that must not lose precision or formatting, such as account numbers
+// with leading zeros sent as numbers, or amounts beyond the precision of a
+// float64.
+//
+// As a field, alone or in slices, arrays and maps, a Number is decoded from
+// a decimal number with an optional sign, fraction and exponent, such as
+// "007", "-12.50" or "1e6", and encoded back as that text, unchanged. An
+// empty value leaves it unchanged.
+type Number string
+
+// String returns the text of n.
+func (n Number) String() string {
+	return string(n)
+}
+
+// Int64 returns n as an integer, failing when it has a fraction or an
+// exponent, or is out of the range of an int64.
+func (n Number) Int64() (int64, error) {
+	return strconv.ParseInt(strings.TrimPrefix(string(n), "+"), 10, 64)
+}
+
+// Float64 returns n as the nearest float64.
+func (n Number) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// UnmarshalText stores text in n if it is a decimal number.
+func (n *Number) UnmarshalText(text []byte) error {
+	if !isNumber(string(text)) {
+		return errNotNumber
+	}
+	*n = Number(text)
+	return nil
+}
+
+func setNumber(src string, dst reflect.Value) error {
+	if src == "" {
+		return nil
+	}
+	return dst.Interface().(*Number).UnmarshalText([]byte(src))
+}
+
+// isNumber reports whether s is a decimal number, as matched by
+// numberPattern.
+func isNumber(s string) bool {
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	mant, exp, hasExp := strings.Cut(strings.ToLower(s), "e")
+	if hasExp {
+		if exp != "" && (exp[0] == '-' || exp[0] == '+') {
+			exp = exp[1:]
+		}
+		if !isDigits(exp) {
+			return false
+		}
+	}
+	units, frac, dot := strings.Cut(mant, ".")
+	return isDigits(units) && (!dot || isDigits(frac))
+}
@@ -0,0 +1,61 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecode_Number(t *testing.T) {
+	type transfer struct {
+		Account Number            `q:"account"`
+		Amounts []Number          `q:"amount,comma"`
+		Limits  map[string]Number `q:"limit"`
+		Fee     *Number           `q:"fee"`
+	}
+
+	var got transfer
+	ok(t, NewDecoder("account=00012&amount=1.50,-2e3,12345678901234567890.01&limit[day]=%2B100&fee=0.10").Decode(&got))
+	fee := Number("0.10")
+	exp := transfer{
+		Account: "00012",
+		Amounts: []Number{"1.50", "-2e3", "12345678901234567890.01"},
+		Limits:  map[string]Number{"day": "+100"},
+		Fee:     &fee,
+	}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	for _, q := range []string{"account=12a", "amount=1,0x10", "fee=.5", "fee=1e", "account=Inf"} {
+		var got transfer
+		err := NewDecoder(q).Decode(&got)
+		if !errors.Is(err, ErrInvalidValue) {
+			t.Fatalf("%s\nexp: %v\ngot: %v", q, ErrInvalidValue, err)
+		}
+		if exp, got := CodeInvalidNumber, err.(CodedError).Code(); exp != got {
+			t.Fatalf("exp: %v\ngot: %v", exp, got)
+		}
+	}
+
+	vals, err := Values(exp)
+	ok(t, err)
+	if exp := "account=00012&amount=1.50%2C-2e3%2C12345678901234567890.01&fee=0.10&limit%5Bday%5D=%2B100"; vals.Encode() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, vals.Encode())
+	}
+}
+
+func TestNumber(t *testing.T) {
+	if n, err := Number("+0042").Int64(); err != nil || n != 42 {
+		t.Fatalf("exp: %v\ngot: %v, %v", 42, n, err)
+	}
+	if _, err := Number("1.5").Int64(); err == nil {
+		t.Fatalf("exp: error\ngot: %v", err)
+	}
+	if f, err := Number("-2.5e1").Float64(); err != nil || f != -25 {
+		t.Fatalf("exp: %v\ngot: %v, %v", -25, f, err)
+	}
+	if exp, got := "007", Number("007").String(); exp != got {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+}
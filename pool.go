@@ -0,0 +1,92 @@
+package query
+
+import (
+	"reflect"
+	"sync"
+)
+
+// calls holds the state of finished calls to Decode, for the next ones to
+// reuse along with the buffers they grew.
+var calls = sync.Pool{
+	New: func() interface{} { return new(Decoder) },
+}
+
+// WithNoPooling makes the decoder allocate the state of each call to Decode
+// instead of reusing the state of earlier calls, which the package keeps in
+// a sync.Pool. Pooled state outlives the calls that used it, which tools
+// looking for leaks may report.
+func WithNoPooling() Option {
+	return func(d *Decoder) {
+		d.noPooling = true
+	}
+}
+
+// newCall returns the state of a call to Decode, configured as d.
+func (d *Decoder) newCall() *Decoder {
+	var call *Decoder
+	if d.noPooling {
+		call = new(Decoder)
+	} else {
+		call = calls.Get().(*Decoder)
+	}
+	call.q, call.decoderOptions = d.q, d.decoderOptions
+	call.files = d.files
+	return call
+}
+
+// release hands the state of a finished call back to the pool, keeping its
+// buffers and dropping everything else. The warnings of the call, which
+// Warnings returns, are not reused.
+func (d *Decoder) release(call *Decoder) {
+	if d.noPooling {
+		return
+	}
+	clear(call.stack)
+	clear(call.names)
+	*call = Decoder{stack: call.stack[:0], names: call.names[:0]}
+	calls.Put(call)
+}
+
+type parsedTag struct {
+	name string
+	opts tagOptions
+}
+
+// tags caches the q tags parsed by the decoder, which reads the tag of every
+// field on each call to Decode. It only grows with the tags of the program.
+var tags struct {
+	sync.RWMutex
+	m map[string]parsedTag
+}
+
+// cachedTag returns the name and options of tag, as parseTag does. The
+// options are shared by every caller and must not be modified; appending to
+// them copies them.
+func cachedTag(tag string) (string, tagOptions) {
+	tags.RLock()
+	p, ok := tags.m[tag]
+	tags.RUnlock()
+	if ok {
+		return p.name, p.opts
+	}
+
+	name, opts := parseTag(tag)
+	p = parsedTag{name, opts[:len(opts):len(opts)]}
+	tags.Lock()
+	if tags.m == nil {
+		tags.m = make(map[string]parsedTag)
+	}
+	tags.m[tag] = p
+	tags.Unlock()
+	return p.name, p.opts
+}
+
+// zeroElem returns ev, or a new value of type t when ev doesn't hold one,
+// set to the zero value of t.
+func zeroElem(ev reflect.Value, t reflect.Type) reflect.Value {
+	if !ev.IsValid() || ev.Type() != t {
+		return reflect.New(t).Elem()
+	}
+	ev.SetZero()
+	return ev
+}
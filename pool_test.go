@@ -0,0 +1,44 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecode_Pooling(t *testing.T) {
+	const query = "q=foo&filter[status]=open&filter[meta][a]=1&filter[meta][b]=2&page=1&per_page=3&x=1"
+	var exp listOptions
+	dec := NewDecoder(query, WithNoPooling())
+	ok(t, dec.Decode(&exp))
+	expWarnings := dec.Warnings()
+
+	dec = NewDecoder(query)
+	for i := 0; i < 3; i++ {
+		var got listOptions
+		ok(t, dec.Decode(&got))
+		if !reflect.DeepEqual(exp, got) {
+			t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+		}
+	}
+	warnings := dec.Warnings()
+	ok(t, NewDecoder("y=2").Decode(&listOptions{}))
+	if !reflect.DeepEqual(expWarnings, warnings) {
+		t.Fatalf("exp: %v\ngot: %v", expWarnings, warnings)
+	}
+}
+
+func TestDecode_PoolingAllocs(t *testing.T) {
+	const query = "q=foo&filter[status]=open&filter[meta][a]=1&filter[meta][b]=2&page=1&per_page=3"
+	allocs := func(opts ...Option) float64 {
+		dec := NewDecoder(query, opts...)
+		return testing.AllocsPerRun(100, func() {
+			var v listOptions
+			if err := dec.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+	if pooled, unpooled := allocs(), allocs(WithNoPooling()); pooled >= unpooled {
+		t.Fatalf("exp: fewer than %v allocs\ngot: %v", unpooled, pooled)
+	}
+}
@@ -0,0 +1,142 @@
+// Package protoconv registers with package query converters for the
+// protobuf wrapper types and timestamps, so that the optional scalars of
+// structs generated from proto messages decode from queries the way
+// grpc-gateway maps them, and encode back:
+//
+//	import _ "github.com/Finciero/go-queryparams/protoconv"
+//
+//	type ListRequest struct {
+//		PageSize *wrapperspb.Int32Value  `q:"page_size"`
+//		After    *timestamppb.Timestamp `q:"after"`
+//	}
+//
+// The BoolValue, Int32Value, Int64Value, UInt32Value, UInt64Value,
+// FloatValue, DoubleValue and StringValue wrappers hold a single value
+// decoded as their Go type would be, and a Timestamp an RFC3339 time. Types
+// are recognized by package path and name, so that neither package has to
+// import protobuf.
+package protoconv
+
+import (
+	"reflect"
+	"strconv"
+	"time"
+
+	query "github.com/Finciero/go-queryparams"
+)
+
+const (
+	wrappersPath  = "google.golang.org/protobuf/types/known/wrapperspb"
+	timestampPath = "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func init() {
+	query.RegisterConverter(wrappers(wrappersPath))
+	query.RegisterConverter(timestamp(timestampPath))
+}
+
+// wrapperNames lists the wrapper types holding a scalar Value field.
+var wrapperNames = map[string]bool{
+	"BoolValue":   true,
+	"Int32Value":  true,
+	"Int64Value":  true,
+	"UInt32Value": true,
+	"UInt64Value": true,
+	"FloatValue":  true,
+	"DoubleValue": true,
+	"StringValue": true,
+}
+
+// wrappers returns the converter of the wrapper types of the package path.
+func wrappers(path string) query.Converter {
+	return query.Converter{
+		Match: func(t reflect.Type) bool {
+			if t.Kind() != reflect.Struct || t.PkgPath() != path || !wrapperNames[t.Name()] {
+				return false
+			}
+			_, ok := t.FieldByName("Value")
+			return ok
+		},
+		Decode: func(vals []string, v reflect.Value) error {
+			return setValue(vals[0], v.FieldByName("Value"))
+		},
+		Encode: func(v reflect.Value) ([]string, error) {
+			return []string{formatValue(v.FieldByName("Value"))}, nil
+		},
+	}
+}
+
+// timestamp returns the converter of the Timestamp type of the package path.
+func timestamp(path string) query.Converter {
+	return query.Converter{
+		Match: func(t reflect.Type) bool {
+			return t.Kind() == reflect.Struct && t.PkgPath() == path && t.Name() == "Timestamp"
+		},
+		Decode: func(vals []string, v reflect.Value) error {
+			t, err := time.Parse(time.RFC3339Nano, vals[0])
+			if err != nil {
+				return err
+			}
+			v.FieldByName("Seconds").SetInt(t.Unix())
+			v.FieldByName("Nanos").SetInt(int64(t.Nanosecond()))
+			return nil
+		},
+		Encode: func(v reflect.Value) ([]string, error) {
+			t := time.Unix(v.FieldByName("Seconds").Int(), v.FieldByName("Nanos").Int())
+			return []string{t.UTC().Format(time.RFC3339Nano)}, nil
+		},
+	}
+}
+
+// setValue parses src into the Value field fv of a wrapper. An empty value
+// is true for a BoolValue, as for bool fields.
+func setValue(src string, fv reflect.Value) error {
+	switch fv.Kind() {
+	case reflect.Bool:
+		if src == "" {
+			fv.SetBool(true)
+			return nil
+		}
+		b, err := strconv.ParseBool(src)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(src, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(src, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(src, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.String:
+		fv.SetString(src)
+	}
+	return nil
+}
+
+// formatValue is the inverse of setValue.
+func formatValue(fv reflect.Value) string {
+	switch fv.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(fv.Bool())
+	case reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), 10)
+	case reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(fv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(fv.Float(), 'g', -1, fv.Type().Bits())
+	}
+	return fv.String()
+}
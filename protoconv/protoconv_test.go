@@ -0,0 +1,101 @@
+package protoconv
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	query "github.com/Finciero/go-queryparams"
+)
+
+// Stand-ins for the generated types, with their unexported message state.
+type (
+	message struct {
+		state         struct{}
+		sizeCache     int32
+		unknownFields []byte
+	}
+	Int32Value struct {
+		message
+		Value int32
+	}
+	UInt64Value struct {
+		message
+		Value uint64
+	}
+	BoolValue struct {
+		message
+		Value bool
+	}
+	DoubleValue struct {
+		message
+		Value float64
+	}
+	StringValue struct {
+		message
+		Value string
+	}
+	Timestamp struct {
+		message
+		Seconds int64
+		Nanos   int32
+	}
+)
+
+func init() {
+	path := reflect.TypeOf(Timestamp{}).PkgPath()
+	query.RegisterConverter(wrappers(path))
+	query.RegisterConverter(timestamp(path))
+}
+
+type listRequest struct {
+	PageSize *Int32Value  `q:"page_size"`
+	MaxID    *UInt64Value `q:"max_id"`
+	Archived *BoolValue   `q:"archived"`
+	MinScore *DoubleValue `q:"min_score"`
+	Query    *StringValue `q:"query"`
+	After    *Timestamp   `q:"after"`
+	Before   *Timestamp   `q:"before,omitempty"`
+}
+
+func TestDecode(t *testing.T) {
+	var got listRequest
+	err := query.NewDecoder("page_size=20&max_id=18446744073709551615&archived&min_score=0.5&query=a+b&after=2024-01-02T03:04:05.5Z").Decode(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := listRequest{
+		PageSize: &Int32Value{Value: 20},
+		MaxID:    &UInt64Value{Value: 18446744073709551615},
+		Archived: &BoolValue{Value: true},
+		MinScore: &DoubleValue{Value: 0.5},
+		Query:    &StringValue{Value: "a b"},
+		After:    &Timestamp{Seconds: 1704164645, Nanos: 5e8},
+	}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	for _, q := range []string{"page_size=2147483648", "after=2024-01-02"} {
+		err := query.NewDecoder(q).Decode(&listRequest{})
+		if !errors.Is(err, query.ErrInvalidValue) {
+			t.Fatalf("exp: %v\ngot: %v", query.ErrInvalidValue, err)
+		}
+	}
+}
+
+func TestValues(t *testing.T) {
+	got, err := query.Values(listRequest{
+		PageSize: &Int32Value{Value: 20},
+		Archived: &BoolValue{Value: false},
+		After:    &Timestamp{Seconds: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Unix()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "after=2024-01-02T03%3A04%3A05Z&archived=false&max_id=&min_score=&page_size=20&query="
+	if got.Encode() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, got.Encode())
+	}
+}
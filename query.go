@@ -1,3 +1,139 @@
 package query
 
-const tagKey = "q"
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Finciero/go-queryparams/internal/tagspec"
+)
+
+// TagKey is the key of the struct tags read by the decoder and the encoder,
+// and by every function of the package inspecting struct types, such as
+// CheckType, Schema and Fields. Tools reading the same tags, such as code
+// generators and documentation tooling, should use it rather than spell it.
+const TagKey = tagspec.Key
+
+// tagOptionNames lists every option understood after the key in a q tag, by
+// either the encoder or the decoder.
+var tagOptionNames = tagspec.Options
+
+// A KeyStyle selects how the keys of nested structs and maps are written in
+// a query string.
+type KeyStyle int
+
+const (
+	// BracketKeys scopes nested keys with brackets: filter[status]=open.
+	BracketKeys KeyStyle = iota
+	// DotKeys scopes nested keys with dots: filter.status=open. The dots of
+	// a field's own key, such as utm.source, are kept from nesting it by the
+	// "literal" tag option, or by escaping them: q:"utm\\.source".
+	DotKeys
+	// FlatKeys writes the fields of nested structs as if they were fields of
+	// the outer struct: status=open. Map entries cannot be told apart from
+	// other keys that way, so they keep the bracket form.
+	FlatKeys
+)
+
+// join returns the key of the struct field name nested in scope.
+func (s KeyStyle) join(scope, name string) string {
+	switch {
+	case scope == "" || s == FlatKeys:
+		return name
+	case s == DotKeys:
+		return scope + "." + name
+	default:
+		return scope + "[" + name + "]"
+	}
+}
+
+// joinMap returns the key of the map entry name nested in scope.
+func (s KeyStyle) joinMap(scope, name string) string {
+	if s == DotKeys {
+		return scope + "." + name
+	}
+	return scope + "[" + name + "]"
+}
+
+// scopes reports whether any key of src may be nested in scope.
+func (s KeyStyle) scopes(src map[string][]string, scope string) bool {
+	if scope == "" || s == FlatKeys {
+		return true
+	}
+	for k := range src {
+		if s.nests(k, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// nests reports whether key is nested in the non-empty scope.
+func (s KeyStyle) nests(key, scope string) bool {
+	open := byte('[')
+	if s == DotKeys {
+		open = '.'
+	}
+	return len(key) > len(scope) && key[len(scope)] == open && strings.HasPrefix(key, scope)
+}
+
+// segment splits key, nested in scope, into the name of the map entry or
+// slice element of scope it refers to and the rest of key, nested further in
+// that entry: a and "[x]" for meta[a][x] in meta, or a and "" for meta[a].
+// It is the one parser of the names of map entries and slice elements, so
+// that maps, indexed slices and slices of structs or maps agree on them.
+func (s KeyStyle) segment(key, scope string) (string, string, bool) {
+	if s == DotKeys {
+		if !strings.HasPrefix(key, scope+".") {
+			return "", "", false
+		}
+		name := key[len(scope)+1:]
+		i := strings.IndexByte(name, '.')
+		switch {
+		case i < 0:
+			return name, "", true
+		case i == len(name)-1:
+			return "", "", false
+		}
+		return name[:i], name[i:], true
+	}
+
+	if !strings.HasPrefix(key, scope+"[") {
+		return "", "", false
+	}
+	rest := key[len(scope)+1:]
+	i := strings.IndexByte(rest, ']')
+	if i < 0 || strings.Contains(rest[:i], "[") {
+		return "", "", false
+	}
+	name, rest := rest[:i], rest[i+1:]
+	if rest != "" && (len(rest) == 1 || rest[0] != '[') {
+		return "", "", false
+	}
+	return name, rest, true
+}
+
+// mapKey is the inverse of joinMap: it returns the name of the map entry
+// scoped by scope that key refers to. Keys nested further are not entries of
+// the map.
+func (s KeyStyle) mapKey(key, scope string) (string, bool) {
+	name, rest, ok := s.segment(key, scope)
+	return name, ok && rest == ""
+}
+
+// mapScope returns the name of the map entry scoped by scope in which key is
+// nested further, such as a for meta[a][x] in meta.
+func (s KeyStyle) mapScope(key, scope string) (string, bool) {
+	name, rest, ok := s.segment(key, scope)
+	return name, ok && rest != "" && name != ""
+}
+
+// parseIndex returns the index of a slice element named name in a key, as
+// in tag[2] or accounts[0][id]: a decimal number without sign or leading
+// zeros, so that each element has a single name.
+func parseIndex(name string) (int, bool) {
+	if name == "" || name[0] == '+' || name[0] == '-' || len(name) > 1 && name[0] == '0' {
+		return 0, false
+	}
+	i, err := strconv.Atoi(name)
+	return i, err == nil
+}
@@ -0,0 +1,43 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTagKey(t *testing.T) {
+	typ := reflect.StructOf([]reflect.StructField{
+		{Name: "Page", Type: reflect.TypeOf(0), Tag: reflect.StructTag(TagKey + `:"page,min=1"`)},
+		{Name: "Sort", Type: reflect.TypeOf(""), Tag: reflect.StructTag(TagKey + `:"sort,bogus"`)},
+	})
+	v := reflect.New(typ)
+
+	ok(t, NewDecoder("page=2&sort=asc").Decode(v.Interface()))
+	if v.Elem().Field(0).Int() != 2 || v.Elem().Field(1).String() != "asc" {
+		t.Fatalf("exp: %v\ngot: %+v", "page 2 and sort asc", v.Elem())
+	}
+
+	vals, err := Values(v.Interface())
+	ok(t, err)
+	if exp := "page=2&sort=asc"; vals.Encode() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, vals.Encode())
+	}
+
+	err = CheckType(v.Interface())
+	if err == nil || !strings.Contains(err.Error(), `Sort: unknown tag option "bogus"`) {
+		t.Fatalf("exp: %v\ngot: %v", `unknown tag option "bogus"`, err)
+	}
+
+	specs, err := Schema(v.Interface())
+	ok(t, err)
+	if len(specs) != 2 || specs[0].Name != "page" || *specs[0].Schema.Minimum != 1 {
+		t.Fatalf("exp: %v\ngot: %+v", "page with a minimum of 1, and sort", specs)
+	}
+
+	infos, err := Fields(v.Interface())
+	ok(t, err)
+	if len(infos) != 2 || infos[1].Key != "sort" {
+		t.Fatalf("exp: %v\ngot: %+v", "page and sort", infos)
+	}
+}
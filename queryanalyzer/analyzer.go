@@ -0,0 +1,405 @@
+// Package queryanalyzer defines an Analyzer that reports problems in the q
+// tags of structs decoded by package query, at build time rather than when a
+// request is decoded.
+//
+// It performs the same checks as query.CheckType: fields of unsupported
+// types, several fields mapped to the same key, unknown tag options and
+// unexported tagged fields. Run it with go vet through the queryvet command:
+//
+//	go vet -vettool=$(which queryvet) ./...
+//
+// Transforms registered with query.RegisterTransform are unknown to it until
+// named by its -transforms flag, as in -transforms=slug,digits.
+package queryanalyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"reflect"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+
+	"github.com/Finciero/go-queryparams/internal/tagspec"
+)
+
+const queryPath = "github.com/Finciero/go-queryparams"
+
+// Analyzer reports structs decoded by the functions and methods of package
+// query whose q tags cannot be decoded.
+var Analyzer = &analysis.Analyzer{
+	Name:     "querytags",
+	Doc:      "check q struct tags of values decoded by package query",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// transforms holds the names given by the -transforms flag.
+var transforms string
+
+func init() {
+	Analyzer.Flags.StringVar(&transforms, "transforms", "", "comma-separated names of the transforms registered with query.RegisterTransform")
+}
+
+// decodeFuncs maps the decode functions of package query, and its decode
+// methods named after their receiver, to the index of the argument pointing
+// to the value they decode into.
+var decodeFuncs = map[string]int{
+	"Bind":                1,
+	"DecodeCookies":       1,
+	"DecodeEnv":           1,
+	"DecodeForm":          1,
+	"DecodeHeader":        1,
+	"DecodeRequest":       1,
+	"DecodeStrict":        1,
+	"DecodeWith":          0,
+	"UnmarshalBytes":      1,
+	"Config.Decode":       1,
+	"Decoder.Decode":      0,
+	"Decoder.DecodeValue": 0,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		t, at := decodeTarget(pass.TypesInfo, n.(*ast.CallExpr))
+		if at == nil {
+			return
+		}
+		st, ok := t.Underlying().(*types.Struct)
+		if !ok {
+			return
+		}
+
+		for _, problem := range check(st) {
+			pass.Reportf(at.Pos(), "%s: %s", types.TypeString(t, types.RelativeTo(pass.Pkg)), problem)
+		}
+	})
+	return nil, nil
+}
+
+// decodeTarget returns the type of the value decoded by call, and the
+// expression giving it, when call is one of the decode functions or methods
+// of package query: the type pointed to by its target argument, whatever
+// options follow it, or the type argument of Middleware.
+func decodeTarget(info *types.Info, call *ast.CallExpr) (types.Type, ast.Expr) {
+	fn, ok := typeutil.Callee(info, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != queryPath {
+		return nil, nil
+	}
+	name := fn.Name()
+	if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+		ptr, ok := recv.Type().(*types.Pointer)
+		if !ok {
+			return nil, nil
+		}
+		named, ok := ptr.Elem().(*types.Named)
+		if !ok {
+			return nil, nil
+		}
+		name = named.Obj().Name() + "." + name
+	}
+
+	if name == "Middleware" {
+		idx, ok := ast.Unparen(call.Fun).(*ast.IndexExpr)
+		if !ok {
+			return nil, nil
+		}
+		return info.TypeOf(idx.Index), idx.Index
+	}
+	i, ok := decodeFuncs[name]
+	if !ok || i >= len(call.Args) {
+		return nil, nil
+	}
+	arg := call.Args[i]
+	if name == "Decoder.DecodeValue" {
+		if arg = valueOf(info, arg); arg == nil {
+			return nil, nil
+		}
+	}
+	ptr, ok := info.TypeOf(arg).(*types.Pointer)
+	if !ok {
+		return nil, nil
+	}
+	return ptr.Elem(), arg
+}
+
+// valueOf returns the argument of e, a call to reflect.ValueOf, or nil when e
+// is any other expression.
+func valueOf(info *types.Info, e ast.Expr) ast.Expr {
+	call, ok := ast.Unparen(e).(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return nil
+	}
+	fn, ok := typeutil.Callee(info, call).(*types.Func)
+	if !ok || fn.Name() != "ValueOf" || fn.Pkg() == nil || fn.Pkg().Path() != "reflect" {
+		return nil
+	}
+	return call.Args[0]
+}
+
+// check mirrors query.CheckType for a struct type known at compile time.
+func check(st *types.Struct) []string {
+	c := &checker{visiting: make(map[*types.Struct]bool)}
+	c.checkStruct(st, "", make(map[string]string))
+	return c.problems
+}
+
+type checker struct {
+	problems []string
+	visiting map[*types.Struct]bool
+}
+
+func (c *checker) report(format string, args ...interface{}) {
+	c.problems = append(c.problems, fmt.Sprintf(format, args...))
+}
+
+func (c *checker) checkStruct(st *types.Struct, path string, keys map[string]string) {
+	if c.visiting[st] {
+		return
+	}
+	c.visiting[st] = true
+	defer delete(c.visiting, st)
+
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		tag, ok := reflect.StructTag(st.Tag(i)).Lookup(tagspec.Key)
+		if tag == "-" {
+			continue
+		}
+		s := strings.Split(tag, ",")
+		name, opts := s[0], s[1:]
+		name, _ = tagspec.Unescape(name)
+		field := path + f.Name()
+
+		if name == "" && f.Anonymous() {
+			if inner, ok := nested(f.Type()).(*types.Struct); ok && !isPointer(f.Type()) {
+				c.checkStruct(inner, field+".", keys)
+				continue
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		if tagspec.Groups(f.Name(), name, opts) {
+			for _, opt := range opts {
+				if n := tagspec.Name(opt); n != "exclusive" && n != "together" {
+					c.report("%s: unknown tag option %q", field, opt)
+				}
+			}
+			continue
+		}
+		if name == "*" || slices.Contains(opts, "inline") {
+			if !inlineMap(f.Type()) {
+				c.report("%s: inline field must be a url.Values or map[string][]string", field)
+			}
+			if prev, ok := keys["*"]; ok {
+				c.report("%s: only one inline field is allowed, %s is already one", field, prev)
+			} else {
+				keys["*"] = field
+			}
+			continue
+		}
+		if prev, ok := keys[name]; ok {
+			c.report("%s: key %q is already used by %s", field, name, prev)
+		} else {
+			keys[name] = field
+		}
+		for _, opt := range opts {
+			if !tagspec.Options[tagspec.Name(opt)] && !(transforms != "" && slices.Contains(strings.Split(transforms, ","), opt)) {
+				c.report("%s: unknown tag option %q", field, opt)
+			}
+		}
+		if !f.Exported() {
+			c.report("%s: field is not exported", field)
+		}
+		if typeParam(f.Type()) || slices.Contains(opts, "files") {
+			continue
+		}
+
+		switch u := nested(f.Type()).(type) {
+		case *types.Struct:
+			c.checkStruct(u, field+".", make(map[string]string))
+		case *types.Map:
+			if !decodableMap(u) {
+				c.report("%s: type %s is not supported", field, f.Type())
+			}
+		default:
+			switch u := nestedElem(f.Type()).(type) {
+			case *types.Struct:
+				c.checkStruct(u, field+"[].", make(map[string]string))
+			case *types.Map:
+				if !decodableMap(u) {
+					c.report("%s: type %s is not supported", field, f.Type())
+				}
+			default:
+				if !decodable(f.Type()) {
+					c.report("%s: type %s is not supported", field, f.Type())
+				}
+			}
+		}
+	}
+}
+
+// typeParam reports whether t is a type parameter, or a pointer, slice,
+// array or map of one, such as the field types of a generic struct decoded
+// within a generic function: they are checked where it is instantiated.
+func typeParam(t types.Type) bool {
+	for {
+		switch u := t.(type) {
+		case *types.TypeParam:
+			return true
+		case *types.Pointer:
+			t = u.Elem()
+		case *types.Slice:
+			t = u.Elem()
+		case *types.Array:
+			t = u.Elem()
+		case *types.Map:
+			t = u.Elem()
+		default:
+			return false
+		}
+	}
+}
+
+// inlineMap reports whether t can hold the values of several keys, as
+// url.Values does.
+func inlineMap(t types.Type) bool {
+	m, ok := t.Underlying().(*types.Map)
+	if !ok {
+		return false
+	}
+	key, ok := m.Key().Underlying().(*types.Basic)
+	elem, isSlice := m.Elem().Underlying().(*types.Slice)
+	if !ok || key.Info()&types.IsString == 0 || !isSlice {
+		return false
+	}
+	b, ok := elem.Elem().Underlying().(*types.Basic)
+	return ok && b.Info()&types.IsString != 0
+}
+
+// nested returns the underlying struct or map type of t, or of the type t
+// points to, when the decoder reads it from several scoped keys. It returns
+// nil for every other type.
+func nested(t types.Type) types.Type {
+	if ptr, ok := t.Underlying().(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Map:
+		return u
+	case *types.Struct:
+		if !unmarshaler(t) {
+			return u
+		}
+	}
+	return nil
+}
+
+// nestedElem returns the nested type of the elements of t, or of the slice
+// t points to, when t is a slice of structs or maps decoded from keys scoped
+// by an index. It returns nil for every other type.
+func nestedElem(t types.Type) types.Type {
+	if ptr, ok := t.Underlying().(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if s, ok := t.Underlying().(*types.Slice); ok && !unmarshaler(t) {
+		return nested(s.Elem())
+	}
+	return nil
+}
+
+func isPointer(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Pointer)
+	return ok
+}
+
+// decodableMap reports whether the decoder knows how to store the entries of
+// the map m, or of the maps it holds.
+func decodableMap(m *types.Map) bool {
+	if !mapKey(m.Key()) {
+		return false
+	}
+	if inner, ok := m.Elem().Underlying().(*types.Map); ok {
+		m = inner
+		if !mapKey(m.Key()) {
+			return false
+		}
+	}
+	return nested(m.Elem()) == nil && decodable(m.Elem())
+}
+
+// mapKey reports whether the decoder can read map keys of type t: strings
+// and integers.
+func mapKey(t types.Type) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Info()&(types.IsString|types.IsInteger) != 0 && b.Kind() != types.Uintptr
+}
+
+// decodable reports whether the decoder knows how to store query values in a
+// field of type t.
+func decodable(t types.Type) bool {
+	if ptr, ok := t.Underlying().(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if unmarshaler(t) {
+		return true
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Slice:
+		t = u.Elem()
+	case *types.Array:
+		t = u.Elem()
+	}
+	if hasMethod(t, "UnmarshalText", types.Typ[types.Byte]) {
+		return true
+	}
+	if ptr, ok := t.Underlying().(*types.Pointer); ok && (isNamed(ptr.Elem(), "math/big", "Int") || isNamed(ptr.Elem(), "math/big", "Rat")) {
+		return true
+	}
+	if isNamed(t, "time", "Time") || isNamed(t, queryPath, "Date") {
+		return true
+	}
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Info()&(types.IsBoolean|types.IsInteger|types.IsFloat|types.IsString) != 0 &&
+		b.Kind() != types.Uintptr && b.Kind() != types.UnsafePointer
+}
+
+// isNamed reports whether t is the type name declared by the package path.
+func isNamed(t types.Type, path, name string) bool {
+	n, ok := t.(*types.Named)
+	return ok && n.Obj().Name() == name && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == path
+}
+
+// unmarshaler reports whether *t implements query.Unmarshaler or
+// encoding.TextUnmarshaler.
+func unmarshaler(t types.Type) bool {
+	return hasMethod(t, "UnmarshalQuery", types.Typ[types.String]) ||
+		hasMethod(t, "UnmarshalText", types.Typ[types.Byte])
+}
+
+// hasMethod reports whether *t has a method name(x []elem) error.
+func hasMethod(t types.Type, name string, elem types.Type) bool {
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), true, nil, name)
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Params().Len() != 1 || sig.Results().Len() != 1 {
+		return false
+	}
+	param, ok := sig.Params().At(0).Type().(*types.Slice)
+	if !ok || !types.Identical(param.Elem(), elem) {
+		return false
+	}
+	return types.Identical(sig.Results().At(0).Type(), types.Universe.Lookup("error").Type())
+}
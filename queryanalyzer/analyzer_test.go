@@ -0,0 +1,16 @@
+package queryanalyzer_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/Finciero/go-queryparams/queryanalyzer"
+)
+
+func TestAnalyzer(t *testing.T) {
+	if err := queryanalyzer.Analyzer.Flags.Set("transforms", "slug"); err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, analysistest.TestData(), queryanalyzer.Analyzer, "a")
+}
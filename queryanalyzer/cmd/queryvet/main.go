@@ -0,0 +1,15 @@
+// Command queryvet runs the queryanalyzer checks, standalone or as a go vet
+// tool:
+//
+//	go vet -vettool=$(which queryvet) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/Finciero/go-queryparams/queryanalyzer"
+)
+
+func main() {
+	singlechecker.Main(queryanalyzer.Analyzer)
+}
@@ -0,0 +1,101 @@
+package a
+
+import (
+	"math/big"
+	"net/http"
+	"net/netip"
+	"reflect"
+	"time"
+
+	query "github.com/Finciero/go-queryparams"
+)
+
+type valid struct {
+	_       struct{}      `q:",exclusive=numeric|text,together=slice+time"`
+	Numeric int           `q:"numeric,allowempty"`
+	Text    *string       `q:"text"`
+	Slice   []float64     `q:"slice,comma"`
+	Time    *time.Time    `q:"time,layout=2006-01-02"`
+	Days    []query.Date  `q:"day"`
+	Amounts []*big.Rat    `q:"amount,maxdigits=30"`
+	Hosts   [2]netip.Addr `q:"host"`
+	Code    string        `q:"code,trim,upper,maxlen=10,slug"`
+	Ignored string        `q:"-"`
+	Other   map[string]string
+	Rows    []map[string]string `q:"rows"`
+	Account []struct {
+		ID int `q:"id"`
+	} `q:"account"`
+	Filter *struct {
+		Status []string          `q:"status"`
+		Meta   map[string]string `q:"meta"`
+	} `q:"filter"`
+}
+
+type invalid struct {
+	Numeric int    `q:"numeric,omitmepty"`
+	Other   int    `q:"numeric"`
+	Func    func() `q:"func"`
+	private int    `q:"private"`
+	Nested  struct {
+		Map map[string]struct{} `q:"map"`
+	} `q:"nested"`
+	Items []*struct {
+		Func func() `q:"func"`
+	} `q:"items"`
+	Rest map[string][]string `q:"*"`
+	More map[string][]string `q:",inline"`
+}
+
+func decode(d *query.Decoder) {
+	var v valid
+	_ = d.Decode(&v)
+
+	var i invalid
+	_ = d.Decode(&i) // want `invalid: Numeric: unknown tag option "omitmepty"` `invalid: Other: key "numeric" is already used by Numeric` `invalid: Func: type func\(\) is not supported` `invalid: private: field is not exported` `invalid: Nested.Map: type map\[string\]struct{} is not supported` `invalid: Items\[\].Func: type func\(\) is not supported` `invalid: More: only one inline field is allowed, Rest is already one`
+}
+
+type missing struct {
+	Func func() `q:"func"`
+}
+
+func decodeWithOptions(d *query.Decoder) {
+	var v valid
+	_ = d.Decode(&v, query.Require("numeric"))
+
+	var m missing
+	_ = d.Decode(&m, query.Require("func")) // want `missing: Func: type func\(\) is not supported`
+}
+
+func decodeEntryPoints(d *query.Decoder, c *query.Config, r *http.Request, h http.Handler) {
+	var m missing
+	_ = query.DecodeStrict("", &m)         // want `missing: Func: type func\(\) is not supported`
+	_ = c.Decode("", &m)                   // want `missing: Func: type func\(\) is not supported`
+	_ = d.DecodeValue(reflect.ValueOf(&m)) // want `missing: Func: type func\(\) is not supported`
+	_ = query.DecodeWith(&m)               // want `missing: Func: type func\(\) is not supported`
+	_ = query.DecodeHeader(r.Header, &m)   // want `missing: Func: type func\(\) is not supported`
+	_ = query.DecodeCookies(r, &m)         // want `missing: Func: type func\(\) is not supported`
+	_, _ = query.DecodeForm(r, &m)         // want `missing: Func: type func\(\) is not supported`
+	_ = query.DecodeRequest(r, &m)         // want `missing: Func: type func\(\) is not supported`
+	_ = query.Bind(r, &m)                  // want `missing: Func: type func\(\) is not supported`
+	_ = query.Middleware[missing](h)       // want `missing: Func: type func\(\) is not supported`
+	_ = query.Middleware[valid](h)
+}
+
+type listOpts[F any] struct {
+	Filter F   `q:"filter"`
+	Page   int `q:"page"`
+}
+
+type orderFilter struct {
+	Status string `q:"status"`
+	Func   func() `q:"func"`
+}
+
+func decodeGeneric[F any](d *query.Decoder) {
+	var v listOpts[F]
+	_ = d.Decode(&v)
+
+	var o listOpts[orderFilter]
+	_ = d.Decode(&o) // want `listOpts\[orderFilter\]: Filter.Func: type func\(\) is not supported`
+}
@@ -0,0 +1,44 @@
+package query
+
+import (
+	"net/http"
+	"reflect"
+)
+
+type Decoder struct{}
+
+type Option func(*Decoder)
+
+type DecodeOption func(*Decoder)
+
+func NewDecoder(s string, opts ...Option) *Decoder { return &Decoder{} }
+
+func Require(keys ...string) DecodeOption { return nil }
+
+func (d *Decoder) Decode(v interface{}, opts ...DecodeOption) error { return nil }
+
+func (d *Decoder) DecodeValue(rv reflect.Value, opts ...DecodeOption) error { return nil }
+
+type Config struct{}
+
+func (c *Config) Decode(s string, v interface{}) error { return nil }
+
+type Source interface{}
+
+func DecodeStrict(s string, v interface{}, opts ...Option) error { return nil }
+
+func DecodeWith(v interface{}, sources ...Source) error { return nil }
+
+func DecodeHeader(h http.Header, v interface{}, opts ...Option) error { return nil }
+
+func DecodeCookies(r *http.Request, v interface{}, opts ...Option) error { return nil }
+
+func DecodeForm(r *http.Request, v interface{}, opts ...Option) ([]byte, error) { return nil, nil }
+
+func DecodeRequest(r *http.Request, v interface{}, opts ...Option) error { return nil }
+
+func Bind(r *http.Request, v interface{}, opts ...Option) error { return nil }
+
+func Middleware[T any](next http.Handler, opts ...Option) http.Handler { return next }
+
+type Date struct{ Year, Month, Day int }
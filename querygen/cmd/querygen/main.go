@@ -0,0 +1,41 @@
+// Command querygen writes the DecodeQuery methods of struct types decoded by
+// package query, to be run by go generate:
+//
+//	//go:generate querygen -type=Options,Filter
+//
+// The methods of the types are written to "<type>_query.go", named after
+// the first type, in the directory of the package, or to the file set with
+// -output.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Finciero/go-queryparams/querygen"
+)
+
+func main() {
+	types := flag.String("type", "", "comma-separated list of struct type names; required")
+	output := flag.String("output", "", "output file name; default <type>_query.go")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: querygen -type=T[,T...] [-output file] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *types == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	if err := querygen.Write(dir, *output, strings.Split(*types, ",")...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,27 @@
+// Package sample declares the structs whose generated DecodeQuery methods
+// are tested against the reflective decoder.
+package sample
+
+//go:generate go run ../../cmd/querygen -type=Search,Listing
+
+// Search is decoded by a generated method.
+type Search struct {
+	Query    string  `q:"q,required"`
+	Page     int     `q:"page,default=1"`
+	PerPage  uint8   `q:"per_page"`
+	Offset   int64   `q:"offset,omitempty"`
+	Ratio    float32 `q:"ratio"`
+	Score    float64 `q:"score"`
+	Active   bool    `q:"active"`
+	Strict   bool    `q:"strict,default=true"`
+	Sort     string  `q:"sort,default=desc"`
+	Internal string  `q:"-"`
+	Untagged string
+}
+
+// Listing holds fields the generated method does not handle, so it uses the
+// reflective decoder.
+type Listing struct {
+	Tags []string `q:"tag"`
+	Page int      `q:"page"`
+}
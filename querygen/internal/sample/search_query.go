@@ -0,0 +1,170 @@
+// Code generated by querygen -type=Search,Listing; DO NOT EDIT.
+
+package sample
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Finciero/go-queryparams"
+)
+
+// DecodeQuery decodes the query string s into v, as query.NewDecoder(s).Decode(v)
+// does. Queries it does not handle, and all queries while query.SetDefaultOptions
+// has set options, are handed to the reflective decoder.
+func (v *Search) DecodeQuery(s string) error {
+	if query.HasDefaultOptions() {
+		return query.NewDecoder(s).Decode(v)
+	}
+	var (
+		raw  [9]string
+		seen [9]bool
+		err  error
+	)
+	for q := s; q != ""; {
+		var pair string
+		pair, q, _ = strings.Cut(q, "&")
+		if pair == "" {
+			continue
+		}
+		if strings.IndexByte(pair, ';') >= 0 {
+			return query.NewDecoder(s).Decode(v)
+		}
+		k, val, _ := strings.Cut(pair, "=")
+		if k, err = url.QueryUnescape(k); err != nil {
+			return query.NewDecoder(s).Decode(v)
+		}
+		var i int
+		switch k {
+		case "q":
+			i = 0
+		case "page":
+			i = 1
+		case "per_page":
+			i = 2
+		case "offset":
+			i = 3
+		case "ratio":
+			i = 4
+		case "score":
+			i = 5
+		case "active":
+			i = 6
+		case "strict":
+			i = 7
+		case "sort":
+			i = 8
+		default:
+			return query.NewDecoder(s).Decode(v)
+		}
+		if seen[i] {
+			return query.NewDecoder(s).Decode(v)
+		}
+		if raw[i], err = url.QueryUnescape(val); err != nil {
+			return query.NewDecoder(s).Decode(v)
+		}
+		seen[i] = true
+	}
+	if seen == [9]bool{} {
+		return query.NewDecoder(s).Decode(v)
+	}
+
+	if !seen[0] {
+		return query.NewDecoder(s).Decode(v)
+	}
+
+	if !seen[1] {
+		raw[1], seen[1] = "1", true
+	}
+	var v1 int64
+	if seen[1] {
+		if v1, err = strconv.ParseInt(raw[1], 10, 0); err != nil {
+			return query.NewDecoder(s).Decode(v)
+		}
+	}
+
+	var v2 uint64
+	if seen[2] {
+		if v2, err = strconv.ParseUint(raw[2], 10, 8); err != nil {
+			return query.NewDecoder(s).Decode(v)
+		}
+	}
+
+	var v3 int64
+	if seen[3] {
+		if v3, err = strconv.ParseInt(raw[3], 10, 64); err != nil {
+			return query.NewDecoder(s).Decode(v)
+		}
+	}
+
+	var v4 float64
+	if seen[4] {
+		if v4, err = strconv.ParseFloat(raw[4], 32); err != nil {
+			return query.NewDecoder(s).Decode(v)
+		}
+	}
+
+	var v5 float64
+	if seen[5] {
+		if v5, err = strconv.ParseFloat(raw[5], 64); err != nil {
+			return query.NewDecoder(s).Decode(v)
+		}
+	}
+
+	v6 := true
+	if seen[6] && raw[6] != "" {
+		if v6, err = strconv.ParseBool(raw[6]); err != nil {
+			return query.NewDecoder(s).Decode(v)
+		}
+	}
+
+	if !seen[7] {
+		raw[7], seen[7] = "true", true
+	}
+	v7 := true
+	if seen[7] && raw[7] != "" {
+		if v7, err = strconv.ParseBool(raw[7]); err != nil {
+			return query.NewDecoder(s).Decode(v)
+		}
+	}
+
+	if !seen[8] {
+		raw[8], seen[8] = "desc", true
+	}
+
+	if seen[0] {
+		v.Query = raw[0]
+	}
+	if seen[1] {
+		v.Page = int(v1)
+	}
+	if seen[2] {
+		v.PerPage = uint8(v2)
+	}
+	if seen[3] {
+		v.Offset = v3
+	}
+	if seen[4] {
+		v.Ratio = float32(v4)
+	}
+	if seen[5] {
+		v.Score = v5
+	}
+	if seen[6] {
+		v.Active = v6
+	}
+	if seen[7] {
+		v.Strict = v7
+	}
+	if seen[8] {
+		v.Sort = raw[8]
+	}
+	return nil
+}
+
+// DecodeQuery decodes the query string s into v with the reflective
+// decoder: the type of Tags is not supported.
+func (v *Listing) DecodeQuery(s string) error {
+	return query.NewDecoder(s).Decode(v)
+}
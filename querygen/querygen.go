@@ -0,0 +1,285 @@
+// Package querygen generates, for struct types decoded by package query, a
+// DecodeQuery method that decodes a query string without reflection:
+//
+//	func (v *T) DecodeQuery(s string) error
+//
+// The method scans the query string and parses the values of the fields
+// with strconv, as query.NewDecoder(s).Decode(v) would. It only handles
+// structs whose tagged fields are strings, bools, integers and floats with
+// a key of their own, and the "required" and "default" tag options; the
+// methods of other structs call the reflective decoder. Queries the method
+// does not handle either are also handed to the reflective decoder: keys no
+// field reads, repeated keys, ';' separators, invalid escapes, values that
+// fail to decode and missing required keys, so that it returns the same
+// errors. So are all queries while query.SetDefaultOptions has set options,
+// which the method does not apply.
+//
+// Run it with go generate through the querygen command:
+//
+//	//go:generate querygen -type=Options
+package querygen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Finciero/go-queryparams/internal/tagspec"
+)
+
+const queryPath = "github.com/Finciero/go-queryparams"
+
+// Generate returns the source of a Go file declaring the DecodeQuery method
+// of each struct type named by types, declared in the package in dir.
+func Generate(dir string, types ...string) ([]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		fset  = token.NewFileSet()
+		pkg   string
+		specs = make(map[string]*ast.TypeSpec)
+	)
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		pkg = f.Name.Name
+		ast.Inspect(f, func(n ast.Node) bool {
+			if spec, ok := n.(*ast.TypeSpec); ok {
+				specs[spec.Name.Name] = spec
+			}
+			return true
+		})
+	}
+
+	g := &generator{imports: map[string]bool{queryPath: true}}
+	for _, name := range types {
+		spec, ok := specs[name]
+		if !ok {
+			return nil, fmt.Errorf("querygen: type %s not found in %s", name, dir)
+		}
+		st, ok := spec.Type.(*ast.StructType)
+		if !ok || spec.TypeParams != nil {
+			return nil, fmt.Errorf("querygen: %s is not a struct type", name)
+		}
+		g.generate(name, st)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by querygen -type=%s; DO NOT EDIT.\n\n", strings.Join(types, ","))
+	fmt.Fprintf(&out, "package %s\n\nimport (\n", pkg)
+	for _, path := range []string{"net/url", "strconv", "strings"} {
+		if g.imports[path] {
+			fmt.Fprintf(&out, "%q\n", path)
+		}
+	}
+	fmt.Fprintf(&out, "\n%q\n)\n", queryPath)
+	out.Write(g.buf.Bytes())
+	return format.Source(out.Bytes())
+}
+
+// Write writes the file generated for types in the package in dir to the
+// file output, relative to dir, or "<type>_query.go" when empty.
+func Write(dir, output string, types ...string) error {
+	src, err := Generate(dir, types...)
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		output = strings.ToLower(types[0]) + "_query.go"
+	}
+	return os.WriteFile(filepath.Join(dir, output), src, 0o644)
+}
+
+// A field is a struct field decoded by a generated method.
+type field struct {
+	name string // Go name
+	key  string // query key
+	kind string // predeclared type name
+	def  *string
+	req  bool
+}
+
+type generator struct {
+	buf     bytes.Buffer
+	imports map[string]bool
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// fallback is the statement handing a query to the reflective decoder.
+const fallback = "return query.NewDecoder(s).Decode(v)"
+
+func (g *generator) generate(name string, st *ast.StructType) {
+	fields, why := fieldsOf(st)
+	if why != "" {
+		g.printf("\n// DecodeQuery decodes the query string s into v with the reflective\n")
+		g.printf("// decoder: %s.\n", why)
+		g.printf("func (v *%s) DecodeQuery(s string) error {\n%s\n}\n", name, fallback)
+		return
+	}
+	g.imports["net/url"], g.imports["strings"] = true, true
+
+	n := len(fields)
+	g.printf("\n// DecodeQuery decodes the query string s into v, as query.NewDecoder(s).Decode(v)\n")
+	g.printf("// does. Queries it does not handle, and all queries while query.SetDefaultOptions\n")
+	g.printf("// has set options, are handed to the reflective decoder.\n")
+	g.printf("func (v *%s) DecodeQuery(s string) error {\n", name)
+	g.printf("if query.HasDefaultOptions() {\n%s\n}\n", fallback)
+	g.printf("var (\nraw [%d]string\nseen [%d]bool\nerr error\n)\n", n, n)
+	g.printf("for q := s; q != \"\"; {\n")
+	g.printf("var pair string\npair, q, _ = strings.Cut(q, \"&\")\n")
+	g.printf("if pair == \"\" {\ncontinue\n}\n")
+	g.printf("if strings.IndexByte(pair, ';') >= 0 {\n%s\n}\n", fallback)
+	g.printf("k, val, _ := strings.Cut(pair, \"=\")\n")
+	g.printf("if k, err = url.QueryUnescape(k); err != nil {\n%s\n}\n", fallback)
+	g.printf("var i int\nswitch k {\n")
+	for i, f := range fields {
+		g.printf("case %q:\ni = %d\n", f.key, i)
+	}
+	g.printf("default:\n%s\n}\n", fallback)
+	g.printf("if seen[i] {\n%s\n}\n", fallback)
+	g.printf("if raw[i], err = url.QueryUnescape(val); err != nil {\n%s\n}\n", fallback)
+	g.printf("seen[i] = true\n}\n")
+	g.printf("if seen == [%d]bool{} {\n%s\n}\n", n, fallback)
+
+	for i, f := range fields {
+		g.printf("\n")
+		switch {
+		case f.def != nil:
+			g.printf("if !seen[%d] {\nraw[%d], seen[%d] = %q, true\n}\n", i, i, i, *f.def)
+		case f.req:
+			g.printf("if !seen[%d] {\n%s\n}\n", i, fallback)
+		}
+		g.parse(i, f)
+	}
+
+	g.printf("\n")
+	for i, f := range fields {
+		switch f.kind {
+		case "string":
+			g.printf("if seen[%d] {\nv.%s = raw[%d]\n}\n", i, f.name, i)
+		case "bool", "int64", "uint64", "float64":
+			g.printf("if seen[%d] {\nv.%s = v%d\n}\n", i, f.name, i)
+		default:
+			g.printf("if seen[%d] {\nv.%s = %s(v%d)\n}\n", i, f.name, f.kind, i)
+		}
+	}
+	g.printf("return nil\n}\n")
+}
+
+// parse writes the statements parsing the value of the field at index i
+// into the variable v<i>, as the reflective decoder does.
+func (g *generator) parse(i int, f field) {
+	if f.kind == "string" {
+		return
+	}
+	g.imports["strconv"] = true
+
+	var typ, call string
+	switch kind := f.kind; {
+	case kind == "bool":
+		g.printf("v%d := true\n", i)
+		g.printf("if seen[%d] && raw[%d] != \"\" {\n", i, i)
+		g.printf("if v%d, err = strconv.ParseBool(raw[%d]); err != nil {\n%s\n}\n}\n", i, i, fallback)
+		return
+	case strings.HasPrefix(kind, "int"):
+		typ, call = "int64", fmt.Sprintf("strconv.ParseInt(raw[%d], 10, %d)", i, bits(kind, "int"))
+	case strings.HasPrefix(kind, "uint"):
+		typ, call = "uint64", fmt.Sprintf("strconv.ParseUint(raw[%d], 10, %d)", i, bits(kind, "uint"))
+	default:
+		typ, call = "float64", fmt.Sprintf("strconv.ParseFloat(raw[%d], %d)", i, bits(kind, "float"))
+	}
+	g.printf("var v%d %s\n", i, typ)
+	g.printf("if seen[%d] {\nif v%d, err = %s; err != nil {\n%s\n}\n}\n", i, i, call, fallback)
+}
+
+// bits returns the size of the type kind named prefix followed by it, 0 for
+// int and uint.
+func bits(kind, prefix string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(kind, prefix))
+	return n
+}
+
+// kinds maps the predeclared types handled by generated methods to the
+// name of their kind.
+var kinds = map[string]string{
+	"string": "string", "bool": "bool",
+	"int": "int", "int8": "int8", "int16": "int16", "int32": "int32", "int64": "int64", "rune": "int32",
+	"uint": "uint", "uint8": "uint8", "uint16": "uint16", "uint32": "uint32", "uint64": "uint64", "byte": "uint8",
+	"float32": "float32", "float64": "float64",
+}
+
+// fieldsOf returns the fields of st read by the decoder, or the reason why
+// a generated method can't decode them.
+func fieldsOf(st *ast.StructType) ([]field, string) {
+	var (
+		fields []field
+		keys   = make(map[string]bool)
+	)
+	for _, f := range st.Fields.List {
+		var tag string
+		if f.Tag != nil {
+			tag, _ = strconv.Unquote(f.Tag.Value)
+		}
+		tag, tagged := reflect.StructTag(tag).Lookup(tagspec.Key)
+		if !tagged || tag == "-" {
+			continue
+		}
+		if len(f.Names) == 0 {
+			return nil, "it has embedded structs"
+		}
+
+		parts := strings.Split(tag, ",")
+		key, _ := tagspec.Unescape(parts[0])
+		fl := field{key: key}
+		for _, opt := range parts[1:] {
+			switch {
+			case opt == "required":
+				fl.req = true
+			case strings.HasPrefix(opt, "default="):
+				def := strings.TrimPrefix(opt, "default=")
+				fl.def = &def
+			case opt == "omitempty", opt == "keepzero", opt == "literal", opt == "nohash":
+			default:
+				return nil, fmt.Sprintf("the %q tag option of %s is not supported", tagspec.Name(opt), f.Names[0])
+			}
+		}
+
+		id, ok := f.Type.(*ast.Ident)
+		if ok {
+			fl.kind, ok = kinds[id.Name]
+		}
+		for _, name := range f.Names {
+			switch {
+			case !ok:
+				return nil, fmt.Sprintf("the type of %s is not supported", name)
+			case !name.IsExported():
+				return nil, fmt.Sprintf("%s is not exported", name)
+			case fl.key == "" || keys[fl.key]:
+				return nil, fmt.Sprintf("the key of %s is empty or shared", name)
+			}
+			keys[fl.key] = true
+			fl.name = name.Name
+			fields = append(fields, fl)
+		}
+	}
+	return fields, ""
+}
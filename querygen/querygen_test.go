@@ -0,0 +1,115 @@
+package querygen
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+
+	query "github.com/Finciero/go-queryparams"
+	"github.com/Finciero/go-queryparams/querygen/internal/sample"
+)
+
+func TestGenerate(t *testing.T) {
+	exp, err := os.ReadFile("internal/sample/search_query.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Generate("internal/sample", "Search", "Listing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(exp, got) {
+		t.Fatalf("exp: internal/sample/search_query.go up to date\ngot:\n%s", got)
+	}
+
+	for _, types := range [][]string{{"Missing"}, {"Search", "decoder"}} {
+		if _, err := Generate("internal/sample", types...); err == nil {
+			t.Fatalf("exp: an error for %v\ngot: %v", types, err)
+		}
+	}
+}
+
+// corpus holds the queries the generated methods are checked against.
+var corpus = []string{
+	"",
+	"q=shoes",
+	"q=shoes&page=2&per_page=50&offset=-7&ratio=0.25&score=1e3&active&strict=false&sort=asc",
+	"q=red+shoes%21&page=+3",
+	"q=a&q=b",
+	"q=%zz",
+	"q=a&%zz=1",
+	"q=a;page=2",
+	"q=a&&page=2&",
+	"page=2",
+	"q=a&page=x",
+	"q=a&per_page=256",
+	"q=a&per_page=-1",
+	"q=a&offset=9223372036854775808",
+	"q=a&ratio=1e40",
+	"q=a&score=nan",
+	"q=a&active=",
+	"q=a&active=maybe",
+	"q=a&strict=0",
+	"q=&sort=",
+	"q=a&unknown=1",
+	"q=a&tag=x&tag=y",
+	"q=a&tag[]=x",
+	"=a",
+	"q=a&page=",
+}
+
+func TestDecodeQuery(t *testing.T) {
+	for _, q := range corpus {
+		var exp, got sample.Search
+		expErr := query.NewDecoder(q).Decode(&exp)
+		gotErr := got.DecodeQuery(q)
+		// NaN values aren't DeepEqual, so the values are compared printed.
+		if fmt.Sprint(expErr) != fmt.Sprint(gotErr) || fmt.Sprintf("%+v", exp) != fmt.Sprintf("%+v", got) {
+			t.Errorf("%q\nexp: %+v, %v\ngot: %+v, %v", q, exp, expErr, got, gotErr)
+		}
+
+		var expL, gotL sample.Listing
+		expErr = query.NewDecoder(q).Decode(&expL)
+		gotErr = gotL.DecodeQuery(q)
+		if fmt.Sprint(expErr) != fmt.Sprint(gotErr) || !reflect.DeepEqual(expL, gotL) {
+			t.Errorf("%q\nexp: %+v, %v\ngot: %+v, %v", q, expL, expErr, gotL, gotErr)
+		}
+	}
+}
+
+func TestDecodeQuery_defaultOptions(t *testing.T) {
+	query.SetDefaultOptions(query.WithDecodeHook(query.TrimHook))
+	defer query.SetDefaultOptions()
+
+	var got sample.Search
+	if err := got.DecodeQuery("q=+shoes+"); err != nil {
+		t.Fatal(err)
+	}
+	if got.Query != "shoes" {
+		t.Fatalf("exp: %v\ngot: %v", "shoes", got.Query)
+	}
+}
+
+func BenchmarkDecodeQuery(b *testing.B) {
+	const q = "q=shoes&page=2&per_page=50&ratio=0.5&active&sort=desc"
+	b.Run("generated", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var v sample.Search
+			if err := v.DecodeQuery(q); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("reflective", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var v sample.Search
+			if err := query.NewDecoder(q).Decode(&v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
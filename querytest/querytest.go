@@ -0,0 +1,174 @@
+// Package querytest provides helpers to test the types encoded and decoded
+// by package query.
+package querytest
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	query "github.com/Finciero/go-queryparams"
+)
+
+// An Option configures RoundTrip.
+type Option func(*config)
+
+type config struct {
+	enc []query.EncoderOption
+	dec []query.Option
+}
+
+// WithEncoderOptions sets the options RoundTrip encodes with.
+func WithEncoderOptions(opts ...query.EncoderOption) Option {
+	return func(c *config) {
+		c.enc = append(c.enc, opts...)
+	}
+}
+
+// WithDecoderOptions sets the options RoundTrip decodes with.
+func WithDecoderOptions(opts ...query.Option) Option {
+	return func(c *config) {
+		c.dec = append(c.dec, opts...)
+	}
+}
+
+// RoundTrip encodes the struct v, or pointed by v, decodes the query string
+// into a new value of its type and reports through t every field that
+// doesn't hold the value it was encoded from, by path, such as
+// "Filter.Tags[1]" or `Meta["owner"]`. Nil and empty slices and maps are
+// considered equal, as a query string can't tell them apart. Values with an
+// Equal method are compared with it, so that times representing the same
+// instant are equal, and structs without exported fields, such as
+// query.StringSet, with reflect.DeepEqual.
+//
+// Call it for every options struct, populated, to check that its encoding
+// decodes back to it:
+//
+//	querytest.RoundTrip(t, ListOptions{Page: 2, Tags: []string{"a"}})
+func RoundTrip(t testing.TB, v interface{}, opts ...Option) {
+	t.Helper()
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	exp := reflect.Indirect(reflect.ValueOf(v))
+	if exp.Kind() != reflect.Struct {
+		t.Fatalf("querytest: RoundTrip of %T, not a struct", v)
+		return
+	}
+	q, err := query.Marshal(exp.Interface(), c.enc...)
+	if err != nil {
+		t.Fatalf("querytest: encoding %T: %v", v, err)
+		return
+	}
+	got := reflect.New(exp.Type())
+	if err := query.NewDecoder(q, c.dec...).Decode(got.Interface()); err != nil {
+		t.Fatalf("querytest: decoding %q into %T: %v", q, v, err)
+		return
+	}
+
+	diff("", exp, got.Elem(), func(path string, exp, got interface{}) {
+		t.Helper()
+		t.Errorf("querytest: %s: encoded %#v, decoded %#v from %q", path, exp, got, q)
+	})
+}
+
+// diff calls report with the path of every value of exp that differs from
+// the one of got, of the same type.
+func diff(path string, exp, got reflect.Value, report func(path string, exp, got interface{})) {
+	switch exp.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		switch {
+		case exp.IsNil() && got.IsNil():
+		case exp.IsNil() || got.IsNil():
+			report(path, iface(exp), iface(got))
+		case exp.Kind() == reflect.Interface && exp.Elem().Type() != got.Elem().Type():
+			report(path, iface(exp), iface(got))
+		default:
+			diff(path, exp.Elem(), got.Elem(), report)
+		}
+	case reflect.Struct:
+		if eq, ok := equal(exp, got); ok {
+			if !eq {
+				report(path, iface(exp), iface(got))
+			}
+			return
+		}
+		for i := 0; i < exp.NumField(); i++ {
+			sf := exp.Type().Field(i)
+			if !sf.IsExported() && !sf.Anonymous {
+				continue
+			}
+			fpath := sf.Name
+			if path != "" {
+				fpath = path + "." + sf.Name
+			}
+			if sf.Anonymous && !sf.IsExported() {
+				fpath = path
+			}
+			diff(fpath, exp.Field(i), got.Field(i), report)
+		}
+	case reflect.Slice, reflect.Array:
+		if exp.Len() != got.Len() {
+			report(path, iface(exp), iface(got))
+			return
+		}
+		for i := 0; i < exp.Len(); i++ {
+			diff(fmt.Sprintf("%s[%d]", path, i), exp.Index(i), got.Index(i), report)
+		}
+	case reflect.Map:
+		for it := exp.MapRange(); it.Next(); {
+			kpath := fmt.Sprintf("%s[%#v]", path, iface(it.Key()))
+			if gv := got.MapIndex(it.Key()); gv.IsValid() {
+				diff(kpath, it.Value(), gv, report)
+			} else {
+				report(kpath, iface(it.Value()), nil)
+			}
+		}
+		for it := got.MapRange(); it.Next(); {
+			if !exp.MapIndex(it.Key()).IsValid() {
+				report(fmt.Sprintf("%s[%#v]", path, iface(it.Key())), nil, iface(it.Value()))
+			}
+		}
+	case reflect.Float32, reflect.Float64:
+		if e, g := exp.Float(), got.Float(); e != g && !(math.IsNaN(e) && math.IsNaN(g)) {
+			report(path, iface(exp), iface(got))
+		}
+	default:
+		if !reflect.DeepEqual(iface(exp), iface(got)) {
+			report(path, iface(exp), iface(got))
+		}
+	}
+}
+
+// equal reports whether the structs exp and got are equal, when they can
+// be compared as a whole: through an Equal method, such as the one of
+// time.Time, or with reflect.DeepEqual when they have no exported fields,
+// such as query.StringSet and big.Int, whose fields diff would skip.
+func equal(exp, got reflect.Value) (eq, ok bool) {
+	if !exp.CanInterface() || !got.CanInterface() {
+		return false, false
+	}
+	t := exp.Type()
+	if m, ok := t.MethodByName("Equal"); ok && m.Type.NumIn() == 2 && m.Type.In(1) == t &&
+		m.Type.NumOut() == 1 && m.Type.Out(0).Kind() == reflect.Bool {
+		return exp.Method(m.Index).Call([]reflect.Value{got})[0].Bool(), true
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if sf := t.Field(i); sf.IsExported() || sf.Anonymous {
+			return false, false
+		}
+	}
+	return reflect.DeepEqual(exp.Interface(), got.Interface()), true
+}
+
+// iface returns the value held by v, or v itself, which fmt prints the same,
+// when it was reached through an unexported embedded field.
+func iface(v reflect.Value) interface{} {
+	if !v.CanInterface() {
+		return v
+	}
+	return v.Interface()
+}
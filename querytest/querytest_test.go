@@ -0,0 +1,92 @@
+package querytest
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	query "github.com/Finciero/go-queryparams"
+)
+
+type page struct {
+	Page int `q:"page"`
+}
+
+type options struct {
+	Query  string            `q:"q"`
+	Tags   []string          `q:"tag"`
+	Empty  []int             `q:"empty"`
+	Meta   map[string]string `q:"meta"`
+	Since  time.Time         `q:"since"`
+	Limit  *int              `q:"limit"`
+	Ratio  float64           `q:"ratio"`
+	Filter struct {
+		Status string `q:"status"`
+		Skip   string `q:"-"`
+	} `q:"filter"`
+	page
+}
+
+// recorder records the errors reported to it.
+type recorder struct {
+	testing.TB
+	errs []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+}
+
+func TestRoundTrip(t *testing.T) {
+	limit := 10
+	v := options{
+		Query: "shoes",
+		Tags:  []string{"a", "b"},
+		Empty: []int{},
+		Meta:  map[string]string{"owner": "me"},
+		Since: time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("CLT", -3*3600)),
+		Limit: &limit,
+		Ratio: math.NaN(),
+		page:  page{Page: 2},
+	}
+	v.Filter.Status = "open"
+	RoundTrip(t, v)
+	RoundTrip(t, &v, WithEncoderOptions(query.EncodeKeyStyle(query.DotKeys)), WithDecoderOptions(query.WithKeyStyle(query.DotKeys)))
+
+	r := &recorder{TB: t}
+	v.Filter.Skip = "lost"
+	RoundTrip(r, v, WithDecoderOptions(query.WithKeyStyle(query.DotKeys)))
+	exp := []string{
+		`querytest: Meta["owner"]: encoded "me", decoded <nil> from "filter%5Bstatus%5D=open&limit=10&meta%5Bowner%5D=me&page=2&q=shoes&ratio=NaN&since=2020-01-02T03%3A04%3A05-03%3A00&tag=a&tag=b"`,
+		`querytest: Filter.Status: encoded "open", decoded "" from "filter%5Bstatus%5D=open&limit=10&meta%5Bowner%5D=me&page=2&q=shoes&ratio=NaN&since=2020-01-02T03%3A04%3A05-03%3A00&tag=a&tag=b"`,
+		`querytest: Filter.Skip: encoded "lost", decoded "" from "filter%5Bstatus%5D=open&limit=10&meta%5Bowner%5D=me&page=2&q=shoes&ratio=NaN&since=2020-01-02T03%3A04%3A05-03%3A00&tag=a&tag=b"`,
+	}
+	if !reflect.DeepEqual(exp, r.errs) {
+		t.Fatalf("exp: %q\ngot: %q", exp, r.errs)
+	}
+
+	r = &recorder{TB: t}
+	RoundTrip(r, 2)
+	if len(r.errs) != 1 {
+		t.Fatalf("exp: %v\ngot: %q", "an error for a non-struct", r.errs)
+	}
+
+	type roles struct {
+		Roles query.StringSet `q:"roles,fold"`
+	}
+	RoundTrip(t, roles{query.NewStringSet("admin", "user")})
+	r = &recorder{TB: t}
+	RoundTrip(r, roles{query.NewStringSet("Admin")})
+	if len(r.errs) != 1 || !strings.HasPrefix(r.errs[0], "querytest: Roles: ") {
+		t.Fatalf("exp: %v\ngot: %q", "an error for Roles", r.errs)
+	}
+}
@@ -0,0 +1,81 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+)
+
+// radix returns the base integer fields with the tag options opts are
+// written in: that of the "base" option, between 2 and 36, or 10.
+func radix(opts tagOptions) int {
+	b, ok := base(opts)
+	if !ok {
+		return 10
+	}
+	return b
+}
+
+// base returns the value of the "base" tag option of opts, and whether it
+// is present and valid.
+func base(opts tagOptions) (int, bool) {
+	v, ok := opts.Value("base")
+	if !ok {
+		return 0, false
+	}
+	b, err := strconv.Atoi(v)
+	return b, err == nil && b >= 2 && b <= 36
+}
+
+// padding returns the value of the "pad" tag option of opts, the number of
+// digits integers are padded to with leading zeros, and whether it is
+// present and valid.
+func padding(opts tagOptions) (int, bool) {
+	v, ok := opts.Value("pad")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(v)
+	return n, err == nil && n > 0
+}
+
+// formatInt returns the integer i in the base given by the "base" tag
+// option of opts, in upper case, padded with zeros to the number of digits
+// of its "pad" option. A negative integer is written as a minus sign
+// followed by the digits of its absolute value, padded as those of a
+// positive one: -31 is "-1F" in base 16, and -7 is "-007" padded to 3.
+func formatInt(i int64, opts tagOptions) string {
+	if i < 0 {
+		return "-" + formatUint(uint64(-i), opts)
+	}
+	return formatUint(uint64(i), opts)
+}
+
+// formatUint is formatInt for unsigned integers.
+func formatUint(u uint64, opts tagOptions) string {
+	digits := strings.ToUpper(strconv.FormatUint(u, radix(opts)))
+	if width, ok := padding(opts); ok && len(digits) < width {
+		digits = strings.Repeat("0", width-len(digits)) + digits
+	}
+	return digits
+}
+
+// isDigitsIn reports whether s is a non-empty string of digits in base b,
+// in either case.
+func isDigitsIn(s string, b int) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range strings.ToLower(s) {
+		d := b
+		switch {
+		case '0' <= c && c <= '9':
+			d = int(c - '0')
+		case 'a' <= c && c <= 'z':
+			d = int(c-'a') + 10
+		}
+		if d >= b {
+			return false
+		}
+	}
+	return true
+}
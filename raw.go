@@ -0,0 +1,52 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+)
+
+// rawInto stores vals, the values read from the query for a field with the
+// tag options opts, in the field of the struct dst named by its "rawinto"
+// option, as they were written before any conversion: a string field gets
+// them joined by ",", a []string field a copy of them.
+//
+// The "rawinto" option keeps what the client sent, such as the leading
+// zeros of "007" or the exponent of "1e3", next to the typed value:
+//
+//	type Payment struct {
+//		Amount    float64 `q:"amount,rawinto=AmountRaw"`
+//		AmountRaw string  `q:"-"`
+//	}
+//
+// The raw field is left untouched when the key is absent from the query.
+func rawInto(dst reflect.Value, vals []string, opts tagOptions) {
+	name, ok := opts.Value("rawinto")
+	if !ok {
+		return
+	}
+	fv := dst.FieldByName(name)
+	if !fv.CanSet() {
+		return
+	}
+	switch {
+	case fv.Kind() == reflect.String:
+		fv.SetString(strings.Join(vals, ","))
+	case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String:
+		raw := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, v := range vals {
+			raw.Index(i).SetString(v)
+		}
+		fv.Set(raw)
+	}
+}
+
+// rawField reports whether the struct type t has a field named name that
+// can hold the raw values of a "rawinto" tag option.
+func rawField(t reflect.Type, name string) bool {
+	sf, ok := t.FieldByName(name)
+	if !ok || sf.PkgPath != "" {
+		return false
+	}
+	return sf.Type.Kind() == reflect.String ||
+		sf.Type.Kind() == reflect.Slice && sf.Type.Elem().Kind() == reflect.String
+}
@@ -0,0 +1,70 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// errDecimal reports a value that is not a decimal number.
+var errDecimal = errors.New("invalid decimal")
+
+// shiftDecimal returns the decimal number s multiplied by 10 to the power of
+// places, as an integer literal, without going through a float: "0.1234" is
+// "01234" with 4 places, and "-1.5" is "-150" with 2. s may have a sign "-"
+// but no exponent, and no more than places decimal places.
+func shiftDecimal(s string, places int) (string, error) {
+	units, frac, _ := strings.Cut(s, ".")
+	if !isDigits(strings.TrimPrefix(units, "-")) || frac != "" && !isDigits(frac) {
+		return "", errDecimal
+	}
+	if len(frac) > places {
+		return "", fmt.Errorf("more than %d decimal places", places)
+	}
+	return units + frac + strings.Repeat("0", places-len(frac)), nil
+}
+
+// isDigits reports whether s is a non-empty string of decimal digits.
+func isDigits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+// unshiftDecimal is the inverse of shiftDecimal: it writes the integer n
+// divided by 10 to the power of places, keeping every decimal place.
+func unshiftDecimal(n string, places int) string {
+	if places <= 0 {
+		return n
+	}
+	sign, digits := "", n
+	if strings.HasPrefix(n, "-") {
+		sign, digits = "-", n[1:]
+	}
+	if len(digits) <= places {
+		digits = strings.Repeat("0", places-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-places] + "." + digits[len(digits)-places:]
+}
+
+// scale returns the number of decimal places given by the "scale" tag option
+// of opts, and whether it has a valid one.
+func scale(opts tagOptions) (int, bool) {
+	v, ok := opts.Value("scale")
+	if !ok {
+		return 0, false
+	}
+	places, err := strconv.Atoi(v)
+	return places, err == nil && places >= 0
+}
+
+// unscale returns the integer literal decoded into an integer field with the
+// tag options opts from src: src itself, or, with the "scale" option, src
+// shifted by as many decimal places as it gives, so that "scale=4" reads
+// "0.1234" as 1234.
+func unscale(src string, opts tagOptions) (string, error) {
+	places, ok := scale(opts)
+	if !ok {
+		return src, nil
+	}
+	return shiftDecimal(src, places)
+}
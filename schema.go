@@ -0,0 +1,319 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// A ParamSpec describes a query parameter read by Decode. It marshals to JSON
+// as an OpenAPI 3 parameter object.
+type ParamSpec struct {
+	Name            string       `json:"name"`
+	In              string       `json:"in"`
+	Required        bool         `json:"required,omitempty"`
+	AllowEmptyValue bool         `json:"allowEmptyValue,omitempty"`
+	Style           string       `json:"style,omitempty"`
+	Explode         *bool        `json:"explode,omitempty"`
+	Schema          *ValueSchema `json:"schema"`
+}
+
+// A ValueSchema describes the values of a query parameter, or of one of its
+// elements or properties. It marshals to JSON as an OpenAPI 3 schema object.
+type ValueSchema struct {
+	Type                 string                  `json:"type"`
+	Format               string                  `json:"format,omitempty"`
+	Default              interface{}             `json:"default,omitempty"`
+	Pattern              string                  `json:"pattern,omitempty"`
+	Enum                 []string                `json:"enum,omitempty"`
+	Minimum              *float64                `json:"minimum,omitempty"`
+	Maximum              *float64                `json:"maximum,omitempty"`
+	Items                *ValueSchema            `json:"items,omitempty"`
+	Properties           map[string]*ValueSchema `json:"properties,omitempty"`
+	Required             []string                `json:"required,omitempty"`
+	AdditionalProperties *ValueSchema            `json:"additionalProperties,omitempty"`
+}
+
+// Schema returns the parameters decoded into the struct v, or pointed by v,
+// in the order of its fields. Their types, formats and constraints are
+// derived from the field types and "q" tags as Decode reads them:
+//
+//   - a slice is an array, whose style follows its tag: repeated keys are the
+//     exploded "form" style, "comma" is "form" without explode, "space" is
+//     "spaceDelimited" and "brackets" a repeated key ending with "[]";
+//   - nested structs and maps are "deepObject" parameters;
+//   - "required", "default", "enum", "min" and "max" set the matching
+//     properties, and the time options the format of a time.Time.
+//
+// Schema returns an error for field types the decoder does not support, and
+// for the "semicolon", "numbered" and "indexed" slice formats, which OpenAPI
+// can't describe.
+func Schema(v interface{}) ([]ParamSpec, error) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, &UnsupportedTypeError{Type: reflect.TypeOf(v)}
+	}
+
+	var specs []ParamSpec
+	err := params(t, func(sf reflect.StructField, name string, opts tagOptions) error {
+		spec, err := paramSpec(sf, name, opts)
+		if err != nil {
+			return withField(err, sf.Name, name)
+		}
+		specs = append(specs, spec)
+		return nil
+	})
+	return specs, err
+}
+
+// params calls fn with every field of the struct type t read by the decoder,
+// flattening untagged embedded structs, along with its key and tag options.
+func params(t reflect.Type, fn func(sf reflect.StructField, name string, opts tagOptions) error) error {
+	var d Decoder
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, opts, ok := d.fieldKey(sf, "")
+		if !ok || opts.Contains("inline") {
+			continue
+		}
+		if name == "" {
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct && isNested(sf.Type) {
+				if err := params(sf.Type, fn); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := fn(sf, name, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func paramSpec(sf reflect.StructField, name string, opts tagOptions) (ParamSpec, error) {
+	spec := ParamSpec{
+		Name:            name,
+		In:              "query",
+		Required:        opts.Contains("required"),
+		AllowEmptyValue: opts.Contains("allowempty") || opts.Contains("flag") || sf.Type == flagType,
+	}
+
+	t := sf.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if isNested(t) {
+		spec.Style, spec.Explode = "deepObject", explode(true)
+	} else if (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && !unmarshaler(t) {
+		switch {
+		case opts.Contains("comma"):
+			spec.Style, spec.Explode = "form", explode(false)
+		case opts.Contains("space"):
+			spec.Style, spec.Explode = "spaceDelimited", explode(false)
+		case opts.Contains("brackets"):
+			spec.Name += "[]"
+			spec.Style, spec.Explode = "form", explode(true)
+		case opts.Contains("semicolon"), opts.Contains("numbered"), opts.Contains("indexed"):
+			return spec, fmt.Errorf("query: the slice format of %s has no OpenAPI style", sf.Name)
+		default:
+			spec.Style, spec.Explode = "form", explode(true)
+		}
+	}
+
+	s, err := valueSchema(sf.Type, opts, make(map[reflect.Type]bool))
+	if err != nil {
+		return spec, err
+	}
+	spec.Schema = s
+	return spec, nil
+}
+
+// JSONSchema returns a JSON Schema (draft-07) of the object decoded from a
+// query string into the struct v, or pointed by v. Its properties are named
+// by the keys of the fields, typed as the values they hold, and described
+// with the same constraints as by Schema. Fields tagged with "-" are left
+// out, and field types the decoder does not support are reported as errors.
+func JSONSchema(v interface{}) ([]byte, error) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, &UnsupportedTypeError{Type: reflect.TypeOf(v)}
+	}
+
+	s, err := valueSchema(t, nil, make(map[reflect.Type]bool))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Schema string `json:"$schema"`
+		*ValueSchema
+	}{"http://json-schema.org/draft-07/schema#", s})
+}
+
+// durationPattern matches the durations parsed by time.ParseDuration.
+const durationPattern = `^[-+]?(\d+(\.\d*)?|\.\d+)(ns|us|µs|ms|s|m|h)((\d+(\.\d*)?|\.\d+)(ns|us|µs|ms|s|m|h))*$|^[-+]?0$`
+
+func explode(b bool) *bool {
+	return &b
+}
+
+// valueSchema returns the schema of the values of a field of type t with the
+// tag options opts. visiting holds the structs being described, to stop at
+// recursive types.
+func valueSchema(t reflect.Type, opts tagOptions, visiting map[reflect.Type]bool) (*ValueSchema, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return timeSchema(opts), nil
+	case t == durationType:
+		if _, ok := opts.Value("unit"); ok {
+			return &ValueSchema{Type: "integer", Format: "int64"}, nil
+		}
+		return &ValueSchema{Type: "string", Format: "duration", Pattern: durationPattern}, nil
+	case t == numberType:
+		return constrain(&ValueSchema{Type: "string", Pattern: numberPattern}, t, opts), nil
+	case t == flagType:
+		return &ValueSchema{Type: "boolean"}, nil
+	case unmarshaler(t):
+		return constrain(&ValueSchema{Type: "string"}, t, opts), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		items, err := valueSchema(t.Elem(), opts, visiting)
+		if err != nil {
+			return nil, err
+		}
+		s := &ValueSchema{Type: "array", Items: items}
+		if def, ok := opts.Value("default"); ok {
+			s.Default = typedDefaults(items, defaultValues(def, t, opts))
+			items.Default = nil
+		}
+		return s, nil
+	case reflect.Map:
+		if !mapKeyKind(t.Key().Kind()) {
+			return nil, &UnsupportedTypeError{Type: t}
+		}
+		elem, err := valueSchema(t.Elem(), nil, visiting)
+		if err != nil {
+			return nil, err
+		}
+		return &ValueSchema{Type: "object", AdditionalProperties: elem}, nil
+	case reflect.Struct:
+		if visiting[t] {
+			return &ValueSchema{Type: "object"}, nil
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		s := &ValueSchema{Type: "object", Properties: make(map[string]*ValueSchema)}
+		err := params(t, func(sf reflect.StructField, name string, opts tagOptions) error {
+			p, err := valueSchema(sf.Type, opts, visiting)
+			if err != nil {
+				return withField(err, sf.Name, name)
+			}
+			s.Properties[name] = p
+			if opts.Contains("required") {
+				s.Required = append(s.Required, name)
+			}
+			return nil
+		})
+		return s, err
+	}
+
+	s := &ValueSchema{}
+	switch t.Kind() {
+	case reflect.String:
+		s.Type = "string"
+	case reflect.Bool:
+		s.Type = "boolean"
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		s.Type, s.Format = "integer", "int64"
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		s.Type, s.Format = "integer", "int32"
+	case reflect.Float32:
+		s.Type, s.Format = "number", "float"
+	case reflect.Float64:
+		s.Type, s.Format = "number", "double"
+	default:
+		return nil, &UnsupportedTypeError{Type: t}
+	}
+	return constrain(s, t, opts), nil
+}
+
+func timeSchema(opts tagOptions) *ValueSchema {
+	switch {
+	case opts.Contains("unix"), opts.Contains("unixmilli"):
+		return &ValueSchema{Type: "integer", Format: "int64"}
+	}
+	if _, ok := opts.Value("layout"); ok {
+		return &ValueSchema{Type: "string"}
+	}
+	return &ValueSchema{Type: "string", Format: "date-time"}
+}
+
+// constrain sets the default, enum, minimum and maximum of the schema s of a
+// scalar of type t from the tag options opts.
+func constrain(s *ValueSchema, t reflect.Type, opts tagOptions) *ValueSchema {
+	if enum, ok := opts.Value("enum"); ok {
+		s.Enum = enumValues(enum)
+	}
+	if expr, ok := opts.Value("pattern"); ok {
+		s.Pattern = expr
+	}
+	if min, ok := opts.Value("min"); ok {
+		if f, err := strconv.ParseFloat(min, 64); err == nil {
+			s.Minimum = &f
+		}
+	}
+	if max, ok := opts.Value("max"); ok {
+		if f, err := strconv.ParseFloat(max, 64); err == nil {
+			s.Maximum = &f
+		}
+	}
+	if s.Minimum == nil && s.Type == "integer" && t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64 {
+		s.Minimum = new(float64)
+	}
+	if def, ok := opts.Value("default"); ok {
+		s.Default = typedDefault(s, def)
+	}
+	return s
+}
+
+// typedDefault returns the default value def as a value of the type of the
+// schema s, so that it marshals to the matching JSON type.
+func typedDefault(s *ValueSchema, def string) interface{} {
+	switch s.Type {
+	case "integer":
+		if n, err := strconv.ParseInt(def, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(def, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(def); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+func typedDefaults(items *ValueSchema, defs []string) []interface{} {
+	vals := make([]interface{}, len(defs))
+	for i, def := range defs {
+		vals[i] = typedDefault(items, def)
+	}
+	return vals
+}
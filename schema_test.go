@@ -0,0 +1,101 @@
+package query
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSchema(t *testing.T) {
+	type params struct {
+		Query  string            `q:"q,required"`
+		Sort   string            `q:"sort,enum=asc|desc,default=asc"`
+		Limit  uint              `q:"limit,max=100,default=20"`
+		Status []string          `q:"status,comma,default=open"`
+		Tags   []string          `q:"tag,brackets"`
+		Since  *time.Time        `q:"since"`
+		Until  time.Time         `q:"until,unix"`
+		Range  pagination        `q:"range"`
+		Meta   map[string]string `q:"meta"`
+		Ignore string            `q:"-"`
+		pagination
+	}
+
+	specs, err := Schema(&params{})
+	ok(t, err)
+	got, err := json.Marshal(specs)
+	ok(t, err)
+
+	exp := `[` +
+		`{"name":"q","in":"query","required":true,"schema":{"type":"string"}},` +
+		`{"name":"sort","in":"query","schema":{"type":"string","default":"asc","enum":["asc","desc"]}},` +
+		`{"name":"limit","in":"query","schema":{"type":"integer","format":"int64","default":20,"minimum":0,"maximum":100}},` +
+		`{"name":"status","in":"query","style":"form","explode":false,"schema":{"type":"array","default":["open"],"items":{"type":"string"}}},` +
+		`{"name":"tag[]","in":"query","style":"form","explode":true,"schema":{"type":"array","items":{"type":"string"}}},` +
+		`{"name":"since","in":"query","schema":{"type":"string","format":"date-time"}},` +
+		`{"name":"until","in":"query","schema":{"type":"integer","format":"int64"}},` +
+		`{"name":"range","in":"query","style":"deepObject","explode":true,"schema":{"type":"object","properties":{"page":{"type":"integer","format":"int64"},"per_page":{"type":"integer","format":"int64"}}}},` +
+		`{"name":"meta","in":"query","style":"deepObject","explode":true,"schema":{"type":"object","additionalProperties":{"type":"string"}}},` +
+		`{"name":"page","in":"query","schema":{"type":"integer","format":"int64"}},` +
+		`{"name":"per_page","in":"query","schema":{"type":"integer","format":"int64"}}` +
+		`]`
+	if string(got) != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, string(got))
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		for _, v := range []interface{}{
+			2,
+			struct {
+				F func() `q:"f"`
+			}{},
+			struct {
+				S []int `q:"s,indexed"`
+			}{},
+		} {
+			if _, err := Schema(v); err == nil {
+				t.Fatalf("%T\nexp: error\ngot: %v", v, err)
+			}
+		}
+	})
+}
+
+func TestJSONSchema(t *testing.T) {
+	type params struct {
+		Sort    string        `q:"sort,required,enum=asc|desc"`
+		Limit   int           `q:"limit,min=1,default=50"`
+		Timeout time.Duration `q:"timeout"`
+		Range   *pagination   `q:"range"`
+		Ignore  string        `q:"-"`
+	}
+
+	got, err := JSONSchema(params{})
+	ok(t, err)
+	exp := `{"$schema":"http://json-schema.org/draft-07/schema#","type":"object","properties":{` +
+		`"limit":{"type":"integer","format":"int64","default":50,"minimum":1},` +
+		`"range":{"type":"object","properties":{"page":{"type":"integer","format":"int64"},"per_page":{"type":"integer","format":"int64"}}},` +
+		`"sort":{"type":"string","enum":["asc","desc"]},` +
+		`"timeout":{"type":"string","format":"duration","pattern":` + strconv.Quote(durationPattern) + `}},` +
+		`"required":["sort"]}`
+	if string(got) != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, string(got))
+	}
+
+	pattern := regexp.MustCompile(durationPattern)
+	for _, d := range []time.Duration{0, time.Millisecond, -90 * time.Minute, 1500 * time.Microsecond} {
+		if !pattern.MatchString(d.String()) {
+			t.Fatalf("exp: %v to match\ngot: no match", d)
+		}
+	}
+	if pattern.MatchString("1 hour") {
+		t.Fatalf("exp: %v not to match\ngot: match", "1 hour")
+	}
+
+	if _, err := JSONSchema(struct {
+		C chan int `q:"c"`
+	}{}); err == nil {
+		t.Fatalf("exp: error\ngot: %v", err)
+	}
+}
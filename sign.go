@@ -0,0 +1,219 @@
+package query
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SignatureKey is the query parameter holding the signature added by Sign.
+const SignatureKey = "signature"
+
+// ErrInvalidSignature is returned by Verify when a query string is not
+// signed, or not signed with the given key. It also matches the
+// *SignatureError of the decoder, with errors.Is.
+var ErrInvalidSignature = errors.New("query: invalid signature")
+
+// EncodeCanonical returns the canonical query string encoding of v, suitable
+// for computing signatures: keys are sorted lexicographically, the values of a
+// repeated key keep the order in which Values produced them, and keys and
+// values are percent-encoded as specified by RFC 3986 (a space is "%20", not
+// "+").
+func EncodeCanonical(v interface{}, opts ...EncoderOption) (string, error) {
+	values, err := Values(v, opts...)
+	if err != nil {
+		return "", err
+	}
+	return canonical(values), nil
+}
+
+// Sign returns the canonical encoding of v followed by a SignatureKey
+// parameter holding the hex-encoded HMAC-SHA256 of that encoding under key.
+func Sign(v interface{}, key []byte, opts ...EncoderOption) (string, error) {
+	s, err := EncodeCanonical(v, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	sig := SignatureKey + "=" + signature(s, key)
+	if s == "" {
+		return sig, nil
+	}
+	return s + "&" + sig, nil
+}
+
+// Verify checks that the query string q carries a SignatureKey parameter
+// matching the signature Sign computes under key for its other parameters.
+// The parameters may arrive in any order or encoding; it returns
+// ErrInvalidSignature otherwise.
+func Verify(q string, key []byte) error {
+	values, err := url.ParseQuery(q)
+	if err != nil {
+		return err
+	}
+
+	sigs := values[SignatureKey]
+	if len(sigs) != 1 {
+		return ErrInvalidSignature
+	}
+	delete(values, SignatureKey)
+
+	if !hmac.Equal([]byte(sigs[0]), []byte(signature(canonical(values), key))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// A SignatureError describes a query whose signature, read by the field
+// tagged with the "constcmp" option, is missing or does not match the one
+// expected by WithExpectedSignature.
+type SignatureError struct {
+	Key string // query key of the signature, empty when no field reads it
+}
+
+func (e *SignatureError) Error() string {
+	if e.Key == "" {
+		return "query: no constcmp field holds the signature"
+	}
+	return "query: invalid signature in " + e.Key
+}
+
+// Is reports whether target is ErrInvalidSignature.
+func (e *SignatureError) Is(target error) bool {
+	return target == ErrInvalidSignature
+}
+
+// Code returns CodeBadSignature.
+func (e *SignatureError) Code() string {
+	return CodeBadSignature
+}
+
+// Params returns the key of the signature.
+func (e *SignatureError) Params() map[string]interface{} {
+	return map[string]interface{}{"key": e.Key}
+}
+
+// WithExpectedSignature makes the decoder verify the signature of the query
+// before decoding anything. The signature is the single value of the key of
+// the string field tagged with the "constcmp" option, as in q:"sig,constcmp",
+// and must equal expected called with the other parameters of the query,
+// compared in constant time. Decode fails with a *SignatureError when it
+// doesn't, when the signature is missing, or when the struct has no such
+// field. HMACSignature returns the expected signature of the queries signed
+// by Sign.
+func WithExpectedSignature(expected func(url.Values) string) Option {
+	return func(d *Decoder) {
+		d.expectedSignature = expected
+	}
+}
+
+// HMACSignature returns the function computing the signature Sign adds to the
+// query under key, for WithExpectedSignature:
+//
+//	dec := query.NewDecoder(q, query.WithExpectedSignature(query.HMACSignature(key)))
+func HMACSignature(key []byte) func(url.Values) string {
+	return func(values url.Values) string {
+		return signature(canonical(values), key)
+	}
+}
+
+// verifySignature checks the signature of src against the one expected by
+// the decoder, read by the field of the struct type t tagged with the
+// "constcmp" option.
+func (d *Decoder) verifySignature(src url.Values, t reflect.Type) error {
+	key, ok := d.signatureKey(t)
+	if !ok {
+		return &SignatureError{}
+	}
+	sigs := src[key]
+	if len(sigs) != 1 {
+		return &SignatureError{Key: key}
+	}
+	rest := make(url.Values, len(src))
+	for k, vals := range src {
+		if k != key {
+			rest[k] = vals
+		}
+	}
+	if subtle.ConstantTimeCompare([]byte(sigs[0]), []byte(d.expectedSignature(rest))) != 1 {
+		return &SignatureError{Key: key}
+	}
+	return nil
+}
+
+// signatureKey returns the key of the field of the struct type t tagged with
+// the "constcmp" option, looking into its untagged embedded structs.
+func (d *Decoder) signatureKey(t reflect.Type) (string, bool) {
+	if t.Kind() != reflect.Struct {
+		return "", false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key, opts, ok := d.fieldKey(sf, "")
+		switch {
+		case !ok:
+		case key == "" && sf.Anonymous:
+			if key, ok := d.signatureKey(sf.Type); ok {
+				return key, true
+			}
+		case opts.Contains("constcmp") && sf.Type.Kind() == reflect.String:
+			return key, true
+		}
+	}
+	return "", false
+}
+
+func signature(s string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(s))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// canonical returns the canonical encoding of values described by
+// EncodeCanonical.
+func canonical(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		for _, v := range values[k] {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(escapeRFC3986(k))
+			b.WriteByte('=')
+			b.WriteString(escapeRFC3986(v))
+		}
+	}
+	return b.String()
+}
+
+// escapeRFC3986 percent-encodes every byte of s but the unreserved characters
+// of RFC 3986.
+func escapeRFC3986(s string) string {
+	const hexUpper = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '.' || c == '_' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hexUpper[c>>4])
+		b.WriteByte(hexUpper[c&15])
+	}
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type signOptions struct {
+	Query string   `q:"q"`
+	Tags  []string `q:"tag"`
+	Page  int      `q:"page"`
+}
+
+func TestEncodeCanonical(t *testing.T) {
+	in := signOptions{Query: "a b+c/ñ", Tags: []string{"z", "a"}, Page: 2}
+	got, err := EncodeCanonical(in)
+	ok(t, err)
+
+	exp := "page=2&q=a%20b%2Bc%2F%C3%B1&tag=z&tag=a"
+	if exp != got {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	key := []byte("secret")
+	in := signOptions{Query: "a b", Tags: []string{"z", "a"}, Page: 2}
+
+	signed, err := Sign(in, key)
+	ok(t, err)
+	ok(t, Verify(signed, key))
+
+	// Reordering keys or using "+" for spaces keeps the signature valid.
+	sig := signed[len(signed)-len("signature=")-64:]
+	ok(t, Verify(sig+"&tag=z&q=a+b&page=2&tag=a", key))
+
+	for _, q := range []string{
+		signed + "&page=3",
+		sig + "&tag=a&tag=z&q=a+b&page=2",
+		"page=2&q=a%20b&tag=z&tag=a",
+	} {
+		if err := Verify(q, key); err != ErrInvalidSignature {
+			t.Fatalf("%s\nexp: %v\ngot: %v", q, ErrInvalidSignature, err)
+		}
+	}
+	if err := Verify(signed, []byte("other")); err != ErrInvalidSignature {
+		t.Fatalf("exp: %v\ngot: %v", ErrInvalidSignature, err)
+	}
+}
+
+func TestDecode_WithExpectedSignature(t *testing.T) {
+	type signed struct {
+		signOptions
+		Sig string `q:"signature,constcmp"`
+	}
+	key := []byte("secret")
+	in := signOptions{Query: "a b", Tags: []string{"z", "a"}, Page: 2}
+	q, err := Sign(in, key)
+	ok(t, err)
+
+	var got signed
+	ok(t, NewDecoder(q, WithExpectedSignature(HMACSignature(key))).Decode(&got))
+	if !reflect.DeepEqual(in, got.signOptions) || len(got.Sig) != 64 {
+		t.Fatalf("exp: %+v\ngot: %+v", in, got)
+	}
+
+	for _, test := range []struct {
+		q   string
+		key []byte
+		v   interface{}
+		err error
+	}{
+		{q + "&page=3", key, &signed{}, &SignatureError{Key: "signature"}},
+		{q, []byte("other"), &signed{}, &SignatureError{Key: "signature"}},
+		{q + "&signature=x", key, &signed{}, &SignatureError{Key: "signature"}},
+		{"page=2", key, &signed{}, &SignatureError{Key: "signature"}},
+		{q, key, &signOptions{}, &SignatureError{}},
+	} {
+		got := reflect.New(reflect.TypeOf(test.v).Elem()).Interface()
+		err := NewDecoder(test.q, WithExpectedSignature(HMACSignature(test.key))).Decode(got)
+		if !reflect.DeepEqual(test.err, err) {
+			t.Fatalf("%s\nexp: %v\ngot: %v", test.q, test.err, err)
+		}
+		if !errors.Is(err, ErrInvalidSignature) || err.(CodedError).Code() != CodeBadSignature {
+			t.Fatalf("exp: %v\ngot: %v", ErrInvalidSignature, err)
+		}
+		if !reflect.DeepEqual(test.v, got) {
+			t.Fatalf("exp: %+v\ngot: %+v", test.v, got)
+		}
+	}
+}
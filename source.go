@@ -0,0 +1,169 @@
+package query
+
+import (
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"reflect"
+)
+
+// A Source provides the values of query keys to DecodeWith.
+//
+// Lookup returns the values of key, and whether it is present. A source that
+// also has a method Keys() []string, listing its keys, can feed the fields
+// whose keys are not known in advance: maps, numbered and indexed slices and
+// inline fields.
+type Source interface {
+	Lookup(key string) ([]string, bool)
+}
+
+// keyLister is implemented by sources that can list their keys.
+type keyLister interface {
+	Keys() []string
+}
+
+// QuerySource is a Source reading parsed query string values.
+type QuerySource url.Values
+
+// Lookup returns the values of key.
+func (s QuerySource) Lookup(key string) ([]string, bool) {
+	vals, ok := s[key]
+	return vals, ok
+}
+
+// Keys returns every key of s.
+func (s QuerySource) Keys() []string {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// PathSource is a Source reading route variables, such as the ones returned
+// by gorilla/mux's Vars or collected from chi's URLParam.
+type PathSource map[string]string
+
+// Lookup returns the value of key.
+func (s PathSource) Lookup(key string) ([]string, bool) {
+	val, ok := s[key]
+	if !ok {
+		return nil, false
+	}
+	return []string{val}, true
+}
+
+// Keys returns every key of s.
+func (s PathSource) Keys() []string {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// HeaderSource returns a Source reading the header h, whose names are
+// matched case-insensitively as by DecodeHeader.
+func HeaderSource(h http.Header) Source {
+	return headerSource(h)
+}
+
+type headerSource http.Header
+
+func (s headerSource) Lookup(key string) ([]string, bool) {
+	vals, ok := s[textproto.CanonicalMIMEHeaderKey(key)]
+	return vals, ok
+}
+
+// Fallback returns a Source that, passed to DecodeWith, only provides the
+// keys missing from the sources before it, instead of overriding them.
+func Fallback(s Source) Source {
+	return fallback{s}
+}
+
+type fallback struct {
+	Source
+}
+
+func (s fallback) Keys() []string {
+	if l, ok := s.Source.(keyLister); ok {
+		return l.Keys()
+	}
+	return nil
+}
+
+// DecodeWith decodes the values of sources into the value pointed by v, as
+// Decode does for a query string. When several sources have a key, the last
+// one wins, except for sources wrapped with Fallback, which are only used
+// when no source before them has it.
+//
+// For example, with path variables taking precedence over the query string:
+//
+//	err := query.DecodeWith(&opts, query.QuerySource(r.URL.Query()), query.PathSource(mux.Vars(r)))
+func DecodeWith(v interface{}, sources ...Source) error {
+	d := NewDecoder("")
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+
+	var keys []string
+	for _, key := range d.fieldKeys(nil, rv.Elem().Type(), "", make(map[reflect.Type]bool)) {
+		keys = append(keys, key, key+"[]")
+	}
+	for _, s := range sources {
+		if l, ok := s.(keyLister); ok {
+			keys = append(keys, l.Keys()...)
+		}
+	}
+
+	src := make(url.Values, len(keys))
+	for _, key := range keys {
+		if _, ok := src[key]; ok {
+			continue
+		}
+		var found bool
+		for _, s := range sources {
+			if _, isFallback := s.(fallback); isFallback && found {
+				continue
+			}
+			if vals, ok := s.Lookup(key); ok {
+				src[key], found = vals, true
+			}
+		}
+	}
+	return d.unmarshal(src, v)
+}
+
+// fieldKeys appends to keys the query keys read by the fields of the struct
+// type t scoped by scope. The keys of maps and of slices of structs or maps,
+// and the bracketed, numbered and indexed forms of slice keys, are left out.
+func (d *Decoder) fieldKeys(keys []string, t reflect.Type, scope string, visiting map[reflect.Type]bool) []string {
+	if visiting[t] {
+		return keys
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key, opts, ok := d.fieldKey(sf, scope)
+		if !ok || opts.Contains("inline") {
+			continue
+		}
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch {
+		case ft.Kind() == reflect.Map, isNestedSlice(ft):
+		case isNested(ft):
+			keys = d.fieldKeys(keys, ft, key, visiting)
+		default:
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,53 @@
+package query
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeWith(t *testing.T) {
+	type options struct {
+		Org    string            `q:"org"`
+		Page   int               `q:"page"`
+		Trace  string            `q:"x-trace"`
+		Tags   []string          `q:"tag,indexed"`
+		Filter map[string]string `q:"filter"`
+		Range  *pagination       `q:"range"`
+	}
+	query := QuerySource(url.Values{
+		"org":           {"from-query"},
+		"page":          {"2"},
+		"tag[0]":        {"a"},
+		"filter[state]": {"open"},
+		"range[page]":   {"3"},
+	})
+	path := PathSource{"org": "from-path", "page": "9"}
+	header := HeaderSource(http.Header{"X-Trace": {"abc"}})
+
+	var got options
+	ok(t, DecodeWith(&got, query, Fallback(path), header))
+	exp := options{
+		Org:    "from-query",
+		Page:   2,
+		Trace:  "abc",
+		Tags:   []string{"a"},
+		Filter: map[string]string{"state": "open"},
+		Range:  &pagination{Page: 3},
+	}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	got = options{}
+	ok(t, DecodeWith(&got, query, path))
+	if got.Org != "from-path" || got.Page != 9 {
+		t.Fatalf("exp: %v\ngot: %+v", "path values", got)
+	}
+
+	err := DecodeWith(got, query)
+	if _, isInvalid := err.(*InvalidUnmarshalError); !isInvalid {
+		t.Fatalf("exp: %T\ngot: %v", &InvalidUnmarshalError{}, err)
+	}
+}
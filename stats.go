@@ -0,0 +1,60 @@
+package query
+
+import (
+	"strings"
+	"time"
+)
+
+// DecodeStats describes what a call to Decode found in the query, for
+// metrics about the parameters clients send.
+type DecodeStats struct {
+	Keys     int            // distinct keys in the query
+	Fields   int            // struct fields, other than maps, set from the query
+	Unknown  []string       // keys no field reads, in sorted order
+	Counts   map[string]int // occurrences of each key in the query string
+	Warnings int            // warnings recorded, as returned by Warnings
+	Duration time.Duration  // time spent in Decode, parsing the query included
+}
+
+// WithStats makes a call to Decode record its statistics in s, which it
+// resets first. Decoding only keeps statistics with this option, and s can
+// be reused from call to call, keeping the memory of Unknown and Counts.
+//
+//	var stats query.DecodeStats
+//	err := dec.Decode(&opts, query.WithStats(&stats))
+func WithStats(s *DecodeStats) DecodeOption {
+	return func(d *Decoder) {
+		s.Reset()
+		d.stats = s
+	}
+}
+
+// Reset clears s, keeping the memory of Unknown and Counts.
+func (s *DecodeStats) Reset() {
+	clear(s.Counts)
+	*s = DecodeStats{Unknown: s.Unknown[:0], Counts: s.Counts}
+}
+
+// count records in s.Counts the occurrences of each key of the query string
+// q, as written: a key repeated for a scalar field counts once for each of
+// its values, whatever the field keeps, and the keys read from WithFallback
+// aren't counted. With borrowed, q shares memory that may change, and the
+// keys are copied.
+func (s *DecodeStats) count(q string, borrowed bool) {
+	if s.Counts == nil {
+		s.Counts = make(map[string]int)
+	}
+	scanPairs(q, func(key, _ string) error {
+		if _, ok := s.Counts[key]; !ok && borrowed {
+			key = strings.Clone(key)
+		}
+		s.Counts[key]++
+		return nil
+	})
+}
+
+// finish records the statistics of the call to Decode started at start.
+func (s *DecodeStats) finish(call *Decoder, start time.Time) {
+	s.Warnings = len(call.warnings)
+	s.Duration = time.Since(start)
+}
@@ -0,0 +1,56 @@
+package query
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestDecode_WithStats(t *testing.T) {
+	type params struct {
+		Page   int               `q:"page"`
+		Sort   string            `q:"sort,default=asc"`
+		Limit  int               `q:"limit"`
+		Filter map[string]string `q:"filter"`
+		Range  pagination        `q:"range"`
+	}
+	d := NewDecoder("page=2&limit=x&filter[a]=b&range[page]=3&utm=1&ref=mail", WithErrorHandler(func(string, error) error { return nil }))
+
+	var stats DecodeStats
+	ok(t, d.Decode(&params{}, WithStats(&stats)))
+	if stats.Keys != 6 || stats.Fields != 2 || stats.Warnings != 3 || stats.Duration <= 0 {
+		t.Fatalf("exp: %v\ngot: %+v", "6 keys, 2 fields, 3 warnings", stats)
+	}
+	if exp := []string{"ref", "utm"}; !reflect.DeepEqual(exp, stats.Unknown) {
+		t.Fatalf("exp: %v\ngot: %v", exp, stats.Unknown)
+	}
+
+	ok(t, NewDecoder("").Decode(&params{}, WithStats(&stats)))
+	if exp := (DecodeStats{Unknown: []string{}, Counts: map[string]int{}, Duration: stats.Duration}); !reflect.DeepEqual(exp, stats) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, stats)
+	}
+}
+
+func TestDecode_WithStatsCounts(t *testing.T) {
+	type params struct {
+		Page int      `q:"page"`
+		Tags []string `q:"tag"`
+	}
+	d := NewDecoder("page=1&page=2&page=3&tag=a&tag=b&utm&utm=&p%61ge=4", WithFallback(url.Values{"sort": {"asc"}}))
+
+	var stats DecodeStats
+	var got params
+	ok(t, d.Decode(&got, WithStats(&stats)))
+	exp := map[string]int{"page": 4, "tag": 2, "utm": 2}
+	if !reflect.DeepEqual(exp, stats.Counts) {
+		t.Fatalf("exp: %v\ngot: %v", exp, stats.Counts)
+	}
+	if got.Page != 1 {
+		t.Fatalf("exp: %v\ngot: %v", 1, got.Page)
+	}
+
+	ok(t, NewDecoderBytes([]byte("tag=c")).Decode(&got, WithStats(&stats)))
+	if exp := map[string]int{"tag": 1}; !reflect.DeepEqual(exp, stats.Counts) {
+		t.Fatalf("exp: %v\ngot: %v", exp, stats.Counts)
+	}
+}
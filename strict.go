@@ -0,0 +1,198 @@
+package query
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// An UnknownKeyError describes a query key that no field reads, which the
+// decoder refuses with WithDisallowUnknownKeys.
+type UnknownKeyError struct {
+	Key string // query key
+}
+
+func (e *UnknownKeyError) Error() string {
+	return "query: unknown key " + e.Key
+}
+
+// Is reports whether target is ErrUnknownKey.
+func (e *UnknownKeyError) Is(target error) bool {
+	return target == ErrUnknownKey
+}
+
+// Fields returns the key with a message stating it is unknown.
+func (e *UnknownKeyError) Fields() map[string]string {
+	return map[string]string{e.Key: "is not a known parameter"}
+}
+
+// MarshalJSON encodes the fields of the error.
+func (e *UnknownKeyError) MarshalJSON() ([]byte, error) {
+	return marshalFields(e.Fields())
+}
+
+// Code returns CodeUnknownKey.
+func (e *UnknownKeyError) Code() string {
+	return CodeUnknownKey
+}
+
+// Params returns the unknown key.
+func (e *UnknownKeyError) Params() map[string]interface{} {
+	return map[string]interface{}{"key": e.Key}
+}
+
+// A RepeatedKeyError describes a key given several times for a field that
+// reads a single value, which the decoder refuses with WithNoRepeatedKeys.
+type RepeatedKeyError struct {
+	Key   string // query key
+	Count int    // number of values of the key
+}
+
+func (e *RepeatedKeyError) Error() string {
+	return "query: key " + e.Key + " is given " + strconv.Itoa(e.Count) + " times"
+}
+
+// Is reports whether target is ErrRepeatedKey.
+func (e *RepeatedKeyError) Is(target error) bool {
+	return target == ErrRepeatedKey
+}
+
+// Fields returns the key with a message stating it must be given once.
+func (e *RepeatedKeyError) Fields() map[string]string {
+	return map[string]string{e.Key: "must be given once"}
+}
+
+// MarshalJSON encodes the fields of the error.
+func (e *RepeatedKeyError) MarshalJSON() ([]byte, error) {
+	return marshalFields(e.Fields())
+}
+
+// Code returns CodeRepeatedKey.
+func (e *RepeatedKeyError) Code() string {
+	return CodeRepeatedKey
+}
+
+// Params returns the key and the number of its values.
+func (e *RepeatedKeyError) Params() map[string]interface{} {
+	return map[string]interface{}{"key": e.Key, "count": e.Count}
+}
+
+// WithDisallowUnknownKeys makes Decode fail with an *UnknownKeyError for the
+// first key, in sorted order, that no field reads, instead of ignoring it
+// with a warning. Keys dropped by the key conflict rule count as unknown.
+func WithDisallowUnknownKeys() Option {
+	return func(d *Decoder) {
+		d.disallowUnknown = true
+	}
+}
+
+// WithNoRepeatedKeys makes Decode fail with a *RepeatedKeyError when a key
+// is given several times for a field that reads a single value, such as
+// "page=1&page=2", rather than decoding the first one. Slices, arrays and
+// the types reading all the values of their key, such as implementations of
+// Unmarshaler, may still be given several values.
+func WithNoRepeatedKeys() Option {
+	return func(d *Decoder) {
+		d.noRepeats = true
+	}
+}
+
+// WithExactArrays makes Decode fail with a *ValidationError of rule "len"
+// when an array field is given more or fewer values than it has elements,
+// rather than ignoring the extra values or leaving the missing ones zero.
+func WithExactArrays() Option {
+	return func(d *Decoder) {
+		d.exactArrays = true
+	}
+}
+
+// WithFiniteFloats makes Decode fail with an *UnmarshalTypeError when a
+// float field, or an element of a float slice or array, is decoded as NaN
+// or an infinity, as from "NaN" or "Inf", which strconv.ParseFloat accepts.
+func WithFiniteFloats() Option {
+	return func(d *Decoder) {
+		d.finiteFloats = true
+	}
+}
+
+// StrictConfig returns a Config decoding with every strictness option of
+// the package, for public APIs that reject any query they don't fully
+// understand. Its options are, and will remain:
+//
+//   - WithDisallowUnknownKeys
+//   - WithNoRepeatedKeys
+//   - WithExactArrays
+//   - WithFiniteFloats
+//
+// along with the checks the decoder always makes, such as that of the keys
+// of the fields tagged with the "required" option. It leaves out the options
+// set by SetDefaultOptions. Every call returns the same Config, which
+// can't be changed.
+func StrictConfig() *Config {
+	return strictConfig
+}
+
+var strictConfig = &Config{opts: NewDecoder("", WithDisallowUnknownKeys(), WithNoRepeatedKeys(),
+	WithExactArrays(), WithFiniteFloats()).decoderOptions}
+
+// DecodeStrict decodes the query string s into the value pointed by v with
+// the options of StrictConfig(), followed by opts, which may relax them: an
+// error handler returning nil for some of the errors, such as those matching
+// ErrUnknownKey, turns them into warnings.
+func DecodeStrict(s string, v interface{}, opts ...Option) error {
+	return strictConfig.NewDecoder(s, opts...).Decode(v)
+}
+
+// errNotFinite is the error of a float decoded as NaN or an infinity, which
+// WithFiniteFloats rejects.
+var errNotFinite = errors.New("not a finite number")
+
+// finite reports whether the floats held by fv, alone, pointed or in a slice
+// or array, are all finite.
+func finite(fv reflect.Value) bool {
+	for fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return true
+		}
+		fv = fv.Elem()
+	}
+	switch fv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f := fv.Float()
+		return !math.IsInf(f, 0) && !math.IsNaN(f)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			if !finite(fv.Index(i)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// readsAll reports whether fields of type t read all the values of their
+// key, which WithNoRepeatedKeys lets be given several times.
+func readsAll(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return listType(t) || t == timeRangeType || t == moneyType ||
+		reflect.PtrTo(t).Implements(unmarshalerType) || converter(t) != nil
+}
+
+// checkStrict checks vals, the values of the key key for a field of type t,
+// against WithNoRepeatedKeys and WithExactArrays.
+func (d *Decoder) checkStrict(key string, vals []string, t reflect.Type, opts tagOptions) error {
+	if d.noRepeats && len(vals) > 1 && !readsAll(t) {
+		return &RepeatedKeyError{Key: key, Count: len(vals)}
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if d.exactArrays && t.Kind() == reflect.Array && !unmarshaler(t) && len(vals) != t.Len() {
+		return &ValidationError{key, shown(strings.Join(vals, ","), opts), "len", strconv.Itoa(t.Len())}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeStrict(t *testing.T) {
+	type search struct {
+		Query  string      `q:"q,required"`
+		Page   int         `q:"page"`
+		Tags   []string    `q:"tag"`
+		Bounds [2]float64  `q:"bounds"`
+		Window TimeRange   `q:"window"`
+		Extra  *[2]float64 `q:"extra"`
+	}
+
+	var got search
+	ok(t, DecodeStrict("q=shoes&page=2&tag=a&tag=b&bounds=1.5&bounds=3", &got))
+	exp := search{Query: "shoes", Page: 2, Tags: []string{"a", "b"}, Bounds: [2]float64{1.5, 3}}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	for _, test := range []struct {
+		query    string
+		sentinel error
+		code     string
+		params   map[string]interface{}
+	}{
+		{"q=shoes&utm=1", ErrUnknownKey, CodeUnknownKey, map[string]interface{}{"key": "utm"}},
+		{"q=shoes&page=1&page=2", ErrRepeatedKey, CodeRepeatedKey, map[string]interface{}{"key": "page", "count": 2}},
+		{"q=shoes&q=boots", ErrRepeatedKey, CodeRepeatedKey, map[string]interface{}{"key": "q", "count": 2}},
+		{"q=shoes&bounds=1", ErrConstraint, CodeInvalidLength, map[string]interface{}{"key": "bounds", "value": "1", "len": "2"}},
+		{"q=shoes&bounds=1&bounds=2&bounds=3", ErrConstraint, CodeInvalidLength, map[string]interface{}{"key": "bounds", "value": "1,2,3", "len": "2"}},
+		{"q=shoes&bounds=1&bounds=NaN", ErrInvalidValue, CodeInvalidNumber, map[string]interface{}{"key": "bounds", "value": "1,NaN"}},
+		{"q=shoes&extra=-Inf&extra=0", ErrInvalidValue, CodeInvalidNumber, map[string]interface{}{"key": "extra", "value": "-Inf,0"}},
+		{"page=1", ErrMissingKey, CodeRequired, map[string]interface{}{"key": "q"}},
+	} {
+		err := DecodeStrict(test.query, &search{})
+		if !errors.Is(err, test.sentinel) {
+			t.Fatalf("%s\nexp: %v\ngot: %v", test.query, test.sentinel, err)
+		}
+		var ce CodedError
+		if !errors.As(err, &ce) || ce.Code() != test.code || !reflect.DeepEqual(test.params, ce.Params()) {
+			t.Fatalf("%s\nexp: %v %v\ngot: %v", test.query, test.code, test.params, err)
+		}
+		ok(t, NewDecoder(test.query, WithErrorHandler(func(string, error) error { return nil })).Decode(&search{}))
+	}
+
+	d := StrictConfig().NewDecoder("q=shoes&utm=1", WithErrorHandler(func(key string, err error) error {
+		if errors.Is(err, ErrUnknownKey) {
+			return nil
+		}
+		return err
+	}))
+	ok(t, d.Decode(&got))
+	if w := d.Warnings(); len(w) != 1 || w[0].Key != "utm" || w[0].Action != WarnUnknownKey || !errors.Is(w[0].Err, ErrUnknownKey) {
+		t.Fatalf("exp: %v\ngot: %v", "utm unknown", w)
+	}
+
+	ok(t, NewDecoder("q=shoes&page=1&page=2&bounds=NaN&utm=1").Decode(&got))
+}
@@ -0,0 +1,102 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+)
+
+var stringSetType = reflect.TypeOf(StringSet{})
+
+// A StringSet is a set of strings that remembers the order in which they
+// were first added. Its zero value is an empty set ready to use.
+//
+// A copy of a StringSet shares the values of the original: adding to either
+// of them corrupts both. Copies, such as those made by assigning a struct
+// holding one, must only be read; Clone returns a set that can be added to.
+//
+// As a field, it is decoded from the values of its key like a []string,
+// following the same tag options, with repeated values dropped. The "fold"
+// tag option lowercases them first, so that values differing only in case
+// are the same. It is encoded like a []string of its values.
+type StringSet struct {
+	set   map[string]struct{}
+	order []string
+}
+
+// NewStringSet returns a set of vals.
+func NewStringSet(vals ...string) StringSet {
+	var s StringSet
+	for _, v := range vals {
+		s.Add(v)
+	}
+	return s
+}
+
+// Clone returns a copy of the set that can be added to without changing s.
+func (s StringSet) Clone() StringSet {
+	return NewStringSet(s.order...)
+}
+
+// Add adds v to the set, unless it is already in it.
+func (s *StringSet) Add(v string) {
+	if _, ok := s.set[v]; ok {
+		return
+	}
+	if s.set == nil {
+		s.set = make(map[string]struct{})
+	}
+	s.set[v] = struct{}{}
+	s.order = append(s.order, v)
+}
+
+// Contains reports whether v is in the set.
+func (s StringSet) Contains(v string) bool {
+	_, ok := s.set[v]
+	return ok
+}
+
+// Len returns the number of values in the set.
+func (s StringSet) Len() int {
+	return len(s.order)
+}
+
+// Slice returns the values of the set in the order they were added. It
+// returns nil for an empty set.
+func (s StringSet) Slice() []string {
+	if len(s.order) == 0 {
+		return nil
+	}
+	return append([]string(nil), s.order...)
+}
+
+// UnmarshalQuery replaces the values of the set with vals.
+func (s *StringSet) UnmarshalQuery(vals []string) error {
+	s.decode(vals, false)
+	return nil
+}
+
+// MarshalQuery returns the values of the set.
+func (s StringSet) MarshalQuery() ([]string, error) {
+	return s.Slice(), nil
+}
+
+// decode replaces the values of the set with vals, lowercased when fold is
+// true. Empty values are skipped.
+func (s *StringSet) decode(vals []string, fold bool) {
+	*s = StringSet{}
+	for _, v := range vals {
+		if fold {
+			v = strings.ToLower(v)
+		}
+		if v != "" {
+			s.Add(v)
+		}
+	}
+}
+
+// listType reports whether fields of type t read several values from the
+// list formats of the tag options: slices and arrays that don't decode
+// themselves, and StringSet.
+func listType(t reflect.Type) bool {
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && !unmarshaler(t) || t == stringSetType
+}
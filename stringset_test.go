@@ -0,0 +1,59 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecode_StringSet(t *testing.T) {
+	type filters struct {
+		Tags   StringSet  `q:"tag"`
+		Roles  StringSet  `q:"roles,comma,fold"`
+		Scopes *StringSet `q:"scope,brackets"`
+	}
+
+	var got filters
+	ok(t, NewDecoder("tag=b&tag=a&tag=b&roles=Admin,user,ADMIN,&scope[]=read").Decode(&got))
+	if exp, got := []string{"b", "a"}, got.Tags.Slice(); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+	if exp, got := []string{"admin", "user"}, got.Roles.Slice(); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+	if !got.Roles.Contains("admin") || got.Roles.Contains("Admin") || got.Roles.Len() != 2 {
+		t.Fatalf("exp: %v\ngot: %v", "admin in roles", got.Roles.Slice())
+	}
+	if got.Scopes == nil || !got.Scopes.Contains("read") {
+		t.Fatalf("exp: %v\ngot: %v", "read in scopes", got.Scopes)
+	}
+
+	template := got
+	ok(t, NewDecoder("tag=c").Decode(&got))
+	if exp, got := []string{"c"}, got.Tags.Slice(); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+	if exp, got := []string{"b", "a"}, template.Tags.Slice(); !reflect.DeepEqual(exp, got) || template.Tags.Contains("c") {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+
+	tags := template.Tags.Clone()
+	tags.Add("d")
+	if template.Tags.Contains("d") || template.Tags.Len() != 2 || !tags.Contains("b") || tags.Len() != 3 {
+		t.Fatalf("exp: %v and %v\ngot: %v and %v", []string{"b", "a"}, []string{"b", "a", "d"}, template.Tags.Slice(), tags.Slice())
+	}
+}
+
+func TestValues_StringSet(t *testing.T) {
+	type filters struct {
+		Tags  StringSet `q:"tag"`
+		Roles StringSet `q:"roles,comma,omitempty"`
+	}
+	got, err := Values(filters{Tags: NewStringSet("b", "a", "b")})
+	ok(t, err)
+	if exp := []string{"b", "a"}; !reflect.DeepEqual(exp, got["tag"]) {
+		t.Fatalf("exp: %v\ngot: %v", exp, got["tag"])
+	}
+	if _, ok := got["roles"]; ok {
+		t.Fatalf("exp: %v\ngot: %v", "roles omitted", got)
+	}
+}
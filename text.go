@@ -0,0 +1,65 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// WithValidUTF8 makes the decoder reject the values of string fields, of the
+// elements of string slices and of string map values that are not valid
+// UTF-8, such as "%FF", with a *ValidationError of rule "utf8".
+func WithValidUTF8() Option {
+	return func(d *Decoder) {
+		d.validUTF8 = true
+	}
+}
+
+// WithNoControlChars makes the decoder reject the values of string fields,
+// of the elements of string slices and of string map values that hold a
+// control character below 0x20 other than a tab, such as "%00", with a
+// *ValidationError of rule "control".
+func WithNoControlChars() Option {
+	return func(d *Decoder) {
+		d.noControlChars = true
+	}
+}
+
+// checkText checks vals, the values of the key key decoded into a field of
+// type t, against WithValidUTF8 and WithNoControlChars, when t holds strings.
+func (d *Decoder) checkText(key string, vals []string, t reflect.Type, opts tagOptions) error {
+	if !d.validUTF8 && !d.noControlChars || !textType(t) {
+		return nil
+	}
+	for _, v := range vals {
+		if d.validUTF8 && !utf8.ValidString(v) {
+			return &ValidationError{Key: key, Value: shown(v, opts), Rule: "utf8"}
+		}
+		if d.noControlChars && hasControl(v) {
+			return &ValidationError{Key: key, Value: shown(v, opts), Rule: "control"}
+		}
+	}
+	return nil
+}
+
+// textType reports whether fields of type t hold strings: strings, pointers
+// to them, slices and arrays of them, and string sets. Types decoding
+// themselves are left to check their own values.
+func textType(t reflect.Type) bool {
+	if t == stringSetType {
+		return true
+	}
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		if unmarshaler(t) {
+			return false
+		}
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.String && !unmarshaler(t)
+}
+
+// hasControl reports whether s holds a control character below 0x20 other
+// than a tab.
+func hasControl(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return r < 0x20 && r != '\t' }) >= 0
+}
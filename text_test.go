@@ -0,0 +1,42 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecode_ValidText(t *testing.T) {
+	type text struct {
+		Name  string            `q:"name"`
+		Tags  []string          `q:"tags"`
+		Meta  map[string]string `q:"meta"`
+		Count int               `q:"count"`
+	}
+	opts := []Option{WithValidUTF8(), WithNoControlChars()}
+	for _, test := range []struct {
+		query string
+		err   error
+	}{
+		{"name=caf%C3%A9&tags=a%09b&meta[k]=v&count=1", nil},
+		{"name=a%FFb", &ValidationError{Key: "name", Value: "a\xffb", Rule: "utf8"}},
+		{"name=a%00b", &ValidationError{Key: "name", Value: "a\x00b", Rule: "control"}},
+		{"tags=a&tags=%0A", &ValidationError{Key: "tags", Value: "\n", Rule: "control"}},
+		{"meta[k]=%C3", &ValidationError{Key: "meta[k]", Value: "\xc3", Rule: "utf8"}},
+	} {
+		err := NewDecoder(test.query, opts...).Decode(&text{})
+		if !reflect.DeepEqual(test.err, err) {
+			t.Fatalf("exp: %v\ngot: %v", test.err, err)
+		}
+		if test.err != nil && !errors.Is(err, ErrConstraint) {
+			t.Fatalf("exp: %v\ngot: %v", ErrConstraint, err)
+		}
+	}
+
+	var got text
+	ok(t, NewDecoder("name=a%00b&meta[k]=%FF").Decode(&got))
+	exp := text{Name: "a\x00b", Meta: map[string]string{"k": "\xff"}}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+}
@@ -0,0 +1,160 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// durationUnits maps the values of the "unit" tag option to the duration they
+// stand for.
+var durationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+}
+
+// formatTime returns the representation of t selected by the tag options:
+// Unix seconds for "unix", Unix milliseconds for "unixmilli", the reference
+// layout given by "layout=" or RFC3339 otherwise, in the location given by
+// "tz=" if any.
+func formatTime(t time.Time, opts tagOptions) string {
+	if opts.Contains("unix") {
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+	if opts.Contains("unixmilli") {
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	}
+	if loc, err := location(opts, nil); err == nil && loc != time.UTC {
+		t = t.In(loc)
+	}
+	if layout, ok := opts.Value("layout"); ok {
+		return t.Format(layout)
+	}
+	return t.Format(time.RFC3339)
+}
+
+// parseTime is the inverse of formatTime. Times without an offset are read
+// in the location given by the "tz" tag option, or else in loc, or else in
+// UTC.
+func parseTime(src string, opts tagOptions, loc *time.Location) (time.Time, error) {
+	if opts.Contains("unix") || opts.Contains("unixmilli") {
+		n, err := strconv.ParseInt(src, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if opts.Contains("unix") {
+			return time.Unix(n, 0), nil
+		}
+		return time.UnixMilli(n), nil
+	}
+	loc, err := location(opts, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if layout, ok := opts.Value("layout"); ok {
+		return time.ParseInLocation(layout, src, loc)
+	}
+	return time.ParseInLocation(time.RFC3339, src, loc)
+}
+
+// WithLocation makes the decoder read the times that have no offset, as
+// parsed by a "layout" tag option such as "layout=2006-01-02 15:04:05", in
+// loc instead of UTC. Times with an offset keep it. The "tz" tag option of a
+// field, such as "tz=America/Santiago", overrides loc for that field.
+//
+// A time that loc repeats, when daylight saving time ends, or skips, when
+// it starts, takes the offset in force before the change, as
+// time.ParseInLocation reads it.
+func WithLocation(loc *time.Location) Option {
+	return func(d *Decoder) {
+		d.location = loc
+	}
+}
+
+// locations caches the locations named by "tz" tag options.
+var locations sync.Map // map[string]*time.Location
+
+// location returns the location named by the "tz" tag option of opts, or
+// loc when there is none, or UTC when loc is nil.
+func location(opts tagOptions, loc *time.Location) (*time.Location, error) {
+	name, ok := opts.Value("tz")
+	if !ok {
+		if loc == nil {
+			return time.UTC, nil
+		}
+		return loc, nil
+	}
+	if l, ok := locations.Load(name); ok {
+		return l.(*time.Location), nil
+	}
+	l, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, l)
+	return l, nil
+}
+
+// durationUnit returns the duration named by the "unit" tag option of opts,
+// or 0 without one.
+func durationUnit(opts tagOptions) (time.Duration, error) {
+	name, ok := opts.Value("unit")
+	if !ok {
+		return 0, nil
+	}
+	unit, ok := durationUnits[name]
+	if !ok {
+		return 0, fmt.Errorf("query: unknown duration unit %q", name)
+	}
+	return unit, nil
+}
+
+// formatDuration returns d as a bare number of the unit given by the "unit"
+// tag option, or as d.String() without one.
+func formatDuration(d time.Duration, opts tagOptions) (string, error) {
+	unit, err := durationUnit(opts)
+	if err != nil || unit == 0 {
+		return d.String(), err
+	}
+	if d%unit == 0 {
+		return strconv.FormatInt(int64(d/unit), 10), nil
+	}
+	return strconv.FormatFloat(float64(d)/float64(unit), 'f', -1, 64), nil
+}
+
+// parseDuration is the inverse of formatDuration. Without a "unit" tag
+// option, it also reads a bare integer as a number of nanoseconds, as
+// durations were decoded before they were read as text.
+func parseDuration(src string, opts tagOptions) (time.Duration, error) {
+	unit, err := durationUnit(opts)
+	if err != nil {
+		return 0, err
+	}
+	if unit == 0 {
+		if isDigits(strings.TrimPrefix(src, "-")) {
+			n, err := strconv.ParseInt(src, 10, 64)
+			return time.Duration(n), err
+		}
+		return time.ParseDuration(src)
+	}
+	if n, err := strconv.ParseInt(src, 10, 64); err == nil {
+		return time.Duration(n) * unit, nil
+	}
+	f, err := strconv.ParseFloat(src, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(f * float64(unit)), nil
+}
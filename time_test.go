@@ -0,0 +1,179 @@
+package query
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type timeOptions struct {
+	Default   time.Time       `q:"default"`
+	Unix      time.Time       `q:"unix,unix"`
+	Milli     *time.Time      `q:"milli,unixmilli"`
+	Layout    time.Time       `q:"layout,layout=2006-01-02"`
+	Slice     []time.Time     `q:"slice,comma,layout=15:04"`
+	Timeout   time.Duration   `q:"timeout"`
+	Seconds   time.Duration   `q:"seconds,unit=s"`
+	Intervals []time.Duration `q:"intervals,unit=ms"`
+	Omitted   time.Time       `q:"omitted,omitempty"`
+}
+
+func TestTime_Values(t *testing.T) {
+	at := time.Date(2024, 3, 31, 14, 5, 6, 7000000, time.UTC)
+	in := timeOptions{
+		Default:   at,
+		Unix:      at,
+		Milli:     &at,
+		Layout:    at,
+		Slice:     []time.Time{at},
+		Timeout:   90 * time.Second,
+		Seconds:   1500 * time.Millisecond,
+		Intervals: []time.Duration{time.Second, 250 * time.Millisecond},
+	}
+
+	v, err := Values(in)
+	if err != nil {
+		t.Fatalf("Values(%v) returned error: %v", in, err)
+	}
+
+	want := url.Values{
+		"default":   {"2024-03-31T14:05:06Z"},
+		"unix":      {"1711893906"},
+		"milli":     {"1711893906007"},
+		"layout":    {"2024-03-31"},
+		"slice":     {"14:05"},
+		"timeout":   {"1m30s"},
+		"seconds":   {"1.5"},
+		"intervals": {"1000", "250"},
+	}
+	if !reflect.DeepEqual(want, v) {
+		t.Errorf("Values(%v) returned %v, want %v", in, v, want)
+	}
+
+	if _, err := Values(in, EncodeRejectZeroTime()); err != nil {
+		t.Errorf("Values(%v) returned error: %v", in, err)
+	}
+	in.Unix = time.Time{}
+	if _, err := Values(in, EncodeRejectZeroTime()); err == nil {
+		t.Errorf("expected Values() to return an error on a zero time")
+	}
+
+	type span struct {
+		Start time.Time `q:"start"`
+	}
+	nested := struct {
+		In   span            `q:"in"`
+		Each map[string]span `q:"each"`
+	}{In: span{}, Each: nil}
+	if _, err := Values(nested, EncodeRejectZeroTime()); err == nil {
+		t.Errorf("expected Values() to return an error on a nested zero time")
+	}
+	nested.In.Start = time.Now()
+	nested.Each = map[string]span{"a": {}}
+	if _, err := Values(nested, EncodeRejectZeroTime()); err == nil {
+		t.Errorf("expected Values() to return an error on a zero time in a map")
+	}
+
+	var fortnights struct {
+		Timeout time.Duration `q:"timeout,unit=fortnight"`
+	}
+	if _, err := Values(fortnights); err == nil {
+		t.Errorf("expected Values() to return an error on an unknown unit")
+	}
+}
+
+func TestTime_Decode(t *testing.T) {
+	var got timeOptions
+	q := "default=2024-03-31T14:05:06Z&unix=1711893906&milli=1711893906007&layout=2024-03-31&slice=14:05,15:06" +
+		"&timeout=1m30s&seconds=1.5&intervals=1000&intervals=250"
+	ok(t, NewDecoder(q).Decode(&got))
+
+	at := time.Date(2024, 3, 31, 14, 5, 6, 0, time.UTC)
+	milli := at.Add(7 * time.Millisecond)
+	exp := timeOptions{
+		Default:   at,
+		Unix:      time.Unix(at.Unix(), 0),
+		Milli:     &milli,
+		Layout:    time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+		Slice:     []time.Time{time.Date(0, 1, 1, 14, 5, 0, 0, time.UTC), time.Date(0, 1, 1, 15, 6, 0, 0, time.UTC)},
+		Timeout:   90 * time.Second,
+		Seconds:   1500 * time.Millisecond,
+		Intervals: []time.Duration{time.Second, 250 * time.Millisecond},
+	}
+
+	if !got.Unix.Equal(exp.Unix) || !got.Milli.Equal(*exp.Milli) {
+		t.Fatalf("exp: %v %v\ngot: %v %v", exp.Unix, exp.Milli, got.Unix, got.Milli)
+	}
+	got.Unix, got.Milli = exp.Unix, exp.Milli
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	t.Run("nanoseconds", func(t *testing.T) {
+		var got timeOptions
+		ok(t, NewDecoder("timeout=90000000000").Decode(&got))
+		if got.Timeout != 90*time.Second {
+			t.Fatalf("exp: %v\ngot: %v", 90*time.Second, got.Timeout)
+		}
+	})
+
+	t.Run("invalid layout", func(t *testing.T) {
+		var got timeOptions
+		if err := NewDecoder("layout=2024-03-31T14:05:06Z").Decode(&got); err == nil {
+			t.Fatalf("exp: error\ngot: %v", err)
+		}
+	})
+}
+
+func TestDecode_WithLocation(t *testing.T) {
+	santiago, err := time.LoadLocation("America/Santiago")
+	ok(t, err)
+
+	type event struct {
+		At    time.Time `q:"at,layout=2006-01-02 15:04:05"`
+		Local time.Time `q:"local,layout=2006-01-02 15:04,tz=America/Santiago"`
+		UTC   time.Time `q:"utc,layout=2006-01-02 15:04,tz=UTC"`
+	}
+	tests := []struct {
+		at  string
+		exp string // in UTC
+	}{
+		// Daylight saving time ends on April 7th, 2024 at midnight, back to 23:00.
+		{"2024-04-06 22:59:00", "2024-04-07T01:59:00Z"},
+		{"2024-04-07 00:30:00", "2024-04-07T04:30:00Z"},
+		// 23:30 happens twice, at -03 and at -04, and is read at -03.
+		{"2024-04-06 23:30:00", "2024-04-07T02:30:00Z"},
+		// It starts on September 8th, 2024 at midnight, forward to 01:00.
+		{"2024-09-07 23:30:00", "2024-09-08T03:30:00Z"},
+		{"2024-09-08 01:30:00", "2024-09-08T04:30:00Z"},
+		// 00:30 never happens, and is read at -04, as 23:30 the day before.
+		{"2024-09-08 00:30:00", "2024-09-08T03:30:00Z"},
+	}
+	for _, test := range tests {
+		var got event
+		ok(t, NewDecoder(url.Values{"at": {test.at}}.Encode(), WithLocation(santiago)).Decode(&got))
+		if got := got.At.UTC().Format(time.RFC3339); got != test.exp {
+			t.Fatalf("%s\nexp: %v\ngot: %v", test.at, test.exp, got)
+		}
+	}
+
+	var got event
+	ok(t, NewDecoder("local=2024-09-08+01:30&utc=2024-09-08+01:30").Decode(&got))
+	if exp, got := "2024-09-08T04:30:00Z", got.Local.UTC().Format(time.RFC3339); exp != got {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+	if exp, got := "2024-09-08T01:30:00Z", got.UTC.Format(time.RFC3339); exp != got {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+
+	// An explicit offset wins over the location.
+	type offset struct {
+		At time.Time `q:"at,layout=2006-01-02 15:04 -0700"`
+	}
+	var o offset
+	ok(t, NewDecoder(url.Values{"at": {"2024-04-06 22:59 +0000"}}.Encode(), WithLocation(santiago)).Decode(&o))
+	if exp, got := "2024-04-06T22:59:00Z", o.At.UTC().Format(time.RFC3339); exp != got {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+}
@@ -0,0 +1,200 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeRangeType = reflect.TypeOf(TimeRange{})
+
+// A TimeRange is a range of time from From to To. A zero From or To leaves
+// the range open on that side.
+//
+// As a field, a TimeRange is decoded from a single value holding both ends
+// separated by "..", such as "range=2024-01-01..2024-02-01", either of them
+// possibly empty: "2024-01-01.." has no end. The "prefix" tag option reads
+// them from two keys instead, named from and to after the value of the
+// option: "prefix=created_" reads created_from and created_to, and a bare
+// "prefix" reads from and to. Both ends follow the "unix", "unixmilli" and
+// "layout" and "tz" tag options of time.Time fields, and are read either as
+// RFC3339 times or as dates of the form 2006-01-02 otherwise.
+//
+// Decoding fails with a *ValidationError when From is after To, or when the
+// range spans more than the duration given by the "maxspan" tag option, such
+// as "maxspan=90d" or "maxspan=12h". A range limited by "maxspan" can't be
+// open. A "maxspan" option that is not a positive duration fails decoding,
+// and is reported by CheckType.
+//
+// A TimeRange is encoded the way it is decoded.
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// Contains reports whether t is within the range, ends included.
+func (r TimeRange) Contains(t time.Time) bool {
+	return (r.From.IsZero() || !t.Before(r.From)) && (r.To.IsZero() || !t.After(r.To))
+}
+
+// Span returns the duration of the range, or 0 when it is open.
+func (r TimeRange) Span() time.Duration {
+	if r.From.IsZero() || r.To.IsZero() {
+		return 0
+	}
+	return r.To.Sub(r.From)
+}
+
+// IsZero reports whether both ends of the range are zero.
+func (r TimeRange) IsZero() bool {
+	return r.From.IsZero() && r.To.IsZero()
+}
+
+// MarshalText returns the ends of the range as RFC3339 times separated by
+// "..".
+func (r TimeRange) MarshalText() ([]byte, error) {
+	return []byte(formatRange(r, nil)), nil
+}
+
+// UnmarshalText parses the ends of a range separated by "..", as RFC3339
+// times or dates of the form 2006-01-02 in UTC.
+func (r *TimeRange) UnmarshalText(text []byte) error {
+	return r.decode(string(text), nil, nil)
+}
+
+// decode parses src into r, with the ends following the tag options opts,
+// and read in loc when they have no offset.
+func (r *TimeRange) decode(src string, opts tagOptions, loc *time.Location) error {
+	from, to, ok := strings.Cut(src, "..")
+	if !ok {
+		return errors.New("missing .. between the ends of the range")
+	}
+	var rng TimeRange
+	var err error
+	if rng.From, err = parseRangeEnd(from, opts, loc); err != nil {
+		return err
+	}
+	if rng.To, err = parseRangeEnd(to, opts, loc); err != nil {
+		return err
+	}
+	*r = rng
+	return nil
+}
+
+// parseRangeEnd parses an end of a range, which is zero when s is empty.
+func parseRangeEnd(s string, opts tagOptions, loc *time.Location) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if _, ok := opts.Value("layout"); ok || opts.Contains("unix") || opts.Contains("unixmilli") || len(s) != len(dateLayout) {
+		return parseTime(s, opts, loc)
+	}
+	loc, err := location(opts, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation(dateLayout, s, loc)
+}
+
+// formatRange is the inverse of TimeRange.decode.
+func formatRange(r TimeRange, opts tagOptions) string {
+	return formatRangeEnd(r.From, opts) + ".." + formatRangeEnd(r.To, opts)
+}
+
+func formatRangeEnd(t time.Time, opts tagOptions) string {
+	if t.IsZero() {
+		return ""
+	}
+	return formatTime(t, opts)
+}
+
+// rangeKeys returns the keys of the ends of a TimeRange field with the
+// "prefix" tag option, scoped by scope, and whether it has the option.
+func (s KeyStyle) rangeKeys(scope string, opts tagOptions) (string, string, bool) {
+	prefix, ok := opts.Value("prefix")
+	if !ok && !opts.Contains("prefix") {
+		return "", "", false
+	}
+	return s.join(scope, prefix+"from"), s.join(scope, prefix+"to"), true
+}
+
+// lookupRange returns the value of a TimeRange field with the "prefix" tag
+// option, joining the values of its two keys, and the number of them present
+// in src.
+func lookupRange(src map[string][]string, from, to string) ([]string, int) {
+	var ends [2]string
+	n := 0
+	for i, k := range []string{from, to} {
+		if vals := src[k]; len(vals) > 0 {
+			ends[i] = vals[0]
+			n++
+		}
+	}
+	if n == 0 {
+		return nil, 0
+	}
+	return []string{ends[0] + ".." + ends[1]}, n
+}
+
+// validateRange checks the TimeRange fv, the field with key key decoded
+// from vals, against the order of its ends and its "maxspan" tag option.
+func validateRange(key string, vals []string, fv reflect.Value, opts tagOptions) error {
+	fv = reflect.Indirect(fv)
+	if fv.Type() != timeRangeType {
+		return nil
+	}
+	r := fv.Interface().(TimeRange)
+	value := strings.Join(vals, ",")
+	if !r.From.IsZero() && !r.To.IsZero() && r.From.After(r.To) {
+		return &ValidationError{key, value, "order", ""}
+	}
+	limit, ok := opts.Value("maxspan")
+	if !ok {
+		return nil
+	}
+	max, err := parseSpan(limit)
+	if err != nil {
+		return err
+	}
+	if r.From.IsZero() || r.To.IsZero() || r.Span() > max {
+		return &ValidationError{key, value, "maxspan", limit}
+	}
+	return nil
+}
+
+// parseSpan parses the "maxspan" tag option s: a positive duration as
+// understood by time.ParseDuration, or a number of days followed by "d".
+func parseSpan(s string) (time.Duration, error) {
+	var span time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err == nil {
+			span = time.Duration(n) * 24 * time.Hour
+		}
+	} else {
+		span, _ = time.ParseDuration(s)
+	}
+	if span <= 0 {
+		return 0, fmt.Errorf("query: invalid maxspan %q", s)
+	}
+	return span, nil
+}
+
+// timeRange adds to values the encoding of the TimeRange r, the field with
+// key name scoped by scope.
+func (e *encoder) timeRange(values adder, scope, name string, r TimeRange, opts tagOptions) {
+	from, to, split := e.keyStyle.rangeKeys(scope, opts)
+	if !split {
+		values.Add(name, formatRange(r, opts))
+		return
+	}
+	if !r.From.IsZero() {
+		values.Add(from, formatRangeEnd(r.From, opts))
+	}
+	if !r.To.IsZero() {
+		values.Add(to, formatRangeEnd(r.To, opts))
+	}
+}
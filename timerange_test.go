@@ -0,0 +1,82 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecode_TimeRange(t *testing.T) {
+	type report struct {
+		Range   TimeRange  `q:"range,maxspan=90d"`
+		Created *TimeRange `q:"created,prefix=created_,layout=2006-01-02"`
+		Period  TimeRange  `q:"period,prefix"`
+	}
+	day := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 0, 0, 0, 0, time.UTC) }
+
+	var got report
+	d := NewDecoder("range=2024-01-01..2024-02-01T12:00:00Z&created_from=2024-03-01&from=2024-01-01&strict=1")
+	ok(t, d.Decode(&got))
+	exp := report{
+		Range:   TimeRange{day(1, 1), day(2, 1).Add(12 * time.Hour)},
+		Created: &TimeRange{From: day(3, 1)},
+		Period:  TimeRange{From: day(1, 1)},
+	}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+	if exp, got := []Warning{{"strict", "1", WarnUnknownKey, nil}}, d.Warnings(); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %v\ngot: %v", exp, got)
+	}
+	if !got.Created.Contains(day(12, 31)) || got.Created.Contains(day(2, 1)) || got.Created.Span() != 0 {
+		t.Fatalf("exp: %v\ngot: %v", "range open after March 1st", got.Created)
+	}
+
+	tests := []struct {
+		query string
+		err   error
+	}{
+		{"range=2024-02-01..2024-01-01", &ValidationError{"range", "2024-02-01..2024-01-01", "order", ""}},
+		{"range=2024-01-01..2024-06-01", &ValidationError{"range", "2024-01-01..2024-06-01", "maxspan", "90d"}},
+		{"range=2024-01-01..", &ValidationError{"range", "2024-01-01..", "maxspan", "90d"}},
+		{"from=2024-02-01&to=2024-01-01", &ValidationError{"period", "2024-02-01..2024-01-01", "order", ""}},
+	}
+	for _, test := range tests {
+		var got report
+		err := NewDecoder(test.query).Decode(&got)
+		if !reflect.DeepEqual(test.err, err) {
+			t.Fatalf("%s\nexp: %v\ngot: %v", test.query, test.err, err)
+		}
+	}
+
+	err := NewDecoder("range=2024-01-01").Decode(&got)
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Fatalf("exp: %v\ngot: %v", ErrInvalidValue, err)
+	}
+
+	var invalid struct {
+		Range TimeRange `q:"range,maxspan=90"`
+	}
+	err = NewDecoder("range=2024-01-01..2030-01-01").Decode(&invalid)
+	if exp := `query: invalid maxspan "90"`; err == nil || err.Error() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+}
+
+func TestValues_TimeRange(t *testing.T) {
+	type report struct {
+		Range   TimeRange `q:"range"`
+		Created TimeRange `q:"created,prefix=created_,layout=2006-01-02"`
+		Period  TimeRange `q:"period,prefix,omitempty"`
+	}
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	got, err := Values(report{
+		Range:   TimeRange{To: from},
+		Created: TimeRange{From: from},
+	})
+	ok(t, err)
+	if exp := "created_from=2024-01-01&range=..2024-01-01T00%3A00%3A00Z"; got.Encode() != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, got.Encode())
+	}
+}
@@ -0,0 +1,129 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Finciero/go-queryparams/internal/tagspec"
+)
+
+var (
+	transformsMu sync.RWMutex
+	transforms   = make(map[string]func(string) (string, error))
+)
+
+// RegisterTransform makes the tag option name transform the values of the
+// fields tagged with it by fn, as the built-in transforms do:
+//
+//   - trim removes the spaces around values.
+//   - upper and lower change the case of their letters.
+//   - maxlen=N truncates them to their first N characters.
+//
+// Transforms run in the order their options are written in, as in
+// q:"code,trim,upper,maxlen=10", on each value of the key of a field, after
+// the hooks of the decoder and before the value is converted and validated.
+// An error returned by fn fails the decoding of the field with an
+// *UnmarshalTypeError.
+//
+// It panics if name is empty, holds a comma or an equal sign, or is the name
+// of a tag option of the package. Registering a name again replaces its
+// transform. It is meant to be called from init functions, before anything
+// is decoded; tags naming transforms that aren't registered are reported by
+// CheckType as unknown options.
+func RegisterTransform(name string, fn func(string) (string, error)) {
+	if name == "" || strings.ContainsAny(name, ",=") || tagOptionNames[name] {
+		panic("query: invalid transform name " + strconv.Quote(name))
+	}
+	transformsMu.Lock()
+	defer transformsMu.Unlock()
+	transforms[name] = fn
+}
+
+// registered reports whether name is the name of a transform registered with
+// RegisterTransform.
+func registered(name string) bool {
+	transformsMu.RLock()
+	defer transformsMu.RUnlock()
+	return transforms[name] != nil
+}
+
+// transformOf returns the transform of the tag option opt, or nil when opt
+// isn't a transform or is one with an invalid value.
+func transformOf(opt string) func(string) (string, error) {
+	switch tagspec.Name(opt) {
+	case "trim":
+		return func(s string) (string, error) { return strings.TrimSpace(s), nil }
+	case "upper":
+		return func(s string) (string, error) { return strings.ToUpper(s), nil }
+	case "lower":
+		return func(s string) (string, error) { return strings.ToLower(s), nil }
+	case "maxlen":
+		n, ok := maxLen(opt)
+		if !ok {
+			return nil
+		}
+		return func(s string) (string, error) { return truncate(s, n), nil }
+	}
+	if tagOptionNames[opt] {
+		return nil
+	}
+	transformsMu.RLock()
+	defer transformsMu.RUnlock()
+	return transforms[opt]
+}
+
+// maxLen returns the length of the "maxlen=N" tag option opt, and whether it
+// is a positive integer.
+func maxLen(opt string) (int, bool) {
+	n, err := strconv.Atoi(strings.TrimPrefix(opt, "maxlen="))
+	return n, err == nil && n > 0
+}
+
+// truncate returns the first n characters of s.
+func truncate(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// hasTransforms reports whether opts holds transforms.
+func hasTransforms(opts tagOptions) bool {
+	for _, opt := range opts {
+		if transformOf(opt) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// transform returns vals transformed by the transforms of opts, in order, or
+// vals itself when opts holds none. The values of the query are not written
+// to.
+func transform(vals []string, opts tagOptions) ([]string, error) {
+	var out []string
+	for _, opt := range opts {
+		fn := transformOf(opt)
+		if fn == nil {
+			continue
+		}
+		if out == nil {
+			out = append(make([]string, 0, len(vals)), vals...)
+		}
+		for i, v := range out {
+			s, err := fn(v)
+			if err != nil {
+				return vals, err
+			}
+			out[i] = s
+		}
+	}
+	if out == nil {
+		return vals, nil
+	}
+	return out, nil
+}
@@ -0,0 +1,56 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode_transforms(t *testing.T) {
+	RegisterTransform("testdigits", func(s string) (string, error) {
+		s = strings.ReplaceAll(s, "-", "")
+		if strings.Trim(s, "0123456789") != "" {
+			return "", errors.New("not digits")
+		}
+		return s, nil
+	})
+
+	type order struct {
+		Code   string   `q:"code,trim,upper,maxlen=5"`
+		Tags   []string `q:"tag,lower,trim"`
+		Phone  int      `q:"phone,testdigits"`
+		Status string   `q:"status,lower,enum=open|closed"`
+	}
+
+	var got order
+	ok(t, NewDecoder("code=+ab%C3%A9cdefg&tag=A+&tag=+B&phone=555-0100&status=OPEN").Decode(&got))
+	exp := order{Code: "ABÉCD", Tags: []string{"a", "b"}, Phone: 5550100, Status: "open"}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	err := NewDecoder("phone=555-CALL").Decode(&got)
+	var te *UnmarshalTypeError
+	if !errors.As(err, &te) || te.Key != "phone" || te.Value != "555-CALL" {
+		t.Fatalf("exp: %v\ngot: %v", "phone 555-CALL", err)
+	}
+
+	ok(t, CheckType(order{}))
+	err = CheckType(struct {
+		A string `q:"a,slug"`
+		B string `q:"b,maxlen=0"`
+	}{})
+	exp2 := `query: invalid type struct { A string "q:\"a,slug\""; B string "q:\"b,maxlen=0\"" }: ` +
+		`A: unknown tag option "slug"; B: maxlen "0" is not a positive integer`
+	if err == nil || err.Error() != exp2 {
+		t.Fatalf("exp: %v\ngot: %v", exp2, err)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("exp: %v\ngot: %v", "panic", r)
+		}
+	}()
+	RegisterTransform("required", func(s string) (string, error) { return s, nil })
+}
@@ -0,0 +1,251 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A ValidationError describes a decoded value rejected by the "enum",
+// "pattern", "min", "max" or "maxspan" tag option of its field, a TimeRange ending before it
+// starts, with the rule "order", or a string rejected by WithValidUTF8 or
+// WithNoControlChars, with the rules "utf8" and "control".
+type ValidationError struct {
+	Key   string // query key of the field
+	Value string // offending value
+	Rule  string // rule rejecting the value: "enum", "pattern", "min", "max", "maxspan", "order", "utf8", "control" or "len"
+	Limit string // value of the tag option, such as "asc|desc", "^[a-z]+$" or "100", or the length of an array
+}
+
+func (e *ValidationError) Error() string {
+	switch e.Rule {
+	case "enum":
+		return "query: value " + strconv.Quote(e.Value) + " of " + e.Key + " is not one of " + e.Limit
+	case "pattern":
+		return "query: value " + strconv.Quote(e.Value) + " of " + e.Key + " does not match " + e.Limit
+	case "min":
+		return "query: value " + strconv.Quote(e.Value) + " of " + e.Key + " is less than " + e.Limit
+	case "maxspan":
+		return "query: range " + e.Value + " of " + e.Key + " spans more than " + e.Limit
+	case "order":
+		return "query: range " + e.Value + " of " + e.Key + " ends before it starts"
+	case "utf8":
+		return "query: value " + strconv.Quote(e.Value) + " of " + e.Key + " is not valid UTF-8"
+	case "control":
+		return "query: value " + strconv.Quote(e.Value) + " of " + e.Key + " holds a control character"
+	case "len":
+		return "query: value " + strconv.Quote(e.Value) + " of " + e.Key + " does not have exactly " + e.Limit + " elements"
+	default:
+		return "query: value " + strconv.Quote(e.Value) + " of " + e.Key + " is greater than " + e.Limit
+	}
+}
+
+// Is reports whether target is ErrConstraint.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrConstraint
+}
+
+// Fields returns the key of the field with a message describing the values
+// it accepts.
+func (e *ValidationError) Fields() map[string]string {
+	var msg string
+	switch e.Rule {
+	case "enum":
+		msg = "must be one of " + strings.Join(enumValues(e.Limit), ", ")
+	case "pattern":
+		msg = "must match " + e.Limit
+	case "min":
+		msg = "must be at least " + e.Limit
+	case "maxspan":
+		msg = "must span at most " + e.Limit
+	case "order":
+		msg = "must end after it starts"
+	case "utf8":
+		msg = "must be valid UTF-8"
+	case "control":
+		msg = "must not hold control characters"
+	case "len":
+		msg = "must have exactly " + e.Limit + " values"
+	default:
+		msg = "must be at most " + e.Limit
+	}
+	return map[string]string{e.Key: msg}
+}
+
+// MarshalJSON encodes the fields of the error.
+func (e *ValidationError) MarshalJSON() ([]byte, error) {
+	return marshalFields(e.Fields())
+}
+
+// enumValues returns the values allowed by the "enum" tag option enum,
+// written separated by "|".
+func enumValues(enum string) []string {
+	return strings.Split(enum, "|")
+}
+
+// validate checks the values vals of the field with key key against its
+// "enum", "pattern", "min" and "max" tag options. Each element of a slice is
+// checked. It returns a *ValidationError for a value they reject, or the
+// *strconv.NumError parsing a value that is not a number, which the caller
+// reports as a type error.
+func validate(key string, vals []string, opts tagOptions) error {
+	enum, hasEnum := opts.Value("enum")
+	expr, hasPattern := opts.Value("pattern")
+	min, hasMin := opts.Value("min")
+	max, hasMax := opts.Value("max")
+	if !hasEnum && !hasPattern && !hasMin && !hasMax {
+		return nil
+	}
+	var re *regexp.Regexp
+	if hasPattern {
+		var err error
+		if re, err = pattern(expr); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range vals {
+		if hasEnum && !inEnum(v, enum) {
+			return &ValidationError{key, shown(v, opts), "enum", enum}
+		}
+		if re != nil && !re.MatchString(v) {
+			return &ValidationError{key, shown(v, opts), "pattern", expr}
+		}
+		if !hasMin && !hasMax {
+			continue
+		}
+		n, err := bounded(strings.TrimSpace(v), opts)
+		if err != nil {
+			return err
+		}
+		if hasMin && n < limit(min) {
+			return &ValidationError{key, shown(v, opts), "min", min}
+		}
+		if hasMax && n > limit(max) {
+			return &ValidationError{key, shown(v, opts), "max", max}
+		}
+	}
+	return nil
+}
+
+// lenientEnum returns vals without the values that the "enum" tag option of
+// opts rejects, with a warning for each one, when opts has the "enumlenient"
+// option. It reports false when it drops every value. vals itself is left
+// untouched.
+func (d *Decoder) lenientEnum(key string, vals []string, opts tagOptions) ([]string, bool) {
+	enum, ok := opts.Value("enum")
+	if !ok || !opts.Contains("enumlenient") {
+		return vals, true
+	}
+	var kept []string
+	for _, v := range vals {
+		if inEnum(v, enum) {
+			kept = append(kept, v)
+		} else {
+			d.warn(key, shown(v, opts), WarnEnumValue, &ValidationError{key, shown(v, opts), "enum", enum})
+		}
+	}
+	if len(kept) == len(vals) {
+		return vals, true
+	}
+	return kept, len(kept) > 0
+}
+
+// patterns caches the regular expressions of "pattern" tag options.
+var patterns sync.Map // map[string]*regexp.Regexp
+
+// pattern returns the regular expression of the "pattern" tag option expr,
+// compiled the first time it is needed, or an error when expr is not a valid
+// regular expression, which CheckType reports.
+func pattern(expr string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("query: invalid pattern %q", expr)
+	}
+	patterns.Store(expr, re)
+	return re, nil
+}
+
+// bounded returns the number v checked against the "min" and "max" tag
+// options: an integer in the base of the "base" option of opts, if any, or
+// a decimal number.
+func bounded(v string, opts tagOptions) (float64, error) {
+	b := radix(opts)
+	if b == 10 {
+		return strconv.ParseFloat(v, 64)
+	}
+	if strings.HasPrefix(v, "-") {
+		i, err := strconv.ParseInt(v, b, 64)
+		return float64(i), err
+	}
+	u, err := strconv.ParseUint(v, b, 64)
+	return float64(u), err
+}
+
+// limit returns the number of the "min" or "max" tag option lim. Invalid
+// limits, reported by CheckType, don't limit anything.
+func limit(lim string) float64 {
+	f, err := strconv.ParseFloat(lim, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return f
+}
+
+func inEnum(v, enum string) bool {
+	for {
+		e, rest, more := strings.Cut(enum, "|")
+		if v == e {
+			return true
+		}
+		if !more {
+			return false
+		}
+		enum = rest
+	}
+}
+
+// numericKind reports whether the "min" and "max" tag options apply to a
+// field of type t, that is, whether it holds numbers.
+func numericKind(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Float32, reflect.Float64:
+		return t != durationType
+	}
+	return false
+}
+
+// floatKind reports whether t holds floats, alone or through pointers,
+// slices and arrays.
+func floatKind(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64
+}
+
+// integerKind reports whether fields of type t hold integers, or slices or
+// pointers of them.
+func integerKind(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return t != durationType
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecode_Validation(t *testing.T) {
+	type params struct {
+		Sort   string   `q:"sort,enum=asc|desc,default=asc"`
+		Limit  int      `q:"limit,min=1,max=100"`
+		Status []string `q:"status,comma,enum=open|closed"`
+		Ratio  *float64 `q:"ratio,min=0.5"`
+	}
+
+	var got params
+	ok(t, NewDecoder("limit=100&status=open,closed").Decode(&got))
+	if got.Sort != "asc" || got.Limit != 100 || len(got.Status) != 2 {
+		t.Fatalf("got: %+v", got)
+	}
+
+	for _, tt := range []struct {
+		query string
+		exp   *ValidationError
+		msg   string
+	}{
+		{"sort=up", &ValidationError{"sort", "up", "enum", "asc|desc"}, "must be one of asc, desc"},
+		{"limit=0", &ValidationError{"limit", "0", "min", "1"}, "must be at least 1"},
+		{"limit=101", &ValidationError{"limit", "101", "max", "100"}, "must be at most 100"},
+		{"status=open,draft", &ValidationError{"status", "draft", "enum", "open|closed"}, "must be one of open, closed"},
+		{"ratio=0.25", &ValidationError{"ratio", "0.25", "min", "0.5"}, "must be at least 0.5"},
+	} {
+		ratio := 1.0
+		prev := params{Sort: "asc", Limit: 7, Status: []string{"open"}, Ratio: &ratio}
+		got := prev
+		err := NewDecoder(tt.query).Decode(&got)
+		if !reflect.DeepEqual(tt.exp, err) {
+			t.Fatalf("%s\nexp: %v\ngot: %v", tt.query, tt.exp, err)
+		}
+		if !reflect.DeepEqual(prev, got) || ratio != 1 {
+			t.Fatalf("%s\nexp: %+v\ngot: %+v", tt.query, prev, got)
+		}
+		if got := tt.exp.Fields()[tt.exp.Key]; got != tt.msg {
+			t.Fatalf("%s\nexp: %v\ngot: %v", tt.query, tt.msg, got)
+		}
+	}
+}
+
+func TestDecode_ValidationPattern(t *testing.T) {
+	type params struct {
+		Code  string   `q:"code,pattern=^[A-Z]{3}$"`
+		Tags  []string `q:"tag,pattern=^[a-z]+$"`
+		Wrong string   `q:"wrong,pattern=[a-z"`
+	}
+
+	var got params
+	ok(t, NewDecoder("code=ABC&tag=a&tag=bc").Decode(&got))
+	if exp := (params{Code: "ABC", Tags: []string{"a", "bc"}}); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+
+	for _, tt := range []struct {
+		query string
+		exp   *ValidationError
+	}{
+		{"code=ABCD", &ValidationError{"code", "ABCD", "pattern", "^[A-Z]{3}$"}},
+		{"tag=a&tag=B", &ValidationError{"tag", "B", "pattern", "^[a-z]+$"}},
+	} {
+		err := NewDecoder(tt.query).Decode(&got)
+		if !reflect.DeepEqual(tt.exp, err) {
+			t.Fatalf("%s\nexp: %v\ngot: %v", tt.query, tt.exp, err)
+		}
+	}
+	if exp, msg := "must match ^[A-Z]{3}$", (&ValidationError{"code", "ABCD", "pattern", "^[A-Z]{3}$"}).Fields()["code"]; msg != exp {
+		t.Fatalf("exp: %v\ngot: %v", exp, msg)
+	}
+
+	if err := NewDecoder("wrong=a").Decode(&got); err == nil {
+		t.Fatalf("exp: error for an invalid pattern\ngot: %v", err)
+	}
+}
+
+func TestDecode_ValidationBase(t *testing.T) {
+	type params struct {
+		Mask uint16 `q:"mask,base=16,max=255"`
+	}
+
+	var got params
+	ok(t, NewDecoder("mask=ff").Decode(&got))
+	if got.Mask != 255 {
+		t.Fatalf("exp: %v\ngot: %v", 255, got.Mask)
+	}
+
+	err := NewDecoder("mask=FFF").Decode(&got)
+	if exp := (&ValidationError{"mask", "FFF", "max", "255"}); !reflect.DeepEqual(exp, err) {
+		t.Fatalf("exp: %v\ngot: %v", exp, err)
+	}
+
+	err = NewDecoder("mask=FG").Decode(&got)
+	if _, isType := err.(*UnmarshalTypeError); !isType {
+		t.Fatalf("exp: %T\ngot: %v", &UnmarshalTypeError{}, err)
+	}
+}
+
+func TestDecode_EnumLenient(t *testing.T) {
+	type params struct {
+		Status []string `q:"status,comma,enum=open|closed,enumlenient"`
+		Sort   string   `q:"sort,enum=asc|desc,enumlenient,default=asc"`
+	}
+
+	var got params
+	d := NewDecoder("status=open,archived,closed,draft&sort=up")
+	ok(t, d.Decode(&got))
+	if exp := (params{Status: []string{"open", "closed"}, Sort: ""}); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("exp: %+v\ngot: %+v", exp, got)
+	}
+	exp := []Warning{
+		{"status", "archived", WarnEnumValue, &ValidationError{"status", "archived", "enum", "open|closed"}},
+		{"status", "draft", WarnEnumValue, &ValidationError{"status", "draft", "enum", "open|closed"}},
+		{"sort", "up", WarnEnumValue, &ValidationError{"sort", "up", "enum", "asc|desc"}},
+	}
+	if !reflect.DeepEqual(exp, d.Warnings()) {
+		t.Fatalf("exp: %v\ngot: %v", exp, d.Warnings())
+	}
+
+	got = params{}
+	ok(t, NewDecoder("status=archived").Decode(&got))
+	if len(got.Status) != 0 || got.Sort != "asc" {
+		t.Fatalf("exp: %v\ngot: %+v", "no status", got)
+	}
+}